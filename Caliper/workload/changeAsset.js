@@ -32,7 +32,7 @@ class MyWorkload extends WorkloadModuleBase {
             contractId: this.roundArguments.contractId,
             contractFunction: 'ChangeAsset',
             invokerIdentity: 'User1',
-            contractArguments: [nationalID,"0"],
+            contractArguments: [nationalID,"0","caliper benchmark"],
             readOnly: false
         };
         console.info(this.txIndex);
@@ -33,6 +33,8 @@ class MyWorkload extends WorkloadModuleBase {
             contractFunction: 'CreateAsset',
             invokerIdentity: 'User1',
             contractArguments: [nationalID, assetID, 25, 0,0,0],
+            // Only read when family 25 has no key yet
+            transientMap: { familyKeySeed: 'caliper benchmark family key seed' },
             readOnly: false
         };
         console.info(this.txIndex);
@@ -0,0 +1,83 @@
+package chaincode
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// accessRules are the contract's transactions as the access check sees them. The
+// default policy is used until a registry admin stores a policy on the ledger.
+var accessRules = &core.AccessRules{
+	Transactions: []string{
+		"InitLedger", "ReadAsset", "AssetExists", "ChangeAsset", "GetAllAssets", "CreateAsset", "DeleteAsset",
+		"TransferAsset", "ErasePatient", "EraseFamily", "ComputeRiskForKey", "AddParent", "CalculateCrossFamilyRisk",
+		"MigrateLedger", "GetAssetsPage", "QueryPatients", "GetPatientHistory",
+		"SetAccessPolicy", "GetAccessPolicy", "GrantConsent", "RevokeConsent", "GetConsents", "ExplainRisk",
+		"ComputeRiskProfile", "SetGenotype", "ComputeRecessiveRisk", "SetBirthYear",
+	},
+	Scopes: map[string][]core.ScopeArgument{
+		"ReadAsset":                {{Index: 0}},
+		"AssetExists":              {{Index: 0}},
+		"ChangeAsset":              {{Index: 0}},
+		"CreateAsset":              {{Index: 2, Family: true}},
+		"DeleteAsset":              {{Index: 0}},
+		"TransferAsset":            {{Index: 0}},
+		"ExplainRisk":              {{Index: 0}},
+		"ComputeRiskProfile":       {{Index: 0}},
+		"SetGenotype":              {{Index: 0}},
+		"SetBirthYear":             {{Index: 0}},
+		"ComputeRecessiveRisk":     {{Index: 0}},
+		"ErasePatient":             {{Index: 0}},
+		"EraseFamily":              {{Index: 0, Family: true}},
+		"ComputeRiskForKey":        {{Index: 0}},
+		"AddParent":                {{Index: 0}, {Index: 1}},
+		"CalculateCrossFamilyRisk": {{Index: 0}},
+		"GetAssetsPage":            {{Index: 2, Family: true}},
+		"GetPatientHistory":        {{Index: 0}},
+		"GrantConsent":             {{Index: 0}},
+		"RevokeConsent":            {{Index: 0}},
+		"GetConsents":              {{Index: 0}},
+	},
+	SetPolicy: "SetAccessPolicy",
+	Default: core.AccessPolicy{Roles: map[string][]string{
+		core.RoleRegistryAdmin: {"*"},
+		core.RoleClinician: {"ReadAsset", "AssetExists", "CreateAsset", "ChangeAsset", "SetGenotype", "SetBirthYear", "TransferAsset", "ExplainRisk", "ComputeRiskProfile",
+			"ComputeRecessiveRisk", "AddParent", "CalculateCrossFamilyRisk", "GetPatientHistory", "GetAssetsPage", "GetConsents"},
+		core.RoleLab:        {"ReadAsset", "AssetExists", "ChangeAsset", "SetGenotype"},
+		core.RoleGeneticist: {"ReadAsset", "AssetExists", "TransferAsset", "ExplainRisk", "ComputeRiskProfile", "ComputeRecessiveRisk", "ComputeRiskForKey", "CalculateCrossFamilyRisk", "AddParent", "GetAssetsPage"},
+		core.RolePatient: {"ReadAsset", "GetPatientHistory", "ErasePatient", "GrantConsent",
+			"RevokeConsent", "GetConsents"},
+	}},
+}
+
+func accessService(ctx contractapi.TransactionContextInterface) *core.AccessService {
+	return &core.AccessService{State: newStore(ctx), Rules: accessRules}
+}
+
+// GetBeforeTransaction runs the access check before every transaction of the contract
+func (s *SmartContract) GetBeforeTransaction() interface{} {
+	return authorize
+}
+
+// SetAccessPolicy replaces the policy stored on the ledger
+func (s *SmartContract) SetAccessPolicy(ctx contractapi.TransactionContextInterface, policyJSON string) (err error) {
+	defer catalogError(&err)
+	return accessService(ctx).SetPolicy(policyJSON)
+}
+
+// GetAccessPolicy returns the policy in force
+func (s *SmartContract) GetAccessPolicy(ctx contractapi.TransactionContextInterface) (_ *core.AccessPolicy, err error) {
+	defer catalogError(&err)
+	return accessService(ctx).Policy()
+}
+
+// authorize runs the access check on the transaction's arguments
+func authorize(ctx contractapi.TransactionContextInterface) (err error) {
+	defer catalogError(&err)
+	function, args := ctx.GetStub().GetFunctionAndParameters()
+	// Strip the contract name of "SmartContract:ReadAsset"
+	transaction := function[strings.LastIndex(function, ":")+1:]
+	return accessService(ctx).Authorize(ctx.GetClientIdentity(), transaction, args)
+}
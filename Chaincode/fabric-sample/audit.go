@@ -0,0 +1,96 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// AuditRecord says who changed a patient record, how and why. One is stored under
+// ("audit", nationalID, txID) for every transaction that writes or deletes the record.
+// DiseaseSlot is core.NoDiseaseSlot for changes that do not touch a single disease slot.
+type AuditRecord struct {
+	DocType           string `json:"docType"`
+	PatientNationalID int    `json:"patientNationalID"`
+	TxID              string `json:"txID"`
+	Timestamp         string `json:"timestamp"`
+	MSPID             string `json:"mspID"`
+	ClientID          string `json:"clientID"`
+	Action            string `json:"action"`
+	DiseaseSlot       int    `json:"diseaseSlot"`
+	Reason            string `json:"reason,omitempty" metadata:",optional"`
+}
+
+// PatientVersion is one version of a patient record from the ledger history
+type PatientVersion struct {
+	TxID      string       `json:"txID"`
+	Timestamp string       `json:"timestamp"`
+	IsDelete  bool         `json:"isDelete"`
+	Erased    bool         `json:"erased"`
+	Record    *PatientView `json:"record,omitempty" metadata:",optional"`
+	Audit     *AuditRecord `json:"audit,omitempty" metadata:",optional"`
+}
+
+// GetPatientHistory returns every version of the patient record, oldest first, with the
+// audit record of the transaction that wrote it. Deletions appear as versions with
+// IsDelete set and erasures as versions with Erased set. The history of an erased
+// patient keeps their audit trail but not the records written before the erasure.
+func (s *SmartContract) GetPatientHistory(ctx contractapi.TransactionContextInterface, patientNationalID string) (_ []*PatientVersion, err error) {
+	defer catalogError(&err)
+	history, err := auditService(ctx).History(patientNationalID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*PatientVersion, len(history))
+	for index, version := range history {
+		versions[index] = &PatientVersion{TxID: version.TxID, Timestamp: version.Timestamp, IsDelete: version.IsDelete, Erased: version.Erased}
+		if version.Record != nil {
+			patient, err := patientFromCore(version.Record)
+			if err != nil {
+				return nil, err
+			}
+			versions[index].Record = newPatientView(patient)
+		}
+		if version.Audit != nil {
+			versions[index].Audit, err = auditRecordFromCore(version.Audit)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return versions, nil
+}
+
+func (record *AuditRecord) toCore() *core.AuditRecord {
+	return &core.AuditRecord{
+		DocType:           record.DocType,
+		PatientNationalID: strconv.Itoa(record.PatientNationalID),
+		TxID:              record.TxID,
+		Timestamp:         record.Timestamp,
+		MSPID:             record.MSPID,
+		ClientID:          record.ClientID,
+		Action:            record.Action,
+		DiseaseSlot:       record.DiseaseSlot,
+		Reason:            record.Reason,
+	}
+}
+
+func auditRecordFromCore(record *core.AuditRecord) (*AuditRecord, error) {
+	nationalID, err := strconv.Atoi(record.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditRecord{
+		DocType:           record.DocType,
+		PatientNationalID: nationalID,
+		TxID:              record.TxID,
+		Timestamp:         record.Timestamp,
+		MSPID:             record.MSPID,
+		ClientID:          record.ClientID,
+		Action:            record.Action,
+		DiseaseSlot:       record.DiseaseSlot,
+		Reason:            record.Reason,
+	}, nil
+}
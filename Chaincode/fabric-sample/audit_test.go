@@ -0,0 +1,117 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+)
+
+// auditEntry is what a test expects of a version of a patient record
+type auditEntry struct {
+	txID        string
+	isDelete    bool
+	erased      bool
+	record      bool
+	action      string
+	diseaseSlot int
+	reason      string
+}
+
+// submit submits the transaction and returns its ID, failing the test when it is not
+// committed
+func (tb *testbed) submit(function string, args ...string) string {
+	tb.t.Helper()
+	result := tb.invoke(nil, function, args...)
+	if !result.Committed {
+		tb.t.Fatalf("%s %v: status %d: %s", function, args, result.Response.Status, result.Response.Message)
+	}
+	return result.TxID
+}
+
+// checkHistory fails the test unless the patient's history has the wanted versions
+func (tb *testbed) checkHistory(nationalID string, want []auditEntry) {
+	tb.t.Helper()
+	var versions []*PatientVersion
+	tb.mustDecode(&versions, "GetPatientHistory", nationalID)
+	if len(versions) != len(want) {
+		tb.t.Fatalf("history of %s has %d versions, want %d", nationalID, len(versions), len(want))
+	}
+	for index, version := range versions {
+		entry := want[index]
+		if version.TxID != entry.txID || version.IsDelete != entry.isDelete || version.Erased != entry.erased || (version.Record != nil) != entry.record {
+			tb.t.Errorf("version %d of %s = %+v, want %+v", index, nationalID, version, entry)
+		}
+		if version.Record != nil && strconv.Itoa(version.Record.PatientNationalID) != nationalID {
+			tb.t.Errorf("version %d of %s records patient %d", index, nationalID, version.Record.PatientNationalID)
+		}
+		if entry.action == "" {
+			if version.Audit != nil {
+				tb.t.Errorf("version %d of %s has audit record %+v, want none", index, nationalID, version.Audit)
+			}
+			continue
+		}
+		audit := version.Audit
+		if audit == nil || audit.TxID != entry.txID || audit.MSPID != "Org1MSP" || audit.Action != entry.action || audit.DiseaseSlot != entry.diseaseSlot || audit.Reason != entry.reason {
+			tb.t.Errorf("audit record of version %d of %s = %+v, want %+v", index, nationalID, audit, entry)
+		}
+	}
+}
+
+// The history lists each version of a record with the audit record of its transaction
+func TestGetPatientHistory(t *testing.T) {
+	tb := newTestbed(t)
+	// A version written before the audit trail existed
+	key := familyKey(t, "22")
+	legacy := chaincodeFunc(func(stub shim.ChaincodeStubInterface) pb.Response {
+		patient := Patient{PatientName: "Deniz Kaya", PatientNationalID: 131, PatientFamilyID: 22, DocType: core.PatientNamespace}
+		for index := range patient.PatientDiseaseTable {
+			patient.PatientDiseaseTable[index], _ = key.Pk.Encrypt(0)
+			patient.PatientGenotypes[index], _ = key.Pk.Encrypt(0)
+		}
+		patientJSON, err := json.Marshal(patient)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		patientID, err := stub.CreateCompositeKey(core.PatientNamespace, []string{"131"})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutState(patientID, patientJSON)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	})
+	legacyTx := tb.ledger.Invoke(legacy, ledgersim.Transaction{Function: "legacy"}).TxID
+	changeTx := tb.submit("ChangeAsset", "131", "1", "lab result")
+	deleteTx := tb.submit("DeleteAsset", "131")
+	tb.checkHistory("131", []auditEntry{
+		{txID: legacyTx, record: true},
+		{txID: changeTx, record: true, action: core.AuditDiseaseChange, diseaseSlot: 1, reason: "lab result"},
+		{txID: deleteTx, isDelete: true, action: core.AuditDelete, diseaseSlot: core.NoDiseaseSlot},
+	})
+}
+
+// The history of an erased patient keeps the audit trail and the tombstone but none of
+// the records written before it
+func TestGetPatientHistoryAfterErasure(t *testing.T) {
+	tb := newTestbed(t)
+	createTx := tb.submit("CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	changeTx := tb.submit("ChangeAsset", "130", "0", "lab result")
+	tb.checkHistory("130", []auditEntry{
+		{txID: createTx, record: true, action: core.AuditCreate, diseaseSlot: core.NoDiseaseSlot},
+		{txID: changeTx, record: true, action: core.AuditDiseaseChange, diseaseSlot: 0, reason: "lab result"},
+	})
+
+	_, eraseTx := tb.erase("ErasePatient", "130", core.ReasonSubjectRequest)
+	tb.checkHistory("130", []auditEntry{
+		{txID: createTx, action: core.AuditCreate, diseaseSlot: core.NoDiseaseSlot},
+		{txID: changeTx, action: core.AuditDiseaseChange, diseaseSlot: 0, reason: "lab result"},
+		{txID: eraseTx, erased: true, action: core.AuditErase, diseaseSlot: core.NoDiseaseSlot, reason: core.ReasonSubjectRequest},
+	})
+}
@@ -0,0 +1,34 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// SetBirthYear records the year the patient was born. Risk calculations read the age of
+// an unaffected relative from it to tell how much their being unaffected says about a
+// late-onset disease; relatives with no birth year recorded are not down-weighted.
+func (s *SmartContract) SetBirthYear(ctx contractapi.TransactionContextInterface, patientNationalID int, birthYear int) (err error) {
+	defer catalogError(&err)
+	currentYear, err := core.Year(newTransaction(ctx))
+	if err != nil {
+		return err
+	}
+	err = core.ValidateBirthYear(birthYear, currentYear)
+	if err != nil {
+		return err
+	}
+	patient, err := readLivePatient(ctx, strconv.Itoa(patientNationalID))
+	if err != nil {
+		return err
+	}
+
+	patient.PatientBirthYear = birthYear
+	err = putPatient(ctx, patient)
+	if err != nil {
+		return err
+	}
+	return auditService(ctx).Record(strconv.Itoa(patientNationalID), core.AuditBirthYearChange, core.NoDiseaseSlot, strconv.Itoa(birthYear))
+}
@@ -0,0 +1,59 @@
+package chaincode
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// An unaffected relative old enough to have shown a disease counts against it in the
+// family evidence, but never takes the risk below zero. Parent 116 is 70 in 2026, when
+// type 2 diabetes has shown in 50% of carriers and achondroplasia in all of them, so
+// they take 70/1 * 50% or 50/1 * 100% off the evidence.
+func TestPenetrance(t *testing.T) {
+	tests := []struct {
+		name         string
+		diseaseIndex int
+		affected     []string
+		risk         int64
+		evidence     int64
+		penetrance   int
+	}{
+		// 70/2 + 70/2 for grandparents 119 and 120, who have it from InitLedger
+		{name: "affected grandparents", diseaseIndex: 1, risk: 70, evidence: 35, penetrance: 50},
+		{name: "no affected relative", diseaseIndex: 2, risk: 0, evidence: -50, penetrance: 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := riskCase{diseaseIndex: test.diseaseIndex, affected: test.affected, consenting: []string{"115", "116", "119", "120"}}.setUp(t)
+			checkResult(t, tb.invoke(nil, "SetBirthYear", "116", "2030"), "birthYear")
+			tb.mustInvoke(nil, "SetBirthYear", "116", "1956")
+			diseaseIndex := strconv.Itoa(test.diseaseIndex)
+
+			var result RiskResult
+			tb.mustDecode(&result, "TransferAsset", "130", diseaseIndex)
+			if result.Risk != test.risk {
+				t.Errorf("risk = %d, want %d", result.Risk, test.risk)
+			}
+			posterior, _ := new(big.Int).SetString(result.EncryptedLogOdds, 16)
+			logOdds, err := core.DecryptPosterior(familyKey(t, "22"), posterior, result.PriorLogOdds, result.MaxEvidence)
+			if err != nil || logOdds-result.PriorLogOdds != test.evidence {
+				t.Errorf("evidence = %d (%v), want %d", logOdds-result.PriorLogOdds, err, test.evidence)
+			}
+
+			var explanation RiskExplanation
+			tb.mustDecode(&explanation, "ExplainRisk", "130", diseaseIndex)
+			for _, term := range explanation.Terms {
+				want := 0
+				if term.PatientNationalID == 116 {
+					want = test.penetrance
+				}
+				if term.Penetrance != want {
+					t.Errorf("relative %d has penetrance %d, want %d", term.PatientNationalID, term.Penetrance, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Command genchain-sim runs a transaction script against the GenChain contract on an
+// in-memory ledger. See ledgersim.Main for the script format.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+)
+
+func main() {
+	cc, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating chaincode: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(ledgersim.Main(cc, os.Args[1:]))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+)
+
+// Every scenario runs to the end with its expectations met
+func TestScenarios(t *testing.T) {
+	scenarios, err := filepath.Glob("scenarios/*.txt")
+	if err != nil || len(scenarios) == 0 {
+		t.Fatalf("no scenarios found (%v)", err)
+	}
+	for _, scenario := range scenarios {
+		t.Run(filepath.Base(scenario), func(t *testing.T) {
+			cc, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			file, err := os.Open(scenario)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			var report strings.Builder
+			failed, err := ledgersim.NewRunner(ledgersim.New(), cc, &report).Run(file)
+			if err != nil || failed > 0 {
+				t.Errorf("%d failures (%v):\n%s", failed, err, report.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// Vectors is the test vector file shared with the Python demo in "Paillier Example".
+// Every number is a JSON integer, which Python reads without loss at any size.
+type Vectors struct {
+	Description string         `json:"description"`
+	Keys        []KeyVector    `json:"keys"`
+	Encryptions []Encryption   `json:"encryptions"`
+	Homomorphic []Homomorphic  `json:"homomorphic"`
+	Pedigrees   []PedigreeRisk `json:"pedigrees"`
+}
+
+// KeyVector is a key pair given by its primes. Lambda is either φ(N), as the chaincode
+// derives it, or lcm(p-1, q-1), as the Python demo does; both decrypt.
+type KeyVector struct {
+	Name   string   `json:"name"`
+	P      *big.Int `json:"p"`
+	Q      *big.Int `json:"q"`
+	N      *big.Int `json:"n"`
+	G      *big.Int `json:"g"`
+	Lambda *big.Int `json:"lambda"`
+	Mu     *big.Int `json:"mu"`
+}
+
+// Encryption is the ciphertext of a plaintext under a key with randomness R
+type Encryption struct {
+	Key        string   `json:"key"`
+	Plaintext  int64    `json:"plaintext"`
+	R          *big.Int `json:"r"`
+	Ciphertext *big.Int `json:"ciphertext"`
+}
+
+// Homomorphic is an operation on the ciphertexts of A and, for add and sub, B. For
+// multPlaintext and addPlaintext, B is the plaintext operand and RB is unused.
+type Homomorphic struct {
+	Key        string   `json:"key"`
+	Operation  string   `json:"operation"`
+	A          int64    `json:"a"`
+	RA         *big.Int `json:"rA"`
+	B          int64    `json:"b"`
+	RB         *big.Int `json:"rB,omitempty"`
+	Ciphertext *big.Int `json:"ciphertext"`
+	Plaintext  int64    `json:"plaintext"`
+}
+
+// PedigreeRisk is a family risk computed the way the chaincode computes it: the sum of
+// each relative's encrypted disease flag times weight/level, starting from Enc(0, R0)
+type PedigreeRisk struct {
+	Key        string     `json:"key"`
+	Weight     int64      `json:"weight"`
+	R0         *big.Int   `json:"r0"`
+	Relatives  []Relative `json:"relatives"`
+	Ciphertext *big.Int   `json:"ciphertext"`
+	Risk       int64      `json:"risk"`
+}
+
+// Relative is one member of a pedigree, with the randomness of their flag's ciphertext
+type Relative struct {
+	Name     string   `json:"name"`
+	Level    int64    `json:"level"`
+	Affected int64    `json:"affected"`
+	R        *big.Int `json:"r"`
+}
+
+// homomorphicOperations are the operations covered by the vectors
+var homomorphicOperations = []string{"add", "sub", "multPlaintext", "addPlaintext"}
+
+// generate builds the vectors for the demo's primes p=17 and q=19
+func generate() (*Vectors, error) {
+	vectors := &Vectors{
+		Description: "Paillier test vectors shared by the Go chaincode library and the Python demo. " +
+			"Regenerate with: go run ./cmd/paillier-vectors -o \"../../Paillier Example/testvectors.json\"",
+	}
+
+	p, q := big.NewInt(17), big.NewInt(19)
+	chaincodeKey := chaincodeKeyPair(p, q)
+	demoKey, err := demoKeyPair(p, q, big.NewInt(48))
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]*Pailler.PrivateKey{"chaincode": chaincodeKey, "demo": demoKey}
+
+	randomness := []int64{23, 45, 101, 150}
+	plaintexts := []int64{0, 1, 2, 10, 25, 100, 321, 322}
+	for _, name := range []string{"chaincode", "demo"} {
+		key := keys[name]
+		vectors.Keys = append(vectors.Keys, KeyVector{
+			Name:   name,
+			P:      p,
+			Q:      q,
+			N:      key.Pk.N,
+			G:      key.Pk.G,
+			Lambda: key.Lambda,
+			Mu:     key.Mu,
+		})
+
+		for i, plaintext := range plaintexts {
+			r := big.NewInt(randomness[i%len(randomness)])
+			ciphertext, err := key.Pk.EncryptWithR(plaintext, r)
+			if err != nil {
+				return nil, err
+			}
+			vectors.Encryptions = append(vectors.Encryptions, Encryption{Key: name, Plaintext: plaintext, R: r, Ciphertext: ciphertext})
+		}
+
+		for _, operation := range homomorphicOperations {
+			for _, operands := range [][2]int64{{10, 2}, {2, 10}, {0, 0}, {322, 1}, {100, 25}} {
+				vector := Homomorphic{Key: name, Operation: operation, A: operands[0], RA: big.NewInt(23), B: operands[1]}
+				if operation == "add" || operation == "sub" {
+					vector.RB = big.NewInt(101)
+				}
+				vector.Ciphertext, vector.Plaintext, err = evaluate(key, vector)
+				if err != nil {
+					return nil, err
+				}
+				vectors.Homomorphic = append(vectors.Homomorphic, vector)
+			}
+		}
+
+		// The demo's family: parents at level 1 and the aunt and grandfather at level 2
+		for _, weight := range []int64{25, 100} {
+			pedigree := PedigreeRisk{
+				Key:    name,
+				Weight: weight,
+				R0:     big.NewInt(45),
+				Relatives: []Relative{
+					{Name: "father", Level: 1, Affected: 0, R: big.NewInt(23)},
+					{Name: "mother", Level: 1, Affected: 1, R: big.NewInt(45)},
+					{Name: "aunt", Level: 2, Affected: 0, R: big.NewInt(101)},
+					{Name: "grandfather", Level: 2, Affected: 1, R: big.NewInt(150)},
+				},
+			}
+			pedigree.Ciphertext, pedigree.Risk, err = pedigreeRisk(key, pedigree)
+			if err != nil {
+				return nil, err
+			}
+			vectors.Pedigrees = append(vectors.Pedigrees, pedigree)
+		}
+	}
+	return vectors, nil
+}
+
+// chaincodeKeyPair builds a key the way GenerateKeyPairFromSeed does from its primes,
+// with g = N+1 and lambda = φ(N)
+func chaincodeKeyPair(p, q *big.Int) *Pailler.PrivateKey {
+	n := new(big.Int).Mul(p, q)
+	nn := new(big.Int).Mul(n, n)
+	lambda := new(big.Int).Mul(new(big.Int).Sub(p, big.NewInt(1)), new(big.Int).Sub(q, big.NewInt(1)))
+	mu := new(big.Int).ModInverse(lambda, n)
+	g := new(big.Int).Add(n, big.NewInt(1))
+	return &Pailler.PrivateKey{Mu: mu, Lambda: lambda, Pk: &Pailler.PublicKey{N: n, G: g, N2: nn}}
+}
+
+// demoKeyPair builds a key the way Paillier.py does, with lambda = lcm(p-1, q-1) and
+// the given generator g
+func demoKeyPair(p, q, g *big.Int) (*Pailler.PrivateKey, error) {
+	n := new(big.Int).Mul(p, q)
+	nn := new(big.Int).Mul(n, n)
+	p1 := new(big.Int).Sub(p, big.NewInt(1))
+	q1 := new(big.Int).Sub(q, big.NewInt(1))
+	gcd := new(big.Int).GCD(nil, nil, p1, q1)
+	lambda := new(big.Int).Mul(p1, q1)
+	lambda.Div(lambda, gcd)
+
+	mu := new(big.Int).ModInverse(Pailler.L(new(big.Int).Exp(g, lambda, nn), n), n)
+	if mu == nil {
+		return nil, fmt.Errorf("g = %v is not a valid generator", g)
+	}
+	return &Pailler.PrivateKey{Mu: mu, Lambda: lambda, Pk: &Pailler.PublicKey{N: n, G: g, N2: nn}}, nil
+}
+
+// evaluate performs a homomorphic operation and decrypts its result
+func evaluate(key *Pailler.PrivateKey, vector Homomorphic) (*big.Int, int64, error) {
+	a, err := key.Pk.EncryptWithR(vector.A, vector.RA)
+	if err != nil {
+		return nil, 0, err
+	}
+	var result *big.Int
+	switch vector.Operation {
+	case "add", "sub":
+		b, err := key.Pk.EncryptWithR(vector.B, vector.RB)
+		if err != nil {
+			return nil, 0, err
+		}
+		if vector.Operation == "add" {
+			result, err = key.Pk.Add(a, b)
+		} else {
+			result, err = key.Pk.Sub(a, b)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	case "multPlaintext":
+		result, err = key.Pk.MultPlaintext(a, vector.B)
+	case "addPlaintext":
+		result, err = key.Pk.AddPlaintext(a, vector.B)
+	default:
+		return nil, 0, fmt.Errorf("unknown operation %q", vector.Operation)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	plaintext, err := key.Decrypt(result)
+	if err != nil {
+		return nil, 0, err
+	}
+	return result, plaintext, nil
+}
+
+// pedigreeRisk computes a pedigree's encrypted risk and decrypts it
+func pedigreeRisk(key *Pailler.PrivateKey, pedigree PedigreeRisk) (*big.Int, int64, error) {
+	risk, err := key.Pk.EncryptWithR(0, pedigree.R0)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, relative := range pedigree.Relatives {
+		flag, err := key.Pk.EncryptWithR(relative.Affected, relative.R)
+		if err != nil {
+			return nil, 0, err
+		}
+		contribution, err := key.Pk.MultPlaintext(flag, pedigree.Weight/relative.Level)
+		if err != nil {
+			return nil, 0, err
+		}
+		risk, err = key.Pk.Add(risk, contribution)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	plaintext, err := key.Decrypt(risk)
+	if err != nil {
+		return nil, 0, err
+	}
+	return risk, plaintext, nil
+}
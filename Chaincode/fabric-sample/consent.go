@@ -0,0 +1,87 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// ConsentRecord is a patient's consent to one organization using their record for one
+// purpose, stored under ("consent", nationalID, purpose, organization). A revoked or
+// expired record is kept so the grant stays on the ledger.
+type ConsentRecord struct {
+	DocType           string `json:"docType"`
+	PatientNationalID int    `json:"patientNationalID"`
+	Purpose           string `json:"purpose"`
+	Organization      string `json:"organization"`
+	GrantedBy         string `json:"grantedBy"`
+	GrantedAt         string `json:"grantedAt"`
+	ExpiresAt         string `json:"expiresAt,omitempty" metadata:",optional"`
+	Revoked           bool   `json:"revoked"`
+	RevokedAt         string `json:"revokedAt,omitempty" metadata:",optional"`
+}
+
+// GrantConsent lets the organization with the given MSP ID use the patient's record for
+// the purpose until `expiresAt` (RFC3339), or indefinitely when it is empty. A guardian
+// grants consent with a patient identity issued for their ward.
+func (s *SmartContract) GrantConsent(ctx contractapi.TransactionContextInterface, patientNationalID string, purpose string, organization string, expiresAt string) (err error) {
+	defer catalogError(&err)
+	return consentService(ctx).Grant(patientNationalID, purpose, organization, expiresAt)
+}
+
+// RevokeConsent withdraws a consent given with GrantConsent
+func (s *SmartContract) RevokeConsent(ctx contractapi.TransactionContextInterface, patientNationalID string, purpose string, organization string) (err error) {
+	defer catalogError(&err)
+	return consentService(ctx).Revoke(patientNationalID, purpose, organization)
+}
+
+// GetConsents returns every consent the patient has granted, including revoked and
+// expired ones
+func (s *SmartContract) GetConsents(ctx contractapi.TransactionContextInterface, patientNationalID string) (_ []*ConsentRecord, err error) {
+	defer catalogError(&err)
+	records, err := consentService(ctx).List(patientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	consents := make([]*ConsentRecord, len(records))
+	for index, record := range records {
+		consents[index], err = consentRecordFromCore(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return consents, nil
+}
+
+func (consent *ConsentRecord) toCore() *core.ConsentRecord {
+	return &core.ConsentRecord{
+		DocType:           consent.DocType,
+		PatientNationalID: strconv.Itoa(consent.PatientNationalID),
+		Purpose:           consent.Purpose,
+		Organization:      consent.Organization,
+		GrantedBy:         consent.GrantedBy,
+		GrantedAt:         consent.GrantedAt,
+		ExpiresAt:         consent.ExpiresAt,
+		Revoked:           consent.Revoked,
+		RevokedAt:         consent.RevokedAt,
+	}
+}
+
+func consentRecordFromCore(consent *core.ConsentRecord) (*ConsentRecord, error) {
+	nationalID, err := strconv.Atoi(consent.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsentRecord{
+		DocType:           consent.DocType,
+		PatientNationalID: nationalID,
+		Purpose:           consent.Purpose,
+		Organization:      consent.Organization,
+		GrantedBy:         consent.GrantedBy,
+		GrantedAt:         consent.GrantedAt,
+		ExpiresAt:         consent.ExpiresAt,
+		Revoked:           consent.Revoked,
+		RevokedAt:         consent.RevokedAt,
+	}, nil
+}
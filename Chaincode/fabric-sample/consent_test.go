@@ -0,0 +1,83 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Revoking a relative's consent takes them out of every risk computation, including the
+// ones under a patient key or a caller's computation key
+func TestRevokedConsentExcludesRelative(t *testing.T) {
+	tb := newTestbed(t)
+	patientSeed := []byte("genchain test patient key seed, 32 bytes or more")
+	tb.mustInvoke(map[string][]byte{core.KeySeedField: patientSeed}, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	tb.mustInvoke(nil, "ChangeAsset", "115", "0", "lab result")
+
+	_, patientKey, err := core.DerivePatientKey("130", patientSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, computationKey, err := core.DerivePatientKey("computation", patientSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	risks := func() map[string]int64 {
+		t.Helper()
+		crossFamily := tb.mustInvoke(nil, "CalculateCrossFamilyRisk", "130", "0")
+		forKey := tb.mustInvoke(nil, "ComputeRiskForKey", "130", "0", computationKey.Pk.N.Text(16), computationKey.Pk.G.Text(16))
+		return map[string]int64{
+			"CalculateCrossFamilyRisk": decryptHex(t, patientKey, crossFamily),
+			"ComputeRiskForKey":        decryptHex(t, computationKey, forKey),
+		}
+	}
+
+	// Parent 115 is affected and weighs 100 once they consent
+	tb.mustInvoke(nil, "GrantConsent", "115", core.PurposeRiskComputation, "Org1MSP", "")
+	for function, risk := range risks() {
+		if risk != 100 {
+			t.Errorf("%s with consent = %d, want 100", function, risk)
+		}
+	}
+
+	tb.mustInvoke(nil, "RevokeConsent", "115", core.PurposeRiskComputation, "Org1MSP")
+	for function, risk := range risks() {
+		if risk != 0 {
+			t.Errorf("%s after the revocation = %d, want 0", function, risk)
+		}
+	}
+}
+
+// GetConsents returns every grant, with the expiry and revocation only when there is one
+func TestGetConsents(t *testing.T) {
+	tb := newTestbed(t)
+	tb.mustInvoke(nil, "GrantConsent", "115", core.PurposeRiskComputation, "Org1MSP", "")
+	tb.mustInvoke(nil, "GrantConsent", "115", core.PurposeResearchAggregation, "Org2MSP", "2027-01-01T00:00:00Z")
+	tb.mustInvoke(nil, "RevokeConsent", "115", core.PurposeRiskComputation, "Org1MSP")
+
+	var consents []*ConsentRecord
+	tb.mustDecode(&consents, "GetConsents", "115")
+	type grant struct {
+		purpose, organization, expiresAt string
+		revoked                          bool
+	}
+	want := []grant{
+		{purpose: core.PurposeResearchAggregation, organization: "Org2MSP", expiresAt: "2027-01-01T00:00:00Z"},
+		{purpose: core.PurposeRiskComputation, organization: "Org1MSP", revoked: true},
+	}
+	if len(consents) != len(want) {
+		t.Fatalf("got %d consents, want %d", len(consents), len(want))
+	}
+	for index, consent := range consents {
+		got := grant{purpose: consent.Purpose, organization: consent.Organization, expiresAt: consent.ExpiresAt, revoked: consent.Revoked}
+		if got != want[index] {
+			t.Errorf("consent %d = %+v, want %+v", index, got, want[index])
+		}
+		if consent.PatientNationalID != 115 || consent.GrantedAt == "" || consent.GrantedBy == "" {
+			t.Errorf("consent %d: patient %d granted by %q at %q", index, consent.PatientNationalID, consent.GrantedBy, consent.GrantedAt)
+		}
+		if consent.Revoked != (consent.RevokedAt != "") {
+			t.Errorf("consent %d: revoked %v at %q", index, consent.Revoked, consent.RevokedAt)
+		}
+	}
+}
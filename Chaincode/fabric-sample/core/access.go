@@ -0,0 +1,230 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Client certificate attributes read by the access check. The role is one of the roles
+// below, the families are a comma separated list of family IDs or "*" for every family,
+// and the national ID ties a patient identity to their own record.
+const (
+	RoleAttribute       = "genchain.role"
+	FamiliesAttribute   = "genchain.families"
+	NationalIDAttribute = "genchain.nationalID"
+)
+
+// Roles that can be granted transactions in the access policy
+const (
+	RoleClinician     = "clinician"
+	RoleLab           = "lab"
+	RoleGeneticist    = "geneticist"
+	RolePatient       = "patient"
+	RoleRegistryAdmin = "registry-admin"
+)
+
+// AccessPolicy maps each role to the transactions it may call. "*" grants every transaction.
+type AccessPolicy struct {
+	Roles map[string][]string `json:"roles"`
+}
+
+// ScopeArgument names a transaction argument that selects a patient, or a family when
+// Family is set
+type ScopeArgument struct {
+	Index  int
+	Family bool
+}
+
+// AccessRules describe a chaincode's transactions to the access check. Transactions are
+// the names a policy may grant, Scopes lists for every transaction the arguments
+// checked against the client's families, and SetPolicy is the transaction that replaces
+// the policy, which the registry admin must keep. Transactions with no scope read or
+// write across families and are only allowed to identities scoped to every family.
+type AccessRules struct {
+	Transactions []string
+	Scopes       map[string][]ScopeArgument
+	SetPolicy    string
+	Default      AccessPolicy
+}
+
+// AccessService stores the access policy and checks transactions against it
+type AccessService struct {
+	State Store
+	Rules *AccessRules
+}
+
+// policyKey addresses the stored access policy
+var policyKey = NewKey(PolicyNamespace, "access")
+
+// Policy returns the stored policy, or the default one when none is stored
+func (s *AccessService) Policy() (*AccessPolicy, error) {
+	policyJSON, err := s.State.Get(policyKey)
+	if err != nil {
+		return nil, err
+	}
+	if policyJSON == nil {
+		return &s.Rules.Default, nil
+	}
+	policy := new(AccessPolicy)
+	err = json.Unmarshal(policyJSON, policy)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SetPolicy validates the policy and replaces the stored one with it
+func (s *AccessService) SetPolicy(policyJSON string) error {
+	policy := new(AccessPolicy)
+	decoder := json.NewDecoder(strings.NewReader(policyJSON))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(policy)
+	if err != nil {
+		return InvalidArgument("policy", "invalid access policy: %v", err)
+	}
+	err = s.validate(policy)
+	if err != nil {
+		return err
+	}
+
+	stored, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return s.State.Put(policyKey, stored)
+}
+
+// Authorize checks the client's role against the access policy and the transaction's
+// patient and family arguments against the client's families
+func (s *AccessService) Authorize(identity Identity, transaction string, args []string) error {
+	role, found, err := identity.GetAttributeValue(RoleAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to read client attributes: %v", err)
+	}
+	if !found {
+		return AccessDenied(transaction, "", "the identity has no "+RoleAttribute+" attribute")
+	}
+
+	policy, err := s.Policy()
+	if err != nil {
+		return err
+	}
+	if !policy.allows(role, transaction) {
+		return AccessDenied(transaction, role, "not granted by the access policy")
+	}
+
+	familiesValue, _, err := identity.GetAttributeValue(FamiliesAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to read client attributes: %v", err)
+	}
+	families := strings.Split(familiesValue, ",")
+	allFamilies := familiesValue == "*"
+
+	scopes, scoped := s.Rules.Scopes[transaction]
+	if !scoped {
+		if !allFamilies {
+			return AccessDenied(transaction, role, "the transaction spans families")
+		}
+		return nil
+	}
+
+	nationalID := ""
+	if role == RolePatient {
+		nationalID, _, err = identity.GetAttributeValue(NationalIDAttribute)
+		if err != nil {
+			return fmt.Errorf("failed to read client attributes: %v", err)
+		}
+	}
+
+	for _, scope := range scopes {
+		if scope.Index >= len(args) {
+			continue
+		}
+		arg := args[scope.Index]
+		if scope.Family && arg == "" {
+			// An optional family filter that is not set lists every family
+			if !allFamilies {
+				return AccessDenied(transaction, role, "the transaction spans families")
+			}
+			continue
+		}
+		if role == RolePatient && (scope.Family || arg != nationalID) {
+			return AccessDenied(transaction, role, "patients may only access their own record")
+		}
+
+		familyID := arg
+		if !scope.Family {
+			familyID, err = s.patientFamily(arg)
+			if err != nil {
+				return err
+			}
+			if familyID == "" {
+				// Missing patients are reported by the transaction itself
+				continue
+			}
+		}
+		if !allFamilies && !containsString(families, familyID) {
+			return AccessDenied(transaction, role, "family "+familyID+" is outside the identity's scope")
+		}
+	}
+	return nil
+}
+
+// patientFamily returns the family ID of a patient or tombstone, or "" when there is
+// none. Chaincodes store the ID as a number or as a string, and json.Number reads both.
+func (s *AccessService) patientFamily(nationalID string) (string, error) {
+	value, err := s.State.Get(NewKey(PatientNamespace, nationalID))
+	if err != nil || value == nil {
+		return "", err
+	}
+	var record struct {
+		PatientFamilyID json.Number `json:"patientFamilyID"`
+	}
+	err = json.Unmarshal(value, &record)
+	if err != nil {
+		return "", err
+	}
+	return record.PatientFamilyID.String(), nil
+}
+
+func (policy *AccessPolicy) allows(role string, transaction string) bool {
+	granted := policy.Roles[role]
+	return containsString(granted, "*") || containsString(granted, transaction)
+}
+
+// validate rejects unknown roles and transactions, and policies that would lock the
+// registry admin out of changing the policy again
+func (s *AccessService) validate(policy *AccessPolicy) error {
+	roles := make([]string, 0, len(policy.Roles))
+	for role := range policy.Roles {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		switch role {
+		case RoleClinician, RoleLab, RoleGeneticist, RolePatient, RoleRegistryAdmin:
+		default:
+			return InvalidArgument("policy", "unknown role %q", role)
+		}
+		for _, transaction := range policy.Roles[role] {
+			if transaction != "*" && !containsString(s.Rules.Transactions, transaction) {
+				return InvalidArgument("policy", "unknown transaction %q for role %q", transaction, role)
+			}
+		}
+	}
+	if !policy.allows(RoleRegistryAdmin, s.Rules.SetPolicy) {
+		return InvalidArgument("policy", "the %s role must keep %s", RoleRegistryAdmin, s.Rules.SetPolicy)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package core
+
+import "testing"
+
+// attributes is an Identity with the given certificate attributes
+type attributes map[string]string
+
+func (a attributes) GetID() (string, error) {
+	return "x509::CN=" + a[RoleAttribute], nil
+}
+
+func (a attributes) GetMSPID() (string, error) {
+	return "Org1MSP", nil
+}
+
+func (a attributes) GetAttributeValue(name string) (string, bool, error) {
+	value, found := a[name]
+	return value, found, nil
+}
+
+var testAccessRules = &AccessRules{
+	Transactions: []string{"read", "create", "list", "setPolicy"},
+	Scopes: map[string][]ScopeArgument{
+		"read":   {{Index: 0}},
+		"create": {{Index: 1, Family: true}},
+	},
+	SetPolicy: "setPolicy",
+	Default: AccessPolicy{Roles: map[string][]string{
+		RoleRegistryAdmin: {"*"},
+		RoleClinician:     {"read", "create", "list"},
+		RolePatient:       {"read"},
+	}},
+}
+
+// errorCode is the catalog code err is reported under, or "" for no error
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	return AsChaincodeError(err).Code
+}
+
+// Authorize checks the role, the families of scoped arguments, and patients' own
+// records. Patient records may hold the family ID as a number or as a string.
+func TestAccessServiceAuthorize(t *testing.T) {
+	state := newMemStore()
+	state.Put(NewKey(PatientNamespace, "115"), []byte(`{"patientFamilyID":22}`))
+	state.Put(NewKey(PatientNamespace, "111"), []byte(`{"patientFamilyID":"20"}`))
+	access := &AccessService{State: state, Rules: testAccessRules}
+
+	clinician := attributes{RoleAttribute: RoleClinician, FamiliesAttribute: "22"}
+	tests := []struct {
+		name        string
+		identity    attributes
+		transaction string
+		args        []string
+		code        string
+	}{
+		{name: "no role", identity: attributes{}, transaction: "read", args: []string{"115"}, code: CodeAccessDenied},
+		{name: "not granted", identity: attributes{RoleAttribute: RoleLab, FamiliesAttribute: "*"}, transaction: "read", args: []string{"115"}, code: CodeAccessDenied},
+		{name: "numeric family", identity: clinician, transaction: "read", args: []string{"115"}},
+		{name: "string family", identity: clinician, transaction: "read", args: []string{"111"}, code: CodeAccessDenied},
+		{name: "missing patient", identity: clinician, transaction: "read", args: []string{"999"}},
+		{name: "family argument", identity: clinician, transaction: "create", args: []string{"130", "22"}},
+		{name: "other family argument", identity: clinician, transaction: "create", args: []string{"130", "20"}, code: CodeAccessDenied},
+		{name: "unscoped", identity: clinician, transaction: "list", code: CodeAccessDenied},
+		{name: "unscoped for every family", identity: attributes{RoleAttribute: RoleClinician, FamiliesAttribute: "*"}, transaction: "list"},
+		{name: "own record", identity: attributes{RoleAttribute: RolePatient, FamiliesAttribute: "22", NationalIDAttribute: "115"}, transaction: "read", args: []string{"115"}},
+		{name: "other record", identity: attributes{RoleAttribute: RolePatient, FamiliesAttribute: "*", NationalIDAttribute: "116"}, transaction: "read", args: []string{"115"}, code: CodeAccessDenied},
+	}
+	for _, test := range tests {
+		err := access.Authorize(test.identity, test.transaction, test.args)
+		if code := errorCode(err); code != test.code {
+			t.Errorf("%s: Authorize = %v, want %q", test.name, err, test.code)
+		}
+	}
+}
+
+// A stored policy replaces the default one, and policies that grant unknown names or
+// take the policy away from the registry admin are rejected
+func TestAccessServiceSetPolicy(t *testing.T) {
+	access := &AccessService{State: newMemStore(), Rules: testAccessRules}
+	for _, policy := range []string{
+		`{"roles":{"auditor":["read"]}}`,
+		`{"roles":{"lab":["write"]}}`,
+		`{"roles":{"registry-admin":["read"]}}`,
+		`{"roles":{},"extra":true}`,
+	} {
+		if code := errorCode(access.SetPolicy(policy)); code != CodeInvalidArgument {
+			t.Errorf("SetPolicy(%s) = %q, want %q", policy, code, CodeInvalidArgument)
+		}
+	}
+
+	lab := attributes{RoleAttribute: RoleLab, FamiliesAttribute: "*"}
+	if err := access.Authorize(lab, "list", nil); errorCode(err) != CodeAccessDenied {
+		t.Errorf("Authorize under the default policy = %v", err)
+	}
+	err := access.SetPolicy(`{"roles":{"registry-admin":["setPolicy"],"lab":["list"]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := access.Authorize(lab, "list", nil); err != nil {
+		t.Errorf("Authorize under the stored policy = %v", err)
+	}
+	policy, err := access.Policy()
+	if err != nil || len(policy.Roles) != 2 {
+		t.Errorf("Policy = %v (%v)", policy, err)
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import "fmt"
+
+// Actions recorded in the audit trail
+const (
+	AuditCreate          = "CREATE"
+	AuditDiseaseChange   = "DISEASE_CHANGE"
+	AuditGenotypeChange  = "GENOTYPE_CHANGE"
+	AuditBirthYearChange = "BIRTH_YEAR_CHANGE"
+	AuditConsentChange   = "CONSENT_CHANGE"
+	AuditDelete          = "DELETE"
+	AuditErase           = "ERASE"
+	AuditReKey           = "REKEY"
+	AuditMigrate         = "MIGRATE"
+)
+
+// NoDiseaseSlot marks audit records of changes that do not touch a single disease slot
+const NoDiseaseSlot = -1
+
+// AuditRecord says who changed a patient record, how and why. One is stored under
+// ("audit", nationalID, txID) for every transaction that writes or deletes the record.
+// DiseaseSlot is NoDiseaseSlot for changes that do not touch a single disease slot.
+type AuditRecord struct {
+	DocType           string `json:"docType"`
+	PatientNationalID string `json:"patientNationalID"`
+	TxID              string `json:"txID"`
+	Timestamp         string `json:"timestamp"`
+	MSPID             string `json:"mspID"`
+	ClientID          string `json:"clientID"`
+	Action            string `json:"action"`
+	DiseaseSlot       int    `json:"diseaseSlot"`
+	Reason            string `json:"reason,omitempty" metadata:",optional"`
+}
+
+// PatientVersion is one version of a patient record from the ledger history. Record is
+// nil for deletions, erasures and the versions an erasure hides.
+type PatientVersion struct {
+	TxID      string
+	Timestamp string
+	IsDelete  bool
+	Erased    bool
+	Record    *Patient
+	Audit     *AuditRecord
+}
+
+// AuditService keeps the audit trail of patient records
+type AuditService struct {
+	State    Store
+	Codec    RecordCodec
+	Patients PatientCodec
+	Tx       Transaction
+}
+
+// Record stores the audit record of the current transaction for a patient
+func (s *AuditService) Record(nationalID string, action string, diseaseSlot int, reason string) error {
+	timestamp, err := Timestamp(s.Tx)
+	if err != nil {
+		return err
+	}
+	client, err := s.Tx.Client()
+	if err != nil {
+		return fmt.Errorf("failed to read the client identity: %v", err)
+	}
+	mspID, err := client.GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to read client MSP ID: %v", err)
+	}
+	clientID, err := client.GetID()
+	if err != nil {
+		return fmt.Errorf("failed to read client ID: %v", err)
+	}
+
+	record := &AuditRecord{
+		DocType:           AuditDocType,
+		PatientNationalID: nationalID,
+		TxID:              s.Tx.TxID(),
+		Timestamp:         timestamp,
+		MSPID:             mspID,
+		ClientID:          clientID,
+		Action:            action,
+		DiseaseSlot:       diseaseSlot,
+		Reason:            reason,
+	}
+	value, err := s.Codec.Encode(record)
+	if err != nil {
+		return err
+	}
+	return s.State.Put(NewKey(AuditNamespace, nationalID, record.TxID), value)
+}
+
+// Get returns the audit record a transaction left for a patient, or nil for
+// transactions from before the audit trail existed
+func (s *AuditService) Get(nationalID string, txID string) (*AuditRecord, error) {
+	value, err := s.State.Get(NewKey(AuditNamespace, nationalID, txID))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	record := new(AuditRecord)
+	err = s.Codec.Decode(value, record)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// History returns every version of the patient record, oldest first, with the audit
+// record of the transaction that wrote it. The history of an erased patient keeps their
+// audit trail but not the records written before the erasure.
+func (s *AuditService) History(nationalID string) ([]*PatientVersion, error) {
+	modifications, err := s.State.History(NewKey(PatientNamespace, nationalID))
+	if err != nil {
+		return nil, err
+	}
+
+	erasedPatient := false
+	for _, modification := range modifications {
+		erasedPatient = erasedPatient || (!modification.IsDelete && IsTombstone(modification.Value))
+	}
+
+	versions := []*PatientVersion{}
+	for _, modification := range modifications {
+		version := &PatientVersion{TxID: modification.TxID, Timestamp: modification.Timestamp, IsDelete: modification.IsDelete}
+		if !modification.IsDelete {
+			if IsTombstone(modification.Value) {
+				version.Erased = true
+			} else if !erasedPatient {
+				version.Record, err = s.Patients.Decode(modification.Value)
+				if err != nil {
+					return nil, InternalError("patient can't be fetched: %v", err)
+				}
+			}
+		}
+		version.Audit, err = s.Get(nationalID, modification.TxID)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
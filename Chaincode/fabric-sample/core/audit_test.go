@@ -0,0 +1,65 @@
+package core
+
+import "testing"
+
+func newAuditService(state Store, tx Transaction) *AuditService {
+	return &AuditService{State: state, Codec: JSONCodec{}, Patients: jsonCodec{}, Tx: tx}
+}
+
+// The audit record names the client and the transaction, and versions written before
+// the audit trail existed have none
+func TestAuditServiceRecord(t *testing.T) {
+	tx := newTestTransaction()
+	tx.client = attributes{RoleAttribute: RoleLab}
+	audit := newAuditService(newMemStore(), tx)
+	err := audit.Record("115", AuditDiseaseChange, 1, "lab result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := audit.Get("115", "tx1")
+	if err != nil || record == nil {
+		t.Fatalf("Get = %v (%v)", record, err)
+	}
+	want := AuditRecord{DocType: AuditDocType, PatientNationalID: "115", TxID: "tx1", Timestamp: "2026-03-01T12:00:00Z",
+		MSPID: "Org1MSP", ClientID: "x509::CN=lab", Action: AuditDiseaseChange, DiseaseSlot: 1, Reason: "lab result"}
+	if *record != want {
+		t.Errorf("Get = %+v, want %+v", record, want)
+	}
+	if record, err := audit.Get("115", "tx0"); record != nil || err != nil {
+		t.Errorf("Get of an unaudited transaction = %v (%v)", record, err)
+	}
+}
+
+// Once a patient is erased, the history hides the records written before the erasure
+// but keeps every version and its audit record
+func TestAuditServiceHistory(t *testing.T) {
+	state := newMemStore()
+	patients := &PatientService{State: state, Codec: jsonCodec{}}
+	audit := newAuditService(state, newTestTransaction())
+	state.txID = "tx0"
+	err := patients.Put(&Patient{Name: "Nusret", NationalID: "115", FamilyID: "22"}, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err := audit.History("115")
+	if err != nil || len(history) != 1 || history[0].Record == nil || history[0].Record.Name != "Nusret" {
+		t.Fatalf("History = %+v (%v)", history, err)
+	}
+
+	state.txID = "tx1"
+	err = state.Put(NewKey(PatientNamespace, "115"), []byte(`{"erased":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = audit.Record("115", AuditErase, NoDiseaseSlot, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err = audit.History("115")
+	if err != nil || len(history) != 2 {
+		t.Fatalf("History = %+v (%v)", history, err)
+	}
+	if history[0].Record != nil || history[0].Audit != nil || !history[1].Erased || history[1].Audit == nil || history[1].Audit.Action != AuditErase {
+		t.Errorf("History after the erasure = %+v, %+v", history[0], history[1])
+	}
+}
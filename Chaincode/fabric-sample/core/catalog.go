@@ -0,0 +1,113 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Codes of the chaincode error catalog. Every failed transaction of either chaincode
+// returns a ChaincodeError as JSON in the response message, so that client SDKs can
+// branch on the code.
+const (
+	CodeInvalidArgument    = "INVALID_ARGUMENT"
+	CodeUnknownTransaction = "UNKNOWN_TRANSACTION"
+	CodeAccessDenied       = "ACCESS_DENIED"
+	CodeNotFound           = "NOT_FOUND"
+	CodeAlreadyExists      = "ALREADY_EXISTS"
+	CodeErased             = "ERASED"
+	CodeInvalidCiphertext  = "INVALID_CIPHERTEXT"
+	CodeInternal           = "INTERNAL"
+	CodeKeyUnavailable     = "KEY_UNAVAILABLE"
+)
+
+// errorStatus is the HTTP-like status of each code. Fabric treats every status from 400
+// up as an error.
+var errorStatus = map[string]int32{
+	CodeInvalidArgument:    400,
+	CodeUnknownTransaction: 400,
+	CodeAccessDenied:       403,
+	CodeNotFound:           404,
+	CodeAlreadyExists:      409,
+	CodeErased:             410,
+	CodeInvalidCiphertext:  422,
+	CodeInternal:           500,
+	CodeKeyUnavailable:     503,
+}
+
+// ChaincodeError is an error from the catalog
+type ChaincodeError struct {
+	Code    string            `json:"code"`
+	Status  int32             `json:"status"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty" metadata:",optional"`
+}
+
+// Error returns the JSON form that is sent to clients
+func (e *ChaincodeError) Error() string {
+	errorJSON, err := json.Marshal(e)
+	if err != nil {
+		return e.Code + ": " + e.Message
+	}
+	return string(errorJSON)
+}
+
+// NewError returns a catalog error with the status of its code
+func NewError(code string, details map[string]string, format string, args ...interface{}) error {
+	return &ChaincodeError{Code: code, Status: errorStatus[code], Message: fmt.Sprintf(format, args...), Details: details}
+}
+
+// InvalidArgument reports a transaction argument that cannot be used
+func InvalidArgument(field string, format string, args ...interface{}) error {
+	return NewError(CodeInvalidArgument, map[string]string{"field": field}, format, args...)
+}
+
+// WrongArgumentCount reports a transaction called with the wrong number of arguments
+func WrongArgumentCount(expected string) error {
+	return NewError(CodeInvalidArgument, map[string]string{"expected": expected}, "incorrect number of arguments, expecting %s", expected)
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return NewError(CodeNotFound, nil, format, args...)
+}
+
+func AlreadyExists(format string, args ...interface{}) error {
+	return NewError(CodeAlreadyExists, nil, format, args...)
+}
+
+func Erased(format string, args ...interface{}) error {
+	return NewError(CodeErased, nil, format, args...)
+}
+
+func InvalidCiphertext(format string, args ...interface{}) error {
+	return NewError(CodeInvalidCiphertext, nil, format, args...)
+}
+
+func KeyUnavailable(format string, args ...interface{}) error {
+	return NewError(CodeKeyUnavailable, nil, format, args...)
+}
+
+func InternalError(format string, args ...interface{}) error {
+	return NewError(CodeInternal, nil, format, args...)
+}
+
+// AccessDenied is returned when the client identity may not call a transaction
+func AccessDenied(transaction string, role string, reason string) error {
+	details := map[string]string{"transaction": transaction, "role": role}
+	return NewError(CodeAccessDenied, details, "role %q may not call %s: %s", role, transaction, reason)
+}
+
+// AsChaincodeError returns the catalog error in err's chain, reports a service failure
+// under the code of its kind, and wraps an unclassified error, such as a failed ledger
+// call, as INTERNAL
+func AsChaincodeError(err error) *ChaincodeError {
+	var chaincodeError *ChaincodeError
+	if errors.As(err, &chaincodeError) {
+		return chaincodeError
+	}
+	var serviceError *Error
+	if errors.As(err, &serviceError) {
+		return NewError(string(serviceError.Kind), nil, "%s", serviceError.Message).(*ChaincodeError)
+	}
+	return &ChaincodeError{Code: CodeInternal, Status: errorStatus[CodeInternal], Message: err.Error()}
+}
@@ -0,0 +1,22 @@
+package core
+
+import "encoding/json"
+
+// RecordCodec converts between the records the services keep besides patients, such as
+// audit records and consents, and the form a chaincode stores them in. Chaincodes store
+// the same fields, but may write IDs as numbers rather than strings.
+type RecordCodec interface {
+	Encode(record interface{}) ([]byte, error)
+	Decode(value []byte, record interface{}) error
+}
+
+// JSONCodec stores records as the services see them
+type JSONCodec struct{}
+
+func (JSONCodec) Encode(record interface{}) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func (JSONCodec) Decode(value []byte, record interface{}) error {
+	return json.Unmarshal(value, record)
+}
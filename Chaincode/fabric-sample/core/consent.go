@@ -0,0 +1,194 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// Purposes a patient can consent to
+const (
+	PurposeRiskComputation     = "risk-computation"
+	PurposeResearchAggregation = "research-aggregation"
+)
+
+// ConsentRecord is a patient's consent to one organization using their record for one
+// purpose, stored under ("consent", nationalID, purpose, organization). A revoked or
+// expired record is kept so the grant stays on the ledger.
+type ConsentRecord struct {
+	DocType           string `json:"docType"`
+	PatientNationalID string `json:"patientNationalID"`
+	Purpose           string `json:"purpose"`
+	Organization      string `json:"organization"`
+	GrantedBy         string `json:"grantedBy"`
+	GrantedAt         string `json:"grantedAt"`
+	ExpiresAt         string `json:"expiresAt,omitempty" metadata:",optional"`
+	Revoked           bool   `json:"revoked"`
+	RevokedAt         string `json:"revokedAt,omitempty" metadata:",optional"`
+}
+
+// ConsentService records patients' consents and checks them
+type ConsentService struct {
+	State    Store
+	Codec    RecordCodec
+	Patients *PatientService
+	Audit    *AuditService
+	Tx       Transaction
+}
+
+// ValidatePurpose checks the purpose is one a patient can consent to
+func ValidatePurpose(purpose string) error {
+	switch purpose {
+	case PurposeRiskComputation, PurposeResearchAggregation:
+		return nil
+	default:
+		return InvalidArgument("purpose", "unknown consent purpose %q", purpose)
+	}
+}
+
+// Grant lets the organization with the given MSP ID use the patient's record for the
+// purpose until expiresAt (RFC3339), or indefinitely when it is empty. A guardian
+// grants consent with a patient identity issued for their ward.
+func (s *ConsentService) Grant(nationalID string, purpose string, organization string, expiresAt string) error {
+	patient, err := s.Patients.Get(nationalID)
+	if err != nil {
+		return err
+	}
+	err = ValidatePurpose(purpose)
+	if err != nil {
+		return err
+	}
+	if organization == "" {
+		return InvalidArgument("organization", "must not be empty")
+	}
+	grantedAt, err := Timestamp(s.Tx)
+	if err != nil {
+		return err
+	}
+	if expiresAt != "" {
+		expiry, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return InvalidArgument("expiresAt", "must be an RFC3339 timestamp")
+		}
+		expiresAt = expiry.UTC().Format(time.RFC3339)
+		if expiresAt <= grantedAt {
+			return InvalidArgument("expiresAt", "must be in the future")
+		}
+	}
+	client, err := s.Tx.Client()
+	if err != nil {
+		return fmt.Errorf("failed to read the client identity: %v", err)
+	}
+	grantedBy, err := client.GetID()
+	if err != nil {
+		return fmt.Errorf("failed to read client ID: %v", err)
+	}
+
+	consent := &ConsentRecord{
+		DocType:           ConsentDocType,
+		PatientNationalID: patient.NationalID,
+		Purpose:           purpose,
+		Organization:      organization,
+		GrantedBy:         grantedBy,
+		GrantedAt:         grantedAt,
+		ExpiresAt:         expiresAt,
+	}
+	err = s.put(consent)
+	if err != nil {
+		return err
+	}
+	return s.Audit.Record(patient.NationalID, AuditConsentChange, NoDiseaseSlot, "granted "+purpose+" to "+organization)
+}
+
+// Revoke withdraws a consent given with Grant
+func (s *ConsentService) Revoke(nationalID string, purpose string, organization string) error {
+	consent, err := s.get(nationalID, purpose, organization)
+	if err != nil {
+		return err
+	}
+	if consent == nil || consent.Revoked {
+		return NotFound("patient %s has no %s consent for %s", nationalID, purpose, organization)
+	}
+	consent.Revoked = true
+	consent.RevokedAt, err = Timestamp(s.Tx)
+	if err != nil {
+		return err
+	}
+	err = s.put(consent)
+	if err != nil {
+		return err
+	}
+	return s.Audit.Record(consent.PatientNationalID, AuditConsentChange, NoDiseaseSlot, "revoked "+purpose+" from "+organization)
+}
+
+// List returns every consent the patient has granted, including revoked and expired ones
+func (s *ConsentService) List(nationalID string) ([]*ConsentRecord, error) {
+	consents := []*ConsentRecord{}
+	err := s.State.Range(NewKey(ConsentNamespace, nationalID), func(key Key, value []byte) error {
+		consent := new(ConsentRecord)
+		err := s.Codec.Decode(value, consent)
+		if err != nil {
+			return InternalError("consent can't be fetched: %v", err)
+		}
+		consents = append(consents, consent)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// Has reports whether the patient has an unrevoked, unexpired consent for the purpose
+// given to the organization
+func (s *ConsentService) Has(nationalID string, purpose string, organization string) (bool, error) {
+	consent, err := s.get(nationalID, purpose, organization)
+	if err != nil || consent == nil || consent.Revoked {
+		return false, err
+	}
+	if consent.ExpiresAt == "" {
+		return true, nil
+	}
+	now, err := Timestamp(s.Tx)
+	if err != nil {
+		return false, err
+	}
+	// Both timestamps are RFC3339 in UTC, which sort as strings
+	return now < consent.ExpiresAt, nil
+}
+
+// ClientConsent returns the consent check for the purpose of the organization of the
+// client submitting the transaction
+func (s *ConsentService) ClientConsent(purpose string) (ConsentFunc, error) {
+	client, err := s.Tx.Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the client identity: %v", err)
+	}
+	organization, err := client.GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client MSP ID: %v", err)
+	}
+	return func(nationalID string) (bool, error) {
+		return s.Has(nationalID, purpose, organization)
+	}, nil
+}
+
+func (s *ConsentService) put(consent *ConsentRecord) error {
+	value, err := s.Codec.Encode(consent)
+	if err != nil {
+		return err
+	}
+	return s.State.Put(NewKey(ConsentNamespace, consent.PatientNationalID, consent.Purpose, consent.Organization), value)
+}
+
+func (s *ConsentService) get(nationalID string, purpose string, organization string) (*ConsentRecord, error) {
+	value, err := s.State.Get(NewKey(ConsentNamespace, nationalID, purpose, organization))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	consent := new(ConsentRecord)
+	err = s.Codec.Decode(value, consent)
+	if err != nil {
+		return nil, err
+	}
+	return consent, nil
+}
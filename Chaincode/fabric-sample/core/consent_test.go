@@ -0,0 +1,101 @@
+package core
+
+import "testing"
+
+func newConsentService(tx *testTransaction) *ConsentService {
+	state := newMemStore()
+	audit := newAuditService(state, tx)
+	return &ConsentService{State: state, Codec: JSONCodec{}, Patients: &PatientService{State: state, Codec: jsonCodec{}}, Audit: audit, Tx: tx}
+}
+
+func TestConsentServiceGrant(t *testing.T) {
+	tx := newTestTransaction()
+	consents := newConsentService(tx)
+	err := consents.Patients.Put(&Patient{Name: "Nusret", NationalID: "115", FamilyID: "22"}, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		nationalID   string
+		purpose      string
+		organization string
+		expiresAt    string
+		code         string
+	}{
+		{name: "missing patient", nationalID: "116", purpose: PurposeRiskComputation, organization: "Org1MSP", code: CodeNotFound},
+		{name: "unknown purpose", nationalID: "115", purpose: "marketing", organization: "Org1MSP", code: CodeInvalidArgument},
+		{name: "no organization", nationalID: "115", purpose: PurposeRiskComputation, code: CodeInvalidArgument},
+		{name: "bad expiry", nationalID: "115", purpose: PurposeRiskComputation, organization: "Org1MSP", expiresAt: "tomorrow", code: CodeInvalidArgument},
+		{name: "past expiry", nationalID: "115", purpose: PurposeRiskComputation, organization: "Org1MSP", expiresAt: "2026-01-01T00:00:00Z", code: CodeInvalidArgument},
+		{name: "indefinite", nationalID: "115", purpose: PurposeRiskComputation, organization: "Org1MSP"},
+		{name: "expiring", nationalID: "115", purpose: PurposeResearchAggregation, organization: "Org2MSP", expiresAt: "2026-06-01T02:00:00+02:00"},
+	}
+	for _, test := range tests {
+		err := consents.Grant(test.nationalID, test.purpose, test.organization, test.expiresAt)
+		if code := errorCode(err); code != test.code {
+			t.Errorf("%s: Grant = %v, want %q", test.name, err, test.code)
+		}
+	}
+
+	list, err := consents.List("115")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List = %v (%v)", list, err)
+	}
+	if list[0].Purpose != PurposeResearchAggregation || list[0].ExpiresAt != "2026-06-01T00:00:00Z" {
+		t.Errorf("List()[0] = %+v", list[0])
+	}
+	if audit, _ := consents.Audit.Get("115", "tx1"); audit == nil || audit.Action != AuditConsentChange {
+		t.Errorf("audit record = %+v", audit)
+	}
+}
+
+// Consents check the client's organization and stop at revocation and expiry
+func TestConsentServiceClientConsent(t *testing.T) {
+	tx := newTestTransaction()
+	consents := newConsentService(tx)
+	err := consents.Patients.Put(&Patient{Name: "Nusret", NationalID: "115", FamilyID: "22"}, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = consents.Grant("115", PurposeRiskComputation, "Org1MSP", "2026-06-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = consents.Grant("115", PurposeResearchAggregation, "Org2MSP", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	risk, err := consents.ClientConsent(PurposeRiskComputation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	research, err := consents.ClientConsent(PurposeResearchAggregation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := risk("115"); !valid || err != nil {
+		t.Errorf("risk consent = %v (%v), want true", valid, err)
+	}
+	if valid, err := research("115"); valid || err != nil {
+		t.Errorf("research consent of another organization = %v (%v), want false", valid, err)
+	}
+
+	tx.time = tx.time.AddDate(0, 6, 0)
+	if valid, err := risk("115"); valid || err != nil {
+		t.Errorf("expired risk consent = %v (%v), want false", valid, err)
+	}
+	tx.time = tx.time.AddDate(0, -6, 0)
+	err = consents.Revoke("115", PurposeRiskComputation, "Org1MSP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := risk("115"); valid || err != nil {
+		t.Errorf("revoked risk consent = %v (%v), want false", valid, err)
+	}
+	if err := consents.Revoke("115", PurposeRiskComputation, "Org1MSP"); errorCode(err) != CodeNotFound {
+		t.Errorf("second Revoke = %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// Disease is one entry of the disease table, stored under its index in the patients'
+// disease tables. Prevalence is in parts per million of the population, and Penetrance
+// is the share of carriers affected by each age, sorted by age.
+type Disease struct {
+	Index      int               `json:"index"`
+	Name       string            `json:"name"`
+	Weight     int               `json:"weight"`
+	Prevalence int               `json:"prevalence"`
+	Penetrance []PenetrancePoint `json:"penetrance,omitempty"`
+}
+
+// diseaseNames are the names of the diseases by index
+var diseaseNames = []string{"sickleCellDisease", "type2Diabetes", "achondroplasia"}
+
+// diseasePrevalences are the population prevalences of the diseases by index, in parts
+// per million. Diseases stored before prevalences were recorded are read with these.
+var diseasePrevalences = []int{100, 100000, 40}
+
+// diseasePenetrances are the penetrance curves of the diseases by index. Sickle cell
+// disease is recessive and shows from childhood in those affected, type 2 diabetes has
+// a late onset and achondroplasia shows at birth. Diseases stored before curves were
+// recorded are read with these.
+var diseasePenetrances = [][]PenetrancePoint{
+	nil,
+	{{Age: 30, Percent: 10}, {Age: 50, Percent: 30}, {Age: 70, Percent: 50}},
+	{{Age: 0, Percent: 100}},
+}
+
+// DiseaseService reads and writes the disease table
+type DiseaseService struct {
+	State Store
+}
+
+// PutTable stores each disease under its own key with the given weights, by index
+func (s *DiseaseService) PutTable(weights ...int) error {
+	if len(weights) > DiseaseSlotCount {
+		return InvalidArgument("diseases", "%d diseases do not fit in %d slots", len(weights), DiseaseSlotCount)
+	}
+	for index, weight := range weights {
+		disease := Disease{
+			Index:      index,
+			Name:       diseaseNames[index],
+			Weight:     weight,
+			Prevalence: diseasePrevalences[index],
+			Penetrance: diseasePenetrances[index],
+		}
+		value, err := json.Marshal(disease)
+		if err != nil {
+			return err
+		}
+		err = s.State.Put(NewKey(DiseaseNamespace, strconv.Itoa(index)), value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the disease stored under the index
+func (s *DiseaseService) Get(diseaseIndex int) (*Disease, error) {
+	if err := ValidateDiseaseIndex(diseaseIndex); err != nil {
+		return nil, err
+	}
+	value, err := s.State.Get(NewKey(DiseaseNamespace, strconv.Itoa(diseaseIndex)))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, NotFound("the disease %d does not exist", diseaseIndex)
+	}
+	return decodeDisease(value)
+}
+
+// List returns every registered disease in index order
+func (s *DiseaseService) List() ([]*Disease, error) {
+	diseases := []*Disease{}
+	err := s.State.Range(NewKey(DiseaseNamespace), func(key Key, value []byte) error {
+		disease, err := decodeDisease(value)
+		if err != nil {
+			return err
+		}
+		diseases = append(diseases, disease)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(diseases, func(i, j int) bool { return diseases[i].Index < diseases[j].Index })
+	return diseases, nil
+}
+
+// Penetrance reads the penetrance of a disease at a relative's age in the year of the
+// transaction. Each disease's curve is read once per transaction.
+func (s *DiseaseService) Penetrance(tx Transaction) PenetranceFunc {
+	curves := map[int][]PenetrancePoint{}
+	return func(relative *Patient, diseaseIndex int) (int, error) {
+		if relative.BirthYear == 0 {
+			return 0, nil
+		}
+		curve, ok := curves[diseaseIndex]
+		if !ok {
+			disease, err := s.Get(diseaseIndex)
+			if err != nil {
+				return 0, err
+			}
+			curve = disease.Penetrance
+			curves[diseaseIndex] = curve
+		}
+		year, err := Year(tx)
+		if err != nil {
+			return 0, err
+		}
+		return PenetranceAt(curve, year-relative.BirthYear), nil
+	}
+}
+
+// decodeDisease reads a stored disease, giving one stored without a prevalence or a
+// penetrance curve the defaults of its index
+func decodeDisease(value []byte) (*Disease, error) {
+	disease := new(Disease)
+	err := json.Unmarshal(value, disease)
+	if err != nil {
+		return nil, err
+	}
+	if disease.Prevalence == 0 && disease.Index >= 0 && disease.Index < len(diseasePrevalences) {
+		disease.Prevalence = diseasePrevalences[disease.Index]
+	}
+	if disease.Penetrance == nil && disease.Index >= 0 && disease.Index < len(diseasePenetrances) {
+		disease.Penetrance = diseasePenetrances[disease.Index]
+	}
+	return disease, nil
+}
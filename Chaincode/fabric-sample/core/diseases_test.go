@@ -0,0 +1,73 @@
+package core
+
+import "testing"
+
+func TestDiseaseServiceTable(t *testing.T) {
+	diseases := &DiseaseService{State: newMemStore()}
+	if _, err := diseases.Get(1); errorCode(err) != CodeNotFound {
+		t.Errorf("Get before PutTable = %v", err)
+	}
+	err := diseases.PutTable(100, 70, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := diseases.PutTable(1, 2, 3, 4); errorCode(err) != CodeInvalidArgument {
+		t.Errorf("PutTable of four diseases = %v", err)
+	}
+
+	list, err := diseases.List()
+	if err != nil || len(list) != DiseaseSlotCount {
+		t.Fatalf("List = %v (%v)", list, err)
+	}
+	for index, disease := range list {
+		if disease.Index != index || disease.Name != diseaseNames[index] || disease.Prevalence != diseasePrevalences[index] {
+			t.Errorf("List()[%d] = %+v", index, disease)
+		}
+	}
+	if _, err := diseases.Get(DiseaseSlotCount); errorCode(err) != CodeInvalidArgument {
+		t.Errorf("Get(%d) = %v", DiseaseSlotCount, err)
+	}
+}
+
+// Diseases stored before prevalences and penetrance curves were recorded are read with
+// the defaults of their index
+func TestDiseaseServiceDefaults(t *testing.T) {
+	diseases := &DiseaseService{State: newMemStore()}
+	err := diseases.State.Put(NewKey(DiseaseNamespace, "2"), []byte(`{"index":2,"name":"achondroplasia","weight":50}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	disease, err := diseases.Get(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disease.Prevalence != 40 || len(disease.Penetrance) != 1 {
+		t.Errorf("Get(2) = %+v", disease)
+	}
+}
+
+// Penetrance reads the curve at the relative's age in the year of the transaction, and
+// is 0 for relatives of unknown age
+func TestDiseaseServicePenetrance(t *testing.T) {
+	diseases := &DiseaseService{State: newMemStore()}
+	err := diseases.PutTable(100, 70, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	penetrance := diseases.Penetrance(newTestTransaction())
+	tests := []struct {
+		birthYear int
+		want      int
+	}{
+		{birthYear: 0, want: 0},
+		{birthYear: 1996, want: 10},
+		{birthYear: 1986, want: 20},
+		{birthYear: 1926, want: 50},
+	}
+	for _, test := range tests {
+		percent, err := penetrance(&Patient{BirthYear: test.birthYear}, 1)
+		if err != nil || percent != test.want {
+			t.Errorf("penetrance born %d = %d (%v), want %d", test.birthYear, percent, err, test.want)
+		}
+	}
+}
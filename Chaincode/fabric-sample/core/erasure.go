@@ -0,0 +1,377 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// Reason codes accepted by an erasure
+const (
+	ReasonSubjectRequest     = "SUBJECT_REQUEST"
+	ReasonConsentWithdrawn   = "CONSENT_WITHDRAWN"
+	ReasonLegalObligation    = "LEGAL_OBLIGATION"
+	ReasonUnlawfulProcessing = "UNLAWFUL_PROCESSING"
+)
+
+// Erasure scopes of a receipt
+const (
+	ErasureScopePatient = "patient"
+	ErasureScopeFamily  = "family"
+)
+
+// Tombstone replaces an erased patient record in the world state
+type Tombstone struct {
+	DocType           string `json:"docType"`
+	PatientNationalID string `json:"patientNationalID"`
+	PatientFamilyID   string `json:"patientFamilyID"`
+	Erased            bool   `json:"erased"`
+	ReasonCode        string `json:"reasonCode"`
+	ErasedAt          string `json:"erasedAt"`
+	TxID              string `json:"txID"`
+}
+
+// ErasureReceipt is stored on the ledger under ("erasure", txID) and emitted as an event
+// for every erasure. PurgedKey is the fingerprint of the key the erased records were
+// encrypted under and KeyPurged tells whether the erasure removed the ledger's copies of
+// it, which it cannot for a legacy key. Neither says the key is gone: a key can be
+// derived again from the seed it came from.
+type ErasureReceipt struct {
+	ReceiptID       string   `json:"receiptID"`
+	Scope           string   `json:"scope"`
+	PatientFamilyID string   `json:"patientFamilyID"`
+	ErasedPatients  []string `json:"erasedPatients"`
+	ReKeyedPatients []string `json:"reKeyedPatients"`
+	ReasonCode      string   `json:"reasonCode"`
+	ErasedAt        string   `json:"erasedAt"`
+	PurgedKey       string   `json:"purgedKey"`
+	KeyPurged       bool     `json:"keyPurged"`
+	ReplacementKey  string   `json:"replacementKey,omitempty" metadata:",optional"`
+}
+
+// ErasureService erases patients and families. Erased patients are replaced by
+// tombstones and the keys their records were encrypted under are purged, so no peer
+// can decrypt their historical ciphertexts from the ledger.
+type ErasureService struct {
+	State      Store
+	Codec      RecordCodec
+	Patients   *PatientService
+	FamilyKeys *FamilyKeyService
+	Keys       *KeyService
+	Audit      *AuditService
+	Randomness *Randomness
+	Tx         Transaction
+}
+
+// ValidateReasonCode checks the reason code is one an erasure accepts
+func ValidateReasonCode(reasonCode string) error {
+	switch reasonCode {
+	case ReasonSubjectRequest, ReasonConsentWithdrawn, ReasonLegalObligation, ReasonUnlawfulProcessing:
+		return nil
+	default:
+		return InvalidArgument("reasonCode", "unknown erasure reason code %q", reasonCode)
+	}
+}
+
+// ValidateFamily checks the family has not been erased and, when mustExist is set, that
+// it has a key or at least one live member
+func (s *ErasureService) ValidateFamily(familyID string, mustExist bool) error {
+	record, err := s.FamilyKeys.Record(familyID)
+	if err != nil {
+		return err
+	}
+	if record != nil && record.Erased {
+		return Erased("family %s has been erased", familyID)
+	}
+	if !mustExist || record != nil {
+		return nil
+	}
+	members, err := s.Patients.FamilyMembers(familyID)
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return NotFound("family %s does not exist", familyID)
+	}
+	return nil
+}
+
+// ErasePatient tombstones a patient and purges the key their records were encrypted
+// under. A patient with their own key only loses that key. For a patient under the
+// family key, the rest of the family is re-keyed under a new family key generated from
+// the keySeed transient field and the previous family key is purged.
+func (s *ErasureService) ErasePatient(nationalID string, reasonCode string) (*ErasureReceipt, error) {
+	err := ValidateReasonCode(reasonCode)
+	if err != nil {
+		return nil, err
+	}
+	patient, err := s.Patients.Get(nationalID)
+	if err != nil {
+		return nil, err
+	}
+	if patient.KeyScope == KeyScopePatient {
+		return s.erasePatientKey(patient, reasonCode)
+	}
+
+	oldKey, oldEpoch, err := s.FamilyKeys.Key(patient.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := s.FamilyKeys.IsLegacy(patient.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := TransientSeed(s.Tx, KeySeedField)
+	if err != nil {
+		return nil, err
+	}
+	newPublicKey, newKey, err := Pailler.GenerateKeyPairFromSeed(seed, FamilyKeyBits)
+	if err != nil {
+		return nil, err
+	}
+	erasedAt, err := Timestamp(s.Tx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.Patients.FamilyMembers(patient.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &ErasureReceipt{
+		ReceiptID:       s.Tx.TxID(),
+		Scope:           ErasureScopePatient,
+		PatientFamilyID: patient.FamilyID,
+		ErasedPatients:  []string{patient.NationalID},
+		ReKeyedPatients: []string{},
+		ReasonCode:      reasonCode,
+		ErasedAt:        erasedAt,
+		PurgedKey:       oldKey.Pk.Fingerprint(),
+		KeyPurged:       !legacy,
+		ReplacementKey:  newPublicKey.Fingerprint(),
+	}
+
+	for _, member := range members {
+		if member.NationalID == patient.NationalID {
+			continue
+		}
+		if member.KeyScope == KeyScopePatient {
+			// Their records stay under their own key, which only needs re-wrapping
+			memberKey, err := s.Keys.PatientKey(member)
+			if err != nil {
+				return nil, err
+			}
+			err = s.Keys.PutPatientKey(member.NationalID, memberKey, newKey, oldEpoch+1)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			err = s.reKey(member, oldKey, newPublicKey, erasedAt)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = s.Audit.Record(member.NationalID, AuditReKey, NoDiseaseSlot, reasonCode)
+		if err != nil {
+			return nil, err
+		}
+		receipt.ReKeyedPatients = append(receipt.ReKeyedPatients, member.NationalID)
+	}
+
+	err = s.putTombstone(patient, reasonCode, erasedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = s.FamilyKeys.Purge(patient.FamilyID, oldEpoch)
+	if err != nil {
+		return nil, err
+	}
+	err = s.FamilyKeys.Put(patient.FamilyID, oldEpoch+1, newKey)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, s.putReceipt(receipt)
+}
+
+// EraseFamily tombstones every member of a family and purges the family key
+func (s *ErasureService) EraseFamily(familyID string, reasonCode string) (*ErasureReceipt, error) {
+	err := ValidateReasonCode(reasonCode)
+	if err != nil {
+		return nil, err
+	}
+	err = s.ValidateFamily(familyID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	key, epoch, err := s.FamilyKeys.Key(familyID)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := s.FamilyKeys.IsLegacy(familyID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.Patients.FamilyMembers(familyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, NotFound("family %s does not exist", familyID)
+	}
+	erasedAt, err := Timestamp(s.Tx)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &ErasureReceipt{
+		ReceiptID:       s.Tx.TxID(),
+		Scope:           ErasureScopeFamily,
+		PatientFamilyID: familyID,
+		ErasedPatients:  []string{},
+		ReKeyedPatients: []string{},
+		ReasonCode:      reasonCode,
+		ErasedAt:        erasedAt,
+		PurgedKey:       key.Pk.Fingerprint(),
+		KeyPurged:       !legacy,
+	}
+
+	for _, member := range members {
+		if member.KeyScope == KeyScopePatient {
+			err = s.Keys.PurgePatientKey(member.NationalID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = s.putTombstone(member, reasonCode, erasedAt)
+		if err != nil {
+			return nil, err
+		}
+		receipt.ErasedPatients = append(receipt.ErasedPatients, member.NationalID)
+	}
+
+	err = s.FamilyKeys.Purge(familyID, epoch)
+	if err != nil {
+		return nil, err
+	}
+	err = s.FamilyKeys.PutRecord(&FamilyKeyRecord{PatientFamilyID: familyID, Epoch: epoch, PublicKey: key.Pk, Erased: true})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, s.putReceipt(receipt)
+}
+
+// erasePatientKey erases a patient that has their own key by purging that key
+func (s *ErasureService) erasePatientKey(patient *Patient, reasonCode string) (*ErasureReceipt, error) {
+	patientKey, err := s.Keys.PatientKey(patient)
+	if err != nil {
+		return nil, err
+	}
+	erasedAt, err := Timestamp(s.Tx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Keys.PurgePatientKey(patient.NationalID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.putTombstone(patient, reasonCode, erasedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &ErasureReceipt{
+		ReceiptID:       s.Tx.TxID(),
+		Scope:           ErasureScopePatient,
+		PatientFamilyID: patient.FamilyID,
+		ErasedPatients:  []string{patient.NationalID},
+		ReKeyedPatients: []string{},
+		ReasonCode:      reasonCode,
+		ErasedAt:        erasedAt,
+		PurgedKey:       patientKey.Pk.Fingerprint(),
+		KeyPurged:       true,
+	}
+	return receipt, s.putReceipt(receipt)
+}
+
+// reKey moves the ciphertexts of a patient under the family key to the new family key
+func (s *ErasureService) reKey(patient *Patient, oldKey *Pailler.PrivateKey, newKey *Pailler.PublicKey, timestamp string) error {
+	var err error
+	for index, value := range patient.DiseaseTable {
+		patient.DiseaseTable[index], err = s.Randomness.Reencrypt(oldKey, value, newKey, DiseaseSlot(patient.NationalID, index))
+		if err != nil {
+			return reKeyError(patient.NationalID, err)
+		}
+	}
+	for index, value := range patient.Genotypes {
+		if value == nil {
+			continue
+		}
+		patient.Genotypes[index], err = s.Randomness.Reencrypt(oldKey, value, newKey, GenotypeSlot(patient.NationalID, index))
+		if err != nil {
+			return reKeyError(patient.NationalID, err)
+		}
+	}
+	patient.KeyFingerprint = newKey.Fingerprint()
+	return s.Patients.Put(patient, timestamp)
+}
+
+// reKeyError reports a ciphertext that cannot be moved to the new family key. A peer
+// that cannot draw the randomness of the new ciphertexts keeps its own error.
+func reKeyError(nationalID string, err error) error {
+	var coreError *Error
+	if errors.As(err, &coreError) {
+		return err
+	}
+	return InvalidCiphertext("cannot re-key patient %s: %v", nationalID, err)
+}
+
+// putTombstone replaces the patient record with a tombstone and takes the patient out
+// of their family
+func (s *ErasureService) putTombstone(patient *Patient, reasonCode string, erasedAt string) error {
+	value, err := s.Codec.Encode(&Tombstone{
+		DocType:           TombstoneDocType,
+		PatientNationalID: patient.NationalID,
+		PatientFamilyID:   patient.FamilyID,
+		Erased:            true,
+		ReasonCode:        reasonCode,
+		ErasedAt:          erasedAt,
+		TxID:              s.Tx.TxID(),
+	})
+	if err != nil {
+		return err
+	}
+	err = s.State.Put(NewKey(PatientNamespace, patient.NationalID), value)
+	if err != nil {
+		return err
+	}
+	err = s.Audit.Record(patient.NationalID, AuditErase, NoDiseaseSlot, reasonCode)
+	if err != nil {
+		return err
+	}
+	return s.Patients.RemoveFamilyMember(patient.NationalID, patient.FamilyID)
+}
+
+// putReceipt stores the receipt under its transaction ID and emits it as an event. An
+// erasure that rotated the family key emits FamilyKeyRotated instead, which refers to
+// the stored receipt.
+func (s *ErasureService) putReceipt(receipt *ErasureReceipt) error {
+	value, err := s.Codec.Encode(receipt)
+	if err != nil {
+		return err
+	}
+	err = s.State.Put(NewKey(ErasureNamespace, receipt.ReceiptID), value)
+	if err != nil {
+		return err
+	}
+	if receipt.ReplacementKey == "" {
+		return s.Tx.SetEvent("ErasureReceipt", value)
+	}
+	event := events.New(events.FamilyKeyRotated)
+	event.PatientNationalIDs = receipt.ReKeyedPatients
+	event.PatientFamilyID = receipt.PatientFamilyID
+	event.KeyFingerprint = receipt.ReplacementKey
+	event.ReceiptID = receipt.ReceiptID
+	return EmitEvent(s.Tx, event)
+}
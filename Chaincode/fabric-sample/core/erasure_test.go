@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// newErasureService returns an erasure service over family 22, whose patients 115 and
+// 116 have a disease table encrypted under the family key
+func newErasureService(t *testing.T) (*ErasureService, *Pailler.PrivateKey) {
+	t.Helper()
+	tx := newTestTransaction()
+	tx.transient = map[string][]byte{KeySeedField: []byte("a key seed of at least thirty-two bytes")}
+	state := newMemStore()
+	familyKeys := &FamilyKeyService{State: state, Private: newMemStore(), Codec: JSONCodec{}, Tx: tx}
+	erasure := &ErasureService{
+		State:      state,
+		Codec:      JSONCodec{},
+		Patients:   &PatientService{State: state, Codec: jsonCodec{}},
+		FamilyKeys: familyKeys,
+		Keys:       &KeyService{FamilyKey: familyKeys.Key},
+		Audit:      newAuditService(state, tx),
+		Randomness: testRandomness,
+		Tx:         tx,
+	}
+
+	familyKey := testKey(t, "family 22")
+	err := familyKeys.Put("22", 0, familyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nationalID := range []string{"115", "116"} {
+		patient := &Patient{NationalID: nationalID, FamilyID: "22", KeyFingerprint: familyKey.Pk.Fingerprint()}
+		for index := 0; index < DiseaseSlotCount; index++ {
+			value, err := erasure.Randomness.Encrypt(familyKey.Pk, int64(index%2), DiseaseSlot(nationalID, index))
+			if err != nil {
+				t.Fatal(err)
+			}
+			patient.DiseaseTable = append(patient.DiseaseTable, value)
+		}
+		err = erasure.Patients.Put(patient, "2026-01-01T00:00:00Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return erasure, familyKey
+}
+
+// Erasing a patient under the family key tombstones them and moves the rest of the
+// family to a key derived from the keySeed
+func TestErasureServiceErasePatient(t *testing.T) {
+	erasure, familyKey := newErasureService(t)
+	if _, err := erasure.ErasePatient("115", "FORGET_ME"); errorCode(err) != CodeInvalidArgument {
+		t.Errorf("ErasePatient with an unknown reason = %v", err)
+	}
+	receipt, err := erasure.ErasePatient("115", ReasonSubjectRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, epoch, err := erasure.FamilyKeys.Key("22")
+	if err != nil || epoch != 1 {
+		t.Fatalf("Key after ErasePatient = %d (%v)", epoch, err)
+	}
+	want := ErasureReceipt{ReceiptID: "tx1", Scope: ErasureScopePatient, PatientFamilyID: "22", ErasedPatients: []string{"115"},
+		ReKeyedPatients: []string{"116"}, ReasonCode: ReasonSubjectRequest, ErasedAt: "2026-03-01T12:00:00Z",
+		PurgedKey: familyKey.Pk.Fingerprint(), KeyPurged: true, ReplacementKey: newKey.Pk.Fingerprint()}
+	if !reflect.DeepEqual(*receipt, want) {
+		t.Errorf("ErasePatient = %+v, want %+v", receipt, want)
+	}
+
+	if _, err := erasure.Patients.Get("115"); errorCode(err) != CodeErased {
+		t.Errorf("Get of the erased patient = %v", err)
+	}
+	if _, err := erasure.ErasePatient("115", ReasonSubjectRequest); errorCode(err) != CodeErased {
+		t.Errorf("ErasePatient of an erased patient = %v", err)
+	}
+	member, err := erasure.Patients.Get("116")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.KeyFingerprint != newKey.Pk.Fingerprint() {
+		t.Error("the family was not moved to the new key")
+	}
+	for index, value := range member.DiseaseTable {
+		plaintext, err := newKey.Decrypt(value)
+		if err != nil || plaintext != int64(index%2) {
+			t.Errorf("disease %d decrypts to %v (%v)", index, plaintext, err)
+		}
+	}
+	if _, err := erasure.Audit.Get("116", "tx1"); err != nil {
+		t.Error(err)
+	}
+}
+
+// Erasing a family tombstones every member, marks the family key erased and emits the
+// stored receipt
+func TestErasureServiceEraseFamily(t *testing.T) {
+	erasure, familyKey := newErasureService(t)
+	receipt, err := erasure.EraseFamily("22", ReasonLegalObligation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ErasureReceipt{ReceiptID: "tx1", Scope: ErasureScopeFamily, PatientFamilyID: "22", ErasedPatients: []string{"115", "116"},
+		ReKeyedPatients: []string{}, ReasonCode: ReasonLegalObligation, ErasedAt: "2026-03-01T12:00:00Z",
+		PurgedKey: familyKey.Pk.Fingerprint(), KeyPurged: true}
+	if !reflect.DeepEqual(*receipt, want) {
+		t.Errorf("EraseFamily = %+v, want %+v", receipt, want)
+	}
+
+	members, err := erasure.Patients.FamilyMembers("22")
+	if err != nil || len(members) != 0 {
+		t.Errorf("FamilyMembers after EraseFamily = %v (%v)", members, err)
+	}
+	if _, _, err := erasure.FamilyKeys.Key("22"); errorCode(err) != CodeErased {
+		t.Errorf("Key of the erased family = %v", err)
+	}
+	if err := erasure.ValidateFamily("22", false); errorCode(err) != CodeErased {
+		t.Errorf("ValidateFamily of the erased family = %v", err)
+	}
+	if err := erasure.ValidateFamily("23", true); errorCode(err) != CodeNotFound {
+		t.Errorf("ValidateFamily of a missing family = %v", err)
+	}
+
+	tx := erasure.Tx.(*testTransaction)
+	var emitted ErasureReceipt
+	if tx.eventName != "ErasureReceipt" || json.Unmarshal(tx.event, &emitted) != nil || !reflect.DeepEqual(emitted, want) {
+		t.Errorf("event %s = %s", tx.eventName, tx.event)
+	}
+}
@@ -0,0 +1,29 @@
+package core
+
+import "fmt"
+
+// Kind classifies a failure of the services. The kinds are named after the chaincode
+// error codes they are reported as.
+type Kind string
+
+// Kinds of service failures
+const (
+	KindNotFound          Kind = "NOT_FOUND"
+	KindErased            Kind = "ERASED"
+	KindKeyUnavailable    Kind = "KEY_UNAVAILABLE"
+	KindInvalidCiphertext Kind = "INVALID_CIPHERTEXT"
+)
+
+// Error is a classified failure. Failures of the Store are returned as they are.
+type Error struct {
+	Kind    Kind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func errorf(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
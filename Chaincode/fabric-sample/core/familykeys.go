@@ -0,0 +1,168 @@
+package core
+
+import (
+	"strconv"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// Transient map fields holding the client supplied key seeds. A keySeed re-keys a family
+// on erasure or gives a new patient their own key; a familyKeySeed creates the key of a
+// new family.
+const (
+	KeySeedField       = "keySeed"
+	FamilyKeySeedField = "familyKeySeed"
+)
+
+// FamilyKeyRecord is the public half of a family key, stored under ("familykey",
+// familyID). The matching private key for the current epoch is kept in private data.
+// Legacy marks a key migrated from an earlier version of the chaincode, which anyone
+// can derive from the family ID with GenerateKeyPair.
+type FamilyKeyRecord struct {
+	PatientFamilyID string             `json:"patientFamilyID"`
+	Epoch           int                `json:"epoch"`
+	PublicKey       *Pailler.PublicKey `json:"publicKey"`
+	Erased          bool               `json:"erased"`
+	Legacy          bool               `json:"legacy,omitempty" metadata:",optional"`
+}
+
+// FamilyKey is the private data copy of a family key, stored under ("familykey",
+// familyID, epoch)
+type FamilyKey struct {
+	PatientFamilyID string              `json:"patientFamilyID"`
+	Key             *Pailler.PrivateKey `json:"key"`
+}
+
+// FamilyKeyService keeps the family keys. A family's first key is at epoch 0 and every
+// erasure that re-keys the family moves it to the next epoch.
+type FamilyKeyService struct {
+	State   Store
+	Private Store
+	Codec   RecordCodec
+	Tx      Transaction
+}
+
+// Record returns the public record of the family's key, or nil when the family has none
+func (s *FamilyKeyService) Record(familyID string) (*FamilyKeyRecord, error) {
+	value, err := s.State.Get(NewKey(FamilyKeyNamespace, familyID))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	record := new(FamilyKeyRecord)
+	err = s.Codec.Decode(value, record)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// PutRecord stores the public record of a family key
+func (s *FamilyKeyService) PutRecord(record *FamilyKeyRecord) error {
+	value, err := s.Codec.Encode(record)
+	if err != nil {
+		return err
+	}
+	return s.State.Put(NewKey(FamilyKeyNamespace, record.PatientFamilyID), value)
+}
+
+// Key returns the private key the family's records are currently encrypted under,
+// together with its epoch
+func (s *FamilyKeyService) Key(familyID string) (*Pailler.PrivateKey, int, error) {
+	record, err := s.Record(familyID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if record == nil {
+		return nil, 0, errorf(KindKeyUnavailable, "family %s has no key", familyID)
+	}
+	if record.Erased {
+		return nil, 0, errorf(KindErased, "the key of family %s has been erased", familyID)
+	}
+
+	value, err := s.Private.Get(familyKeyID(familyID, record.Epoch))
+	if err != nil {
+		return nil, 0, err
+	}
+	if value == nil {
+		return nil, 0, errorf(KindKeyUnavailable, "the key of family %s is not available on this peer", familyID)
+	}
+	familyKey := new(FamilyKey)
+	err = s.Codec.Decode(value, familyKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	return familyKey.Key, record.Epoch, nil
+}
+
+// Put stores the private key for the given epoch and makes it the family's current key
+func (s *FamilyKeyService) Put(familyID string, epoch int, privateKey *Pailler.PrivateKey) error {
+	return s.put(familyID, epoch, privateKey, false)
+}
+
+// PutLegacy stores a key from an earlier version of the chaincode as the family's first key
+func (s *FamilyKeyService) PutLegacy(familyID string, privateKey *Pailler.PrivateKey) error {
+	return s.put(familyID, 0, privateKey, true)
+}
+
+// New derives the first key of a new family from a client supplied seed and stores it
+func (s *FamilyKeyService) New(familyID string, seed []byte) (*Pailler.PrivateKey, error) {
+	_, privateKey, err := DeriveFamilyKey(familyID, seed)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Put(familyID, 0, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return privateKey, nil
+}
+
+// KeyOrNew returns the family's current key and its epoch. A family without a key gets
+// its first one from the familyKeySeed transient field.
+func (s *FamilyKeyService) KeyOrNew(familyID string) (*Pailler.PrivateKey, int, error) {
+	record, err := s.Record(familyID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if record != nil {
+		return s.Key(familyID)
+	}
+	seed, err := TransientSeed(s.Tx, FamilyKeySeedField)
+	if err != nil {
+		return nil, 0, err
+	}
+	privateKey, err := s.New(familyID, seed)
+	return privateKey, 0, err
+}
+
+// Purge purges the private copy of the family key of the given epoch. Legacy keys were
+// written to the world state before they were migrated, so copies of them remain in
+// earlier blocks.
+func (s *FamilyKeyService) Purge(familyID string, epoch int) error {
+	return s.Private.Purge(familyKeyID(familyID, epoch))
+}
+
+// IsLegacy reports whether the family's current key is a legacy key
+func (s *FamilyKeyService) IsLegacy(familyID string) (bool, error) {
+	record, err := s.Record(familyID)
+	if err != nil {
+		return false, err
+	}
+	return record != nil && record.Legacy, nil
+}
+
+func (s *FamilyKeyService) put(familyID string, epoch int, privateKey *Pailler.PrivateKey, legacy bool) error {
+	value, err := s.Codec.Encode(&FamilyKey{PatientFamilyID: familyID, Key: privateKey})
+	if err != nil {
+		return err
+	}
+	err = s.Private.Put(familyKeyID(familyID, epoch), value)
+	if err != nil {
+		return err
+	}
+	return s.PutRecord(&FamilyKeyRecord{PatientFamilyID: familyID, Epoch: epoch, PublicKey: privateKey.Pk, Legacy: legacy})
+}
+
+func familyKeyID(familyID string, epoch int) Key {
+	return NewKey(FamilyKeyNamespace, familyID, strconv.Itoa(epoch))
+}
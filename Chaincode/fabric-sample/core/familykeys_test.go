@@ -0,0 +1,47 @@
+package core
+
+import "testing"
+
+func newFamilyKeyService(tx Transaction) *FamilyKeyService {
+	return &FamilyKeyService{State: newMemStore(), Private: newMemStore(), Codec: JSONCodec{}, Tx: tx}
+}
+
+// A family key is read back at its epoch until it is purged, and a family without a
+// key only gets one from the familyKeySeed transient field
+func TestFamilyKeyService(t *testing.T) {
+	tx := newTestTransaction()
+	familyKeys := newFamilyKeyService(tx)
+	familyKey := testKey(t, "family 22")
+
+	if _, _, err := familyKeys.Key("22"); errorCode(err) != CodeKeyUnavailable {
+		t.Errorf("Key of a family without a key = %v", err)
+	}
+	err := familyKeys.Put("22", 1, familyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, epoch, err := familyKeys.Key("22")
+	if err != nil || epoch != 1 || key.Pk.Fingerprint() != familyKey.Pk.Fingerprint() {
+		t.Fatalf("Key = %v, %d (%v)", key, epoch, err)
+	}
+	err = familyKeys.Purge("22", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := familyKeys.Key("22"); errorCode(err) != CodeKeyUnavailable {
+		t.Errorf("Key after Purge = %v", err)
+	}
+
+	if _, _, err := familyKeys.KeyOrNew("23"); errorCode(err) != CodeInvalidArgument {
+		t.Errorf("KeyOrNew without a seed = %v", err)
+	}
+	err = familyKeys.PutLegacy("21", testKey(t, "family 21"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for familyID, want := range map[string]bool{"21": true, "22": false, "23": false} {
+		if legacy, err := familyKeys.IsLegacy(familyID); legacy != want || err != nil {
+			t.Errorf("IsLegacy(%s) = %v (%v), want %v", familyID, legacy, err, want)
+		}
+	}
+}
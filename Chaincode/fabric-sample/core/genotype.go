@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// Genotypes of a patient for a disease, stored encrypted beside the disease flags. For a
+// recessive disease the genotype is the number of copies of the disease allele.
+const (
+	GenotypeNonCarrier = 0
+	GenotypeCarrier    = 1
+	GenotypeAffected   = 2
+)
+
+// transmissionPercent is the chance, in percent, that a parent passes the disease allele
+// on per copy they carry: a carrier passes it half the time, an affected parent always
+const transmissionPercent = 50
+
+// RecessiveRisk computes, under the target key, the chance in percent that each of the
+// two parents passes the allele of a recessive disease on: 50 * g for genotype g. A
+// child is affected with the product of the two chances over 100. Paillier cannot
+// multiply two ciphertexts and no genotype is decrypted, so the product is left to the
+// holder of the target key, with RecessiveRiskPercent. It fails unless there are two
+// parents and both have consented and have a recorded genotype.
+func (s *RiskService) RecessiveRisk(target *Pailler.PublicKey, nationalID string, parents []string, diseaseIndex int, consent ConsentFunc) ([]*big.Int, error) {
+	if len(parents) != 2 {
+		return nil, errorf(KindNotFound, "patient %s has %d known parents, not 2", nationalID, len(parents))
+	}
+
+	unknown := []string{}
+	known := make([]*Patient, 0, len(parents))
+	for _, parentID := range parents {
+		if consent != nil {
+			consented, err := consent(parentID)
+			if err != nil {
+				return nil, err
+			}
+			if !consented {
+				unknown = append(unknown, parentID)
+				continue
+			}
+		}
+		parent, _, err := s.lookup(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || diseaseIndex >= len(parent.Genotypes) || parent.Genotypes[diseaseIndex] == nil {
+			unknown = append(unknown, parentID)
+			continue
+		}
+		known = append(known, parent)
+	}
+	if len(unknown) > 0 {
+		return nil, errorf(KindNotFound, "the genotype of parents %s of patient %s cannot be used", strings.Join(unknown, ", "), nationalID)
+	}
+
+	transmissions := make([]*big.Int, len(known))
+	for index, parent := range known {
+		key, err := s.Keys.PatientKey(parent)
+		if err != nil {
+			return nil, err
+		}
+		transmission, err := key.Pk.MultPlaintext(parent.Genotypes[diseaseIndex], transmissionPercent)
+		if err != nil {
+			return nil, errorf(KindInvalidCiphertext, "the genotype of patient %s is not a valid ciphertext", parent.NationalID)
+		}
+		if key.Pk.N.Cmp(target.N) != 0 {
+			transmission, err = s.Randomness.Reencrypt(key, transmission, target, riskSlot("recessive", nationalID, diseaseIndex)+"/"+parent.NationalID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		transmissions[index] = transmission
+	}
+	return transmissions, nil
+}
+
+// RecessiveRiskPercent decrypts the chances RecessiveRisk returns with the target key and
+// multiplies them into the chance, in percent, that the child is affected
+func RecessiveRiskPercent(key *Pailler.PrivateKey, transmissions []*big.Int) (int64, error) {
+	risk := int64(100)
+	for _, transmission := range transmissions {
+		chance, err := key.Decrypt(transmission)
+		if err != nil || chance > 100 {
+			return 0, fmt.Errorf("%v is not the chance of passing an allele on", transmission)
+		}
+		risk = risk * chance / 100
+	}
+	return risk, nil
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// Modulus sizes of the keys derived from client supplied seeds
+const (
+	FamilyKeyBits  = 1024
+	PatientKeyBits = 1024
+)
+
+// KeyWrapSecretEnv names the environment variable holding the secret patient keys are
+// wrapped with. Every peer of the organization sets the same secret and it never reaches
+// the ledger, so reading the family key from the private data alone does not open the
+// patient keys wrapped under it. It is one secret for all patients and families: a peer
+// that has it, and the family key, opens every patient key of the family.
+const KeyWrapSecretEnv = "GENCHAIN_KEY_WRAP_SECRET"
+
+// KeyWrapSecret returns the key wrap secret of this peer, or nil when none is set
+func KeyWrapSecret() []byte {
+	secret := os.Getenv(KeyWrapSecretEnv)
+	if secret == "" {
+		return nil
+	}
+	return []byte(secret)
+}
+
+// WrappedPatientKey is the private data copy of a patient's key, sealed under the
+// family key of the given epoch
+type WrappedPatientKey struct {
+	FamilyEpoch int    `json:"familyEpoch"`
+	Wrapped     []byte `json:"wrapped"`
+}
+
+// PatientKeyRecord is the public half of a patient's own key, stored under
+// ("patientkey", nationalID) in the world state
+type PatientKeyRecord struct {
+	PatientNationalID string             `json:"patientNationalID"`
+	PatientFamilyID   string             `json:"patientFamilyID"`
+	PublicKey         *Pailler.PublicKey `json:"publicKey"`
+}
+
+// FamilyKeyFunc returns the private key a family's records are currently encrypted
+// under, together with its epoch
+type FamilyKeyFunc func(familyID string) (*Pailler.PrivateKey, int, error)
+
+// KeyService resolves the key each patient's disease table is encrypted under. Patient
+// keys are kept wrapped under the family key and WrapSecret in Private, with their
+// public half in State; family keys are read through FamilyKey. Every endorsing peer
+// that computes with a patient's ciphertexts unwraps their key, so per-patient keys
+// limit what a client or a reader of the private data learns, not what a peer does.
+type KeyService struct {
+	State      Store
+	Private    Store
+	Codec      RecordCodec
+	FamilyKey  FamilyKeyFunc
+	WrapSecret []byte
+	Tx         Transaction
+}
+
+// PatientKey returns the key the patient's disease table is encrypted under
+func (s *KeyService) PatientKey(patient *Patient) (*Pailler.PrivateKey, error) {
+	familyKey, familyEpoch, err := s.FamilyKey(patient.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	if patient.KeyScope != KeyScopePatient {
+		return CheckKeyFingerprint(patient, familyKey)
+	}
+
+	wrappedJSON, err := s.Private.Get(NewKey(PatientKeyNamespace, patient.NationalID))
+	if err != nil {
+		return nil, err
+	}
+	if wrappedJSON == nil {
+		return nil, errorf(KindKeyUnavailable, "the key of patient %s is not available on this peer", patient.NationalID)
+	}
+	var wrapped WrappedPatientKey
+	err = json.Unmarshal(wrappedJSON, &wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped.FamilyEpoch != familyEpoch {
+		return nil, errorf(KindKeyUnavailable, "the key of patient %s is wrapped under a retired family key", patient.NationalID)
+	}
+	if err := checkWrapSecret(s.WrapSecret); err != nil {
+		return nil, err
+	}
+	patientKey, err := familyKey.UnwrapKey(wrapped.Wrapped, PatientKeyLabel(patient.NationalID), s.WrapSecret)
+	if err != nil {
+		return nil, errorf(KindKeyUnavailable, "the key of patient %s cannot be unwrapped with the %s of this peer", patient.NationalID, KeyWrapSecretEnv)
+	}
+	return CheckKeyFingerprint(patient, patientKey)
+}
+
+// PutPatientKey wraps the patient's key under the given family key and stores it
+func (s *KeyService) PutPatientKey(nationalID string, privateKey *Pailler.PrivateKey, familyKey *Pailler.PrivateKey, familyEpoch int) error {
+	if err := checkWrapSecret(s.WrapSecret); err != nil {
+		return err
+	}
+	sealed, err := familyKey.WrapKey(privateKey, PatientKeyLabel(nationalID), s.WrapSecret)
+	if err != nil {
+		return err
+	}
+	wrappedJSON, err := json.Marshal(WrappedPatientKey{FamilyEpoch: familyEpoch, Wrapped: sealed})
+	if err != nil {
+		return err
+	}
+	return s.Private.Put(NewKey(PatientKeyNamespace, nationalID), wrappedJSON)
+}
+
+// NewPatientKey gives the patient their own key when the client passed a keySeed in the
+// transient map, wraps it under the given family key and stores it. It returns nil when
+// no seed was given, in which case the patient stays under the family key.
+func (s *KeyService) NewPatientKey(nationalID string, familyID string, familyKey *Pailler.PrivateKey, familyEpoch int) (*Pailler.PublicKey, error) {
+	seeded, err := HasTransient(s.Tx, KeySeedField)
+	if err != nil || !seeded {
+		return nil, err
+	}
+	seed, err := TransientSeed(s.Tx, KeySeedField)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, privateKey, err := DerivePatientKey(nationalID, seed)
+	if err != nil {
+		return nil, err
+	}
+	err = s.PutPatientKey(nationalID, privateKey, familyKey, familyEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.Codec.Encode(&PatientKeyRecord{PatientNationalID: nationalID, PatientFamilyID: familyID, PublicKey: publicKey})
+	if err != nil {
+		return nil, err
+	}
+	err = s.State.Put(NewKey(PatientKeyNamespace, nationalID), value)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
+
+// PurgePatientKey purges the private copy of the patient's own key
+func (s *KeyService) PurgePatientKey(nationalID string) error {
+	return s.Private.Purge(NewKey(PatientKeyNamespace, nationalID))
+}
+
+// DerivePatientKey derives the patient's own key pair from a client supplied seed
+func DerivePatientKey(nationalID string, seed []byte) (*Pailler.PublicKey, *Pailler.PrivateKey, error) {
+	patientSeed := sha256.Sum256(append([]byte("patient "+nationalID+" "), seed...))
+	return Pailler.GenerateKeyPairFromSeed(patientSeed[:], PatientKeyBits)
+}
+
+// checkWrapSecret reports a peer without a usable key wrap secret as a missing key
+func checkWrapSecret(secret []byte) error {
+	if len(secret) < Pailler.MinWrapSecretLength {
+		return errorf(KindKeyUnavailable, "%s is not set to a secret of at least %d bytes on this peer", KeyWrapSecretEnv, Pailler.MinWrapSecretLength)
+	}
+	return nil
+}
+
+// DeriveFamilyKey derives a family's key pair from a client supplied seed
+func DeriveFamilyKey(familyID string, seed []byte) (*Pailler.PublicKey, *Pailler.PrivateKey, error) {
+	familySeed := sha256.Sum256(append([]byte("family "+familyID+" "), seed...))
+	return Pailler.GenerateKeyPairFromSeed(familySeed[:], FamilyKeyBits)
+}
+
+// CheckKeyFingerprint returns the key when the patient's ciphertexts are tagged with its
+// fingerprint. Records written before tagging carry no fingerprint and are accepted.
+func CheckKeyFingerprint(patient *Patient, key *Pailler.PrivateKey) (*Pailler.PrivateKey, error) {
+	if patient.KeyFingerprint != "" && patient.KeyFingerprint != key.Pk.Fingerprint() {
+		return nil, errorf(KindInvalidCiphertext, "the records of patient %s are encrypted under another key", patient.NationalID)
+	}
+	return key, nil
+}
+
+// PatientKeyLabel binds a wrapped patient key to the patient it belongs to
+func PatientKeyLabel(nationalID string) string {
+	return "patientkey/" + nationalID
+}
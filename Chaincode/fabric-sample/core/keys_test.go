@@ -0,0 +1,109 @@
+package core
+
+import (
+	"testing"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// newKeyService returns a key service whose families all use the same key at the given epoch
+func newKeyService(familyKey *Pailler.PrivateKey, epoch *int) *KeyService {
+	return &KeyService{
+		Private: newMemStore(),
+		FamilyKey: func(familyID string) (*Pailler.PrivateKey, int, error) {
+			return familyKey, *epoch, nil
+		},
+		WrapSecret: []byte("a key wrap secret of thirty-two bytes"),
+	}
+}
+
+func TestKeyServiceFamilyScope(t *testing.T) {
+	familyKey := testKey(t, "family 22")
+	epoch := 1
+	keys := newKeyService(familyKey, &epoch)
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		kind        Kind
+	}{
+		{name: "untagged"},
+		{name: "tagged", fingerprint: familyKey.Pk.Fingerprint()},
+		{name: "other key", fingerprint: testKey(t, "family 21").Pk.Fingerprint(), kind: KindInvalidCiphertext},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := keys.PatientKey(&Patient{NationalID: "115", FamilyID: "22", KeyFingerprint: test.fingerprint})
+			if errorKind(err) != test.kind {
+				t.Fatalf("PatientKey = %v, want kind %q", err, test.kind)
+			}
+			if err == nil && key != familyKey {
+				t.Error("PatientKey did not return the family key")
+			}
+		})
+	}
+}
+
+func TestKeyServicePatientScope(t *testing.T) {
+	familyKey := testKey(t, "family 22")
+	patientKey := testKey(t, "patient 130")
+	epoch := 1
+	keys := newKeyService(familyKey, &epoch)
+	patient := &Patient{NationalID: "130", FamilyID: "22", KeyScope: KeyScopePatient, KeyFingerprint: patientKey.Pk.Fingerprint()}
+
+	_, err := keys.PatientKey(patient)
+	if errorKind(err) != KindKeyUnavailable {
+		t.Fatalf("PatientKey before PutPatientKey = %v", err)
+	}
+
+	err = keys.PutPatientKey("130", patientKey, familyKey, epoch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := keys.PatientKey(patient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Pk.Fingerprint() != patientKey.Pk.Fingerprint() {
+		t.Error("PatientKey did not return the patient's key")
+	}
+
+	// The wrapped key only opens with the secret it was wrapped with
+	keys.WrapSecret = []byte("another secret of at least thirty-two bytes")
+	if _, err = keys.PatientKey(patient); errorKind(err) != KindKeyUnavailable {
+		t.Errorf("PatientKey with another wrap secret = %v", err)
+	}
+	keys.WrapSecret = nil
+	if _, err = keys.PatientKey(patient); errorKind(err) != KindKeyUnavailable {
+		t.Errorf("PatientKey without a wrap secret = %v", err)
+	}
+
+	// A re-keyed family retires the keys wrapped under its previous key
+	epoch = 2
+	_, err = keys.PatientKey(patient)
+	if errorKind(err) != KindKeyUnavailable {
+		t.Errorf("PatientKey after re-keying = %v", err)
+	}
+}
+
+func TestDerivePatientKey(t *testing.T) {
+	seed := []byte("a seed of at least thirty-two bytes")
+	first, _, err := DerivePatientKey("130", seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := DerivePatientKey("130", seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := DerivePatientKey("131", seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Fingerprint() != again.Fingerprint() {
+		t.Error("the same seed derived different keys")
+	}
+	if first.Fingerprint() == other.Fingerprint() {
+		t.Error("two patients derived the same key from one seed")
+	}
+}
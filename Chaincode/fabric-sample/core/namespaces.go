@@ -0,0 +1,24 @@
+package core
+
+// World state namespaces. Every record is stored under a composite key in one of them,
+// so range queries never mix record types and IDs of different kinds cannot collide.
+const (
+	PatientNamespace    = "patient"
+	FamilyNamespace     = "family"
+	FamilyKeyNamespace  = "familykey"
+	DiseaseNamespace    = "disease"
+	EdgeNamespace       = "edge"
+	PatientKeyNamespace = "patientkey"
+	ErasureNamespace    = "erasure"
+	AuditNamespace      = "audit"
+	PolicyNamespace     = "policy"
+	ConsentNamespace    = "consent"
+)
+
+// Document types of world state values, used by CouchDB indexes and rich queries
+const (
+	PatientDocType   = "patient"
+	TombstoneDocType = "tombstone"
+	ConsentDocType   = "consent"
+	AuditDocType     = "audit"
+)
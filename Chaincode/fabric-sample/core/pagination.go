@@ -0,0 +1,60 @@
+package core
+
+// MaxPageSize bounds the number of records a single page query may read
+const MaxPageSize = 1000
+
+// PatientPage is one page of a patient listing. Pass Bookmark to the next call to
+// continue; an empty bookmark means the listing is complete.
+type PatientPage struct {
+	Patients            []*Patient
+	FetchedRecordsCount int32
+	Bookmark            string
+}
+
+// Page returns up to pageSize patients starting at the bookmark. When familyID is not
+// empty only members of that family are listed, by paging through the family's
+// membership index. Erased patients are counted as fetched but left out of Patients, so
+// a page may hold fewer patients than pageSize while more remain.
+func (s *PatientService) Page(familyID string, pageSize int32, bookmark string) (*PatientPage, error) {
+	if pageSize <= 0 || pageSize > MaxPageSize {
+		return nil, InvalidArgument("pageSize", "must be between 1 and %d", MaxPageSize)
+	}
+	prefix := NewKey(PatientNamespace)
+	if familyID != "" {
+		err := ValidateID("patientFamilyID", familyID)
+		if err != nil {
+			return nil, err
+		}
+		prefix = NewKey(FamilyNamespace, familyID)
+	}
+
+	page := &PatientPage{Patients: []*Patient{}}
+	var err error
+	page.FetchedRecordsCount, page.Bookmark, err = s.State.RangePage(prefix, pageSize, bookmark, func(key Key, value []byte) error {
+		if familyID != "" {
+			// Family index entries only name the member
+			var err error
+			value, err = s.State.Get(NewKey(PatientNamespace, key.Attributes[1]))
+			if err != nil {
+				return err
+			}
+		}
+		return s.addToPage(page, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *PatientService) addToPage(page *PatientPage, value []byte) error {
+	if value == nil || IsTombstone(value) {
+		return nil
+	}
+	patient, err := s.Codec.Decode(value)
+	if err != nil {
+		return InternalError("patient can't be fetched: %v", err)
+	}
+	page.Patients = append(page.Patients, patient)
+	return nil
+}
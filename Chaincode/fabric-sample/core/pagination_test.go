@@ -0,0 +1,53 @@
+package core
+
+import "testing"
+
+// Pages of a family list its live members in order, count erased ones as fetched and
+// end with an empty bookmark
+func TestPatientServicePage(t *testing.T) {
+	state := newMemStore()
+	patients := &PatientService{State: state, Codec: jsonCodec{}}
+	for _, patient := range []*Patient{
+		{NationalID: "115", FamilyID: "22"},
+		{NationalID: "116", FamilyID: "22"},
+		{NationalID: "117", FamilyID: "22"},
+		{NationalID: "112", FamilyID: "20"},
+	} {
+		err := patients.Put(patient, "2026-01-01T00:00:00Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := state.Put(NewKey(PatientNamespace, "116"), []byte(`{"erased":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := patients.Page("22", 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Patients) != 1 || first.Patients[0].NationalID != "115" || first.FetchedRecordsCount != 2 || first.Bookmark == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+	second, err := patients.Page("22", 2, first.Bookmark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Patients) != 1 || second.Patients[0].NationalID != "117" || second.Bookmark != "" {
+		t.Errorf("second page = %+v", second)
+	}
+
+	all, err := patients.Page("", MaxPageSize, "")
+	if err != nil || len(all.Patients) != 3 || all.FetchedRecordsCount != 4 {
+		t.Errorf("Page of every family = %+v (%v)", all, err)
+	}
+	for _, pageSize := range []int32{0, MaxPageSize + 1} {
+		if _, err := patients.Page("", pageSize, ""); errorCode(err) != CodeInvalidArgument {
+			t.Errorf("Page of %d = %v", pageSize, err)
+		}
+	}
+	if _, err := patients.Page("2x", 10, ""); errorCode(err) != CodeInvalidArgument {
+		t.Errorf("Page of an invalid family = %v", err)
+	}
+}
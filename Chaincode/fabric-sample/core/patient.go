@@ -0,0 +1,182 @@
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// KeyScopePatient marks a patient whose disease table is encrypted under their own key.
+// Records without a key scope are encrypted under the family key.
+const KeyScopePatient = "patient"
+
+// familyIndexValue is stored in the family membership index. The state database treats
+// an empty value as a delete, so the index needs a non-empty placeholder.
+var familyIndexValue = []byte{0x00}
+
+// Patient is a patient record as the services see it. IDs are decimal strings whatever
+// form a chaincode stores them in.
+type Patient struct {
+	Name           string
+	NationalID     string
+	FamilyID       string
+	DiseaseTable   []*big.Int
+	Genotypes      []*big.Int
+	BirthYear      int
+	KeyScope       string
+	KeyFingerprint string
+	CreatedAt      string
+	UpdatedAt      string
+}
+
+// PatientCodec converts between a Patient and the record a chaincode stores
+type PatientCodec interface {
+	Decode(value []byte) (*Patient, error)
+	Encode(patient *Patient) ([]byte, error)
+}
+
+// PatientService reads and writes patients and the pedigree between them
+type PatientService struct {
+	State Store
+	Codec PatientCodec
+}
+
+// IsTombstone reports whether a stored patient value is the tombstone of an erased patient
+func IsTombstone(value []byte) bool {
+	var probe struct {
+		Erased bool `json:"erased"`
+	}
+	return json.Unmarshal(value, &probe) == nil && probe.Erased
+}
+
+// Get returns the patient, failing for missing or erased records
+func (s *PatientService) Get(nationalID string) (*Patient, error) {
+	value, err := s.State.Get(NewKey(PatientNamespace, nationalID))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, errorf(KindNotFound, "patient %s does not exist", nationalID)
+	}
+	if IsTombstone(value) {
+		return nil, errorf(KindErased, "patient %s has been erased", nationalID)
+	}
+	return s.Codec.Decode(value)
+}
+
+// Lookup returns the patient, or nil when the patient is missing or erased. Relatives
+// are looked up this way, since a gap in the pedigree is not an error.
+func (s *PatientService) Lookup(nationalID string) (*Patient, error) {
+	value, err := s.State.Get(NewKey(PatientNamespace, nationalID))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil || IsTombstone(value) {
+		return nil, nil
+	}
+	return s.Codec.Decode(value)
+}
+
+// Put stamps and stores the patient and adds them to their family's membership index
+func (s *PatientService) Put(patient *Patient, timestamp string) error {
+	if patient.CreatedAt == "" {
+		patient.CreatedAt = timestamp
+	}
+	patient.UpdatedAt = timestamp
+
+	value, err := s.Codec.Encode(patient)
+	if err != nil {
+		return err
+	}
+	err = s.State.Put(NewKey(PatientNamespace, patient.NationalID), value)
+	if err != nil {
+		return err
+	}
+	return s.State.Put(NewKey(FamilyNamespace, patient.FamilyID, patient.NationalID), familyIndexValue)
+}
+
+// Delete removes the patient and their family membership
+func (s *PatientService) Delete(nationalID string, familyID string) error {
+	err := s.State.Delete(NewKey(PatientNamespace, nationalID))
+	if err != nil {
+		return err
+	}
+	return s.RemoveFamilyMember(nationalID, familyID)
+}
+
+// RemoveFamilyMember takes the patient out of the family's membership index
+func (s *PatientService) RemoveFamilyMember(nationalID string, familyID string) error {
+	return s.State.Delete(NewKey(FamilyNamespace, familyID, nationalID))
+}
+
+// FamilyMembers returns every patient of the family that has not been erased
+func (s *PatientService) FamilyMembers(familyID string) ([]*Patient, error) {
+	var members []*Patient
+	err := s.State.Range(NewKey(FamilyNamespace, familyID), func(key Key, value []byte) error {
+		patient, err := s.Lookup(key.Attributes[1])
+		if err != nil || patient == nil {
+			return err
+		}
+		members = append(members, patient)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// Parents returns the national IDs of the recorded parents of a patient
+func (s *PatientService) Parents(nationalID string) ([]string, error) {
+	var parents []string
+	err := s.State.Range(NewKey(EdgeNamespace, nationalID), func(key Key, value []byte) error {
+		parents = append(parents, key.Attributes[1])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parents, nil
+}
+
+// Generations walks the parent edges up to depth generations and returns the ancestors
+// of each generation, parents first. Patients without recorded parents fall back to
+// the given generations.
+func (s *PatientService) Generations(nationalID string, depth int, fallback [][]string) ([][]string, error) {
+	var generations [][]string
+	current := []string{nationalID}
+	seen := map[string]bool{nationalID: true}
+
+	for level := 0; level < depth && len(current) > 0; level++ {
+		var next []string
+		for _, childID := range current {
+			parents, err := s.Parents(childID)
+			if err != nil {
+				return nil, err
+			}
+			for _, parentID := range parents {
+				if !seen[parentID] {
+					seen[parentID] = true
+					next = append(next, parentID)
+				}
+			}
+		}
+		if len(next) > 0 {
+			generations = append(generations, next)
+		}
+		current = next
+	}
+
+	if len(generations) == 0 {
+		return fallback, nil
+	}
+	return generations, nil
+}
+
+// FixedGenerations splits a list of fathers and mothers, nearest first, into generations
+func FixedGenerations(ancestorIDs []string) [][]string {
+	var generations [][]string
+	for i := 0; i+1 < len(ancestorIDs); i += 2 {
+		generations = append(generations, ancestorIDs[i:i+2])
+	}
+	return generations
+}
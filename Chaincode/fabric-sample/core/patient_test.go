@@ -0,0 +1,147 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newPatientService() *PatientService {
+	return &PatientService{State: newMemStore(), Codec: jsonCodec{}}
+}
+
+func errorKind(err error) Kind {
+	var serviceError *Error
+	if errors.As(err, &serviceError) {
+		return serviceError.Kind
+	}
+	return ""
+}
+
+func TestPatientServiceGet(t *testing.T) {
+	patients := newPatientService()
+	err := patients.Put(&Patient{Name: "Asli", NationalID: "116", FamilyID: "22"}, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = patients.State.Put(NewKey(PatientNamespace, "117"), []byte(`{"erased":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		nationalID string
+		kind       Kind
+		found      bool
+	}{
+		{nationalID: "116", found: true},
+		{nationalID: "117", kind: KindErased},
+		{nationalID: "118", kind: KindNotFound},
+	}
+	for _, test := range tests {
+		patient, err := patients.Get(test.nationalID)
+		if errorKind(err) != test.kind || (err == nil) != test.found {
+			t.Errorf("Get(%s) = %v, want kind %q", test.nationalID, err, test.kind)
+		}
+		if test.found && patient.Name != "Asli" {
+			t.Errorf("Get(%s) = %+v", test.nationalID, patient)
+		}
+		patient, err = patients.Lookup(test.nationalID)
+		if err != nil || (patient != nil) != test.found {
+			t.Errorf("Lookup(%s) = %v, %v, want found %v", test.nationalID, patient, err, test.found)
+		}
+	}
+}
+
+func TestPatientServicePutAndDelete(t *testing.T) {
+	patients := newPatientService()
+	patient := &Patient{Name: "Nusret", NationalID: "115", FamilyID: "22"}
+	err := patients.Put(patient, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = patients.Put(patient, "2026-01-02T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patient.CreatedAt != "2026-01-01T00:00:00Z" || patient.UpdatedAt != "2026-01-02T00:00:00Z" {
+		t.Errorf("timestamps = %s, %s", patient.CreatedAt, patient.UpdatedAt)
+	}
+
+	members := func() []string {
+		var nationalIDs []string
+		err := patients.State.Range(NewKey(FamilyNamespace, "22"), func(key Key, value []byte) error {
+			nationalIDs = append(nationalIDs, key.Attributes[1])
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nationalIDs
+	}
+	if got := members(); !reflect.DeepEqual(got, []string{"115"}) {
+		t.Errorf("family members = %v", got)
+	}
+
+	err = patients.Delete("115", "22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := members(); len(got) != 0 {
+		t.Errorf("family members after delete = %v", got)
+	}
+	if _, err := patients.Get("115"); errorKind(err) != KindNotFound {
+		t.Errorf("Get after delete = %v", err)
+	}
+}
+
+func TestPatientServiceGenerations(t *testing.T) {
+	fallback := [][]string{{"115", "116"}}
+	tests := []struct {
+		name  string
+		edges [][2]string
+		depth int
+		want  [][]string
+	}{
+		{name: "no parents", depth: 2, want: fallback},
+		{name: "parents", edges: [][2]string{{"130", "115"}, {"130", "116"}}, depth: 2, want: [][]string{{"115", "116"}}},
+		{
+			name:  "grandparents",
+			edges: [][2]string{{"130", "115"}, {"130", "116"}, {"115", "119"}, {"116", "120"}, {"119", "140"}},
+			depth: 2,
+			want:  [][]string{{"115", "116"}, {"119", "120"}},
+		},
+		{
+			name:  "shared ancestor",
+			edges: [][2]string{{"130", "115"}, {"130", "116"}, {"115", "119"}, {"116", "119"}},
+			depth: 3,
+			want:  [][]string{{"115", "116"}, {"119"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patients := newPatientService()
+			for _, edge := range test.edges {
+				err := patients.State.Put(NewKey(EdgeNamespace, edge[0], edge[1]), []byte("{}"))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := patients.Generations("130", test.depth, fallback)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Generations = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFixedGenerations(t *testing.T) {
+	got := FixedGenerations([]string{"115", "116", "119", "120", "121"})
+	want := [][]string{{"115", "116"}, {"119", "120"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FixedGenerations = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package core
+
+// PenetrancePoint is a point of a penetrance curve: the percent of carriers of a disease
+// who are affected by the given age
+type PenetrancePoint struct {
+	Age     int `json:"age"`
+	Percent int `json:"percent"`
+}
+
+// PenetranceFunc returns the percent of carriers of the disease affected at the
+// relative's age, or 0 when their age is not known
+type PenetranceFunc func(relative *Patient, diseaseIndex int) (int, error)
+
+// PenetranceAt reads a curve, sorted by age, at the given age. Between two points the
+// curve is linear; it is 0 before the first point and stays at the last point after it.
+func PenetranceAt(curve []PenetrancePoint, age int) int {
+	percent := 0
+	for index, point := range curve {
+		if age < point.Age {
+			if index == 0 {
+				return 0
+			}
+			previous := curve[index-1]
+			percent = previous.Percent + (point.Percent-previous.Percent)*(age-previous.Age)/(point.Age-previous.Age)
+			break
+		}
+		percent = point.Percent
+	}
+	if percent < 0 {
+		return 0
+	}
+	if percent > 100 {
+		return 100
+	}
+	return percent
+}
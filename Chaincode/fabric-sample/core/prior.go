@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// Risk scores are log-odds in fixed point. A disease's prior is the log-odds of its
+// population prevalence, and each affected ancestor adds the log of a likelihood ratio:
+// the disease weight over the ancestor's generation, so a weight of 100 means an
+// affected parent multiplies the odds by e. Unaffected ancestors may take some away, as
+// RiskService describes. The posterior log-odds is the prior plus the encrypted family
+// evidence Explain returns, which only takes additions under Paillier.
+const (
+	// LogOddsScale is the fixed-point scale of scores: a score of LogOddsScale is one nat
+	LogOddsScale = 100
+	// PrevalenceScale is the unit prevalences are given in, parts per million
+	PrevalenceScale = 1000000
+)
+
+// PriorLogOdds returns the fixed-point log-odds of a prevalence in parts per million
+func PriorLogOdds(prevalence int) (int64, error) {
+	if prevalence <= 0 || prevalence >= PrevalenceScale {
+		return 0, fmt.Errorf("prevalence %d is not between 1 and %d parts per million", prevalence, PrevalenceScale-1)
+	}
+	p := float64(prevalence)
+	return int64(math.Round(LogOddsScale * math.Log(p/(PrevalenceScale-p)))), nil
+}
+
+// Probability returns the probability of a fixed-point log-odds
+func Probability(logOdds int64) float64 {
+	return 1 / (1 + math.Exp(-float64(logOdds)/LogOddsScale))
+}
+
+// Posterior adds the prior log-odds to the encrypted family evidence under the same key.
+// The prior is public and added to the evidence as a plaintext, a negative prior as
+// N - |prior|, so nothing is decrypted and no randomness is drawn.
+func Posterior(target *Pailler.PublicKey, evidence *big.Int, prior int64) (*big.Int, error) {
+	return target.AddSignedPlaintext(evidence, prior)
+}
+
+// MaxEvidence bounds the evidence the generations can add or take away for a disease of
+// the given weight: every ancestor affected, or every ancestor unaffected at full
+// penetrance
+func MaxEvidence(generations [][]string, weight int) int64 {
+	var evidence int64
+	for index, generation := range generations {
+		evidence += int64(len(generation)) * int64(weight/(index+1))
+	}
+	return evidence
+}
+
+// DecryptEvidence reads back the family evidence of an Explanation, which lies within
+// maxEvidence either side of zero since unaffected relatives count against it, or the
+// risk Compute returns, which lies between zero and maxEvidence.
+func DecryptEvidence(key *Pailler.PrivateKey, evidence *big.Int, maxEvidence int64) (int64, error) {
+	return decryptWindow(key, evidence, 0, maxEvidence)
+}
+
+// DecryptPosterior reads a posterior back: the score lies within maxEvidence either side
+// of the prior
+func DecryptPosterior(key *Pailler.PrivateKey, posterior *big.Int, prior int64, maxEvidence int64) (int64, error) {
+	return decryptWindow(key, posterior, prior, maxEvidence)
+}
+
+// decryptWindow decrypts a score known to lie within maxEvidence of center
+func decryptWindow(key *Pailler.PrivateKey, ciphertext *big.Int, center int64, maxEvidence int64) (int64, error) {
+	err := checkWindow(key.Pk, maxEvidence)
+	if err != nil {
+		return 0, err
+	}
+	return key.DecryptInRange(ciphertext, center-maxEvidence)
+}
+
+// checkWindow fails unless the key is large enough to tell apart every score within
+// maxEvidence of a center, that is unless 2*maxEvidence < N. Scores outside it would
+// wrap around modulo N and decrypt to a wrong value.
+func checkWindow(target *Pailler.PublicKey, maxEvidence int64) error {
+	span := new(big.Int).Lsh(big.NewInt(maxEvidence), 1)
+	if maxEvidence < 0 || span.Cmp(target.N) != -1 {
+		return fmt.Errorf("the key is too small for scores spanning %v", span)
+	}
+	return nil
+}
+
+// PosteriorRisk is the encrypted posterior of a risk computation. It decrypts with
+// DecryptPosterior to a log-odds within MaxEvidence of Prior.
+type PosteriorRisk struct {
+	Prior       int64
+	MaxEvidence int64
+	Ciphertext  *big.Int
+}
+
+// ComputePosterior combines a disease's prevalence with the encrypted family evidence
+// Explain returned for it over the generations, under the target key. Nothing is
+// decrypted; it fails when the key is too small for the posterior to be read back.
+func ComputePosterior(target *Pailler.PublicKey, evidence *big.Int, generations [][]string, weight int, prevalence int) (*PosteriorRisk, error) {
+	prior, err := PriorLogOdds(prevalence)
+	if err != nil {
+		return nil, err
+	}
+	maxEvidence := MaxEvidence(generations, weight)
+	err = checkWindow(target, maxEvidence)
+	if err != nil {
+		return nil, errorf(KindKeyUnavailable, "the posterior cannot be computed: %v", err)
+	}
+	ciphertext, err := Posterior(target, evidence, prior)
+	if err != nil {
+		return nil, errorf(KindInvalidCiphertext, "the posterior cannot be computed: %v", err)
+	}
+	return &PosteriorRisk{Prior: prior, MaxEvidence: maxEvidence, Ciphertext: ciphertext}, nil
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+func TestComputePosterior(t *testing.T) {
+	key := testKey(t, "family 22")
+	// 1% prevalence: ln(1/99) in hundredths of a nat
+	prior, err := PriorLogOdds(10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prior != -460 {
+		t.Fatalf("PriorLogOdds(10000) = %d, want -460", prior)
+	}
+
+	// MaxEvidence(testGenerations, 100) is 300
+	for _, evidence := range []int64{0, 150, -150, 300, -300} {
+		zero, err := key.Pk.Encrypt(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encryptedEvidence, err := key.Pk.AddSignedPlaintext(zero, evidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		posterior, err := ComputePosterior(key.Pk, encryptedEvidence, testGenerations, 100, 10000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if posterior.Prior != prior || posterior.MaxEvidence != 300 {
+			t.Errorf("prior %d within %d, want %d within 300", posterior.Prior, posterior.MaxEvidence, prior)
+		}
+		logOdds, err := DecryptPosterior(key, posterior.Ciphertext, posterior.Prior, posterior.MaxEvidence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if logOdds != prior+evidence {
+			t.Errorf("posterior of evidence %d = %d, want %d", evidence, logOdds, prior+evidence)
+		}
+	}
+}
+
+// A key whose modulus cannot hold every posterior of the pedigree is refused rather than
+// returning a posterior that wraps around
+func TestComputePosteriorWindowExceeded(t *testing.T) {
+	// p = 23 and q = 29
+	n := big.NewInt(667)
+	small := &Pailler.PublicKey{N: n, G: new(big.Int).Add(n, big.NewInt(1)), N2: new(big.Int).Mul(n, n)}
+	evidence, err := small.Encrypt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2 * (2*100 + 2*50) = 600 fits, 2 * (2*100 + 2*50 + 2*33) = 732 does not
+	if _, err := ComputePosterior(small, evidence, testGenerations, 100, 10000); err != nil {
+		t.Errorf("two generations: %v", err)
+	}
+	generations := [][]string{{"115", "116"}, {"119", "120"}, {"123", "124"}}
+	_, err = ComputePosterior(small, evidence, generations, 100, 10000)
+	if errorKind(err) != KindKeyUnavailable {
+		t.Errorf("three generations: got %v, want KEY_UNAVAILABLE", err)
+	}
+}
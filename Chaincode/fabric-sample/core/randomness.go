@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Randomness draws the randomness of the ciphertexts a transaction computes. Every
+// endorsing peer must return the same write set and response, so it cannot come from
+// crypto/rand. It is expanded with HKDF from the key wrap secret, keyed by the
+// transaction ID and labelled with the key's fingerprint and a slot naming what is
+// encrypted, so each ciphertext of a transaction gets its own r. The transaction ID and
+// the key are public: without the secret anyone could recompute r and test candidate
+// plaintexts, so a peer without it cannot encrypt. Slots must be unique within a
+// transaction for each key; the same r for two plaintexts gives their difference away.
+//
+// A nil Randomness draws from crypto/rand, for computations that are not endorsed.
+type Randomness struct {
+	Secret []byte
+	TxID   string
+}
+
+// TransactionRandomness returns the randomness of a transaction on this peer
+func TransactionRandomness(txID string) *Randomness {
+	return &Randomness{Secret: KeyWrapSecret(), TxID: txID}
+}
+
+// Encrypt encrypts msg under the target key with the randomness of the slot
+func (r *Randomness) Encrypt(target *Pailler.PublicKey, msg int64, slot string) (*big.Int, error) {
+	if r == nil {
+		return target.Encrypt(msg)
+	}
+	random, err := r.reader(target, slot)
+	if err != nil {
+		return nil, err
+	}
+	return target.EncryptFrom(random, msg)
+}
+
+// Reencrypt moves a ciphertext of key to the target key with the randomness of the slot
+func (r *Randomness) Reencrypt(key *Pailler.PrivateKey, ciphertext *big.Int, target *Pailler.PublicKey, slot string) (*big.Int, error) {
+	if r == nil {
+		return key.Reencrypt(ciphertext, target)
+	}
+	random, err := r.reader(target, slot)
+	if err != nil {
+		return nil, err
+	}
+	return key.ReencryptFrom(random, ciphertext, target)
+}
+
+// reader returns the stream of a slot under the target key
+func (r *Randomness) reader(target *Pailler.PublicKey, slot string) (io.Reader, error) {
+	if err := checkWrapSecret(r.Secret); err != nil {
+		return nil, err
+	}
+	if r.TxID == "" {
+		return nil, fmt.Errorf("the randomness of %s has no transaction", slot)
+	}
+	return hkdf.New(sha256.New, r.Secret, []byte(r.TxID), []byte(target.Fingerprint()+"/"+slot)), nil
+}
+
+// DiseaseSlot labels the ciphertext of a disease flag of a patient's record
+func DiseaseSlot(nationalID string, diseaseIndex int) string {
+	return "patient/" + nationalID + "/disease/" + strconv.Itoa(diseaseIndex)
+}
+
+// GenotypeSlot labels the ciphertext of a genotype of a patient's record
+func GenotypeSlot(nationalID string, diseaseIndex int) string {
+	return "patient/" + nationalID + "/genotype/" + strconv.Itoa(diseaseIndex)
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"testing"
+)
+
+var testRandomness = &Randomness{Secret: []byte("a key wrap secret of thirty-two bytes"), TxID: "tx000001"}
+
+// A slot gives the same ciphertext every time in a transaction, and another in any
+// other slot, key or transaction
+func TestRandomness(t *testing.T) {
+	key := testKey(t, "family 22")
+	encrypt := func(random *Randomness, slot string) string {
+		t.Helper()
+		ciphertext, err := random.Encrypt(key.Pk, 1, slot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := decrypt(t, key, ciphertext); got != 1 {
+			t.Fatalf("%s decrypts to %d, want 1", slot, got)
+		}
+		return ciphertext.Text(16)
+	}
+
+	first := encrypt(testRandomness, "patient/130/disease/0")
+	if encrypt(testRandomness, "patient/130/disease/0") != first {
+		t.Error("the same slot gave two ciphertexts")
+	}
+	if encrypt(testRandomness, "patient/130/disease/1") == first {
+		t.Error("two slots gave the same ciphertext")
+	}
+	if encrypt(&Randomness{Secret: testRandomness.Secret, TxID: "tx000002"}, "patient/130/disease/0") == first {
+		t.Error("two transactions gave the same ciphertext")
+	}
+	if encrypt(&Randomness{Secret: []byte("another key wrap secret of 32 bytes"), TxID: testRandomness.TxID}, "patient/130/disease/0") == first {
+		t.Error("two secrets gave the same ciphertext")
+	}
+	other := testKey(t, "patient 120")
+	reencrypted, err := testRandomness.Reencrypt(other, encryptAll(t, other.Pk, 1)[0], key.Pk, "patient/130/disease/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reencrypted.Text(16) != first {
+		t.Error("re-encrypting into a slot gave another ciphertext than encrypting into it")
+	}
+
+	var random *Randomness
+	if encrypt(random, "patient/130/disease/0") == encrypt(random, "patient/130/disease/0") {
+		t.Error("a nil Randomness repeated a ciphertext")
+	}
+}
+
+// The transaction ID and the key are public, so r is never derived without the secret
+func TestRandomnessWithoutSecret(t *testing.T) {
+	key := testKey(t, "family 22")
+	for _, secret := range []string{"", "too short"} {
+		random := &Randomness{Secret: []byte(secret), TxID: "tx000001"}
+		if _, err := random.Encrypt(key.Pk, 1, "patient/130/disease/0"); errorKind(err) != KindKeyUnavailable {
+			t.Errorf("secret %q: got %v, want KEY_UNAVAILABLE", secret, err)
+		}
+	}
+	if _, err := (&Randomness{Secret: testRandomness.Secret}).Encrypt(key.Pk, 1, "patient/130/disease/0"); err == nil {
+		t.Error("encrypted without a transaction")
+	}
+}
+
+// Two peers endorsing the same risk computation return the same ciphertexts
+func TestRiskServiceDeterministic(t *testing.T) {
+	fixture := newRiskFixture(t)
+	fixture.risk.Randomness = testRandomness
+	first, explanation, err := fixture.risk.Explain(fixture.familyKey.Pk, "130", testGenerations, 100, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := fixture.risk.Explain(fixture.familyKey.Pk, "130", testGenerations, 100, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cmp(second) != 0 {
+		t.Error("the same transaction computed two risks")
+	}
+	seen := map[string]string{explanation.Initial.Text(16): "initial"}
+	for _, term := range explanation.Terms {
+		if previous, ok := seen[term.Contribution.Text(16)]; ok {
+			t.Errorf("the contribution of %s repeats the ciphertext of %s", term.NationalID, previous)
+		}
+		seen[term.Contribution.Text(16)] = term.NationalID
+	}
+
+	profile, _, err := fixture.risk.ComputeProfile(fixture.familyKey.Pk, "130", testGenerations, []DiseaseWeight{{Index: 0, Weight: 100}, {Index: 1, Weight: 100}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := fixture.risk.ComputeProfile(fixture.familyKey.Pk, "130", testGenerations, []DiseaseWeight{{Index: 0, Weight: 100}, {Index: 1, Weight: 100}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, risk := range profile {
+		if risk.Cmp(again[index]) != 0 {
+			t.Errorf("the same transaction computed two risks of disease %d", index)
+		}
+	}
+}
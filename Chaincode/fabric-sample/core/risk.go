@@ -0,0 +1,330 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// ConsentFunc reports whether a relative may take part in a risk computation
+type ConsentFunc func(nationalID string) (bool, error)
+
+// RiskService computes a patient's encrypted disease risk from their relatives' records.
+// An affected relative adds the disease weight over their generation, so the risk is
+// never negative. With Penetrance set, an unaffected relative of known age also counts
+// against the disease in log-odds space: they take the same weight away from the family
+// evidence in proportion to the penetrance at their age, so one still unaffected at 70
+// lowers the posterior more than one unaffected at 20. The ciphertexts it computes draw
+// their randomness from Randomness.
+type RiskService struct {
+	Patients   *PatientService
+	Keys       *KeyService
+	Penetrance PenetranceFunc
+	Randomness *Randomness
+}
+
+// TermStatus tells how a relative took part in a risk computation
+type TermStatus string
+
+// Statuses of the relatives in an Explanation. Relatives that are not found, are erased
+// or have no value for the disease contribute an encrypted zero.
+const (
+	TermIncluded      TermStatus = "included"
+	TermNoConsent     TermStatus = "no-consent"
+	TermNotFound      TermStatus = "not-found"
+	TermErased        TermStatus = "erased"
+	TermNoDiseaseData TermStatus = "no-disease-data"
+)
+
+// Term is one relative's part in a risk computation. Contribution is the ciphertext
+// added to the risk under the target key, nil for relatives left out for lack of consent.
+// Penetrance is the percent of the weight an unaffected relative takes away from the
+// family evidence.
+type Term struct {
+	NationalID   string
+	Relationship string
+	Level        int
+	Weight       int
+	Penetrance   int
+	Status       TermStatus
+	Contribution *big.Int
+}
+
+// Explanation breaks a risk down into the encrypted zero the sum starts from and one
+// term per relative, in the order they were added. The key holder can decrypt every
+// contribution and check that they add up to the risk. Evidence is the family evidence
+// in log-odds that Posterior adds the prior to: the risk less what unaffected relatives
+// take away.
+type Explanation struct {
+	Initial  *big.Int
+	Terms    []Term
+	Evidence *big.Int
+}
+
+// Excluded returns the relatives left out for lack of consent
+func (e *Explanation) Excluded() []string {
+	excluded := []string{}
+	for _, term := range e.Terms {
+		if term.Status == TermNoConsent {
+			excluded = append(excluded, term.NationalID)
+		}
+	}
+	return excluded
+}
+
+// Compute adds up the weighted contributions of the ancestors in each generation under
+// the target key. Ancestors the consent check turns down are left out and returned.
+// A nil consent check includes everyone.
+func (s *RiskService) Compute(target *Pailler.PublicKey, nationalID string, generations [][]string, weight int, diseaseIndex int, consent ConsentFunc) (*big.Int, []string, error) {
+	result, explanation, err := s.Explain(target, nationalID, generations, weight, diseaseIndex, consent)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, explanation.Excluded(), nil
+}
+
+// Explain computes the same risk as Compute and also returns how each relative took part
+func (s *RiskService) Explain(target *Pailler.PublicKey, nationalID string, generations [][]string, weight int, diseaseIndex int, consent ConsentFunc) (*big.Int, *Explanation, error) {
+	slot := riskSlot("risk", nationalID, diseaseIndex)
+	result, err := s.Randomness.Encrypt(target, 0, slot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	explanation := &Explanation{Initial: result, Evidence: result}
+	for index, generation := range generations {
+		level := index + 1
+		for _, ancestorID := range generation {
+			term := Term{NationalID: ancestorID, Relationship: AncestorRelationship(level), Level: level, Weight: weight / level}
+			if consent != nil {
+				consented, err := consent(ancestorID)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !consented {
+					term.Status = TermNoConsent
+					explanation.Terms = append(explanation.Terms, term)
+					continue
+				}
+			}
+			relative, status, err := s.relative(ancestorID, diseaseIndex)
+			if err != nil {
+				return nil, nil, err
+			}
+			term.Status = status
+			var evidence *big.Int
+			term.Contribution, evidence, term.Penetrance, err = s.contribution(relative, ancestorID, target, weight, level, diseaseIndex, slot+"/"+ancestorID)
+			if err != nil {
+				return nil, nil, err
+			}
+			result, err = target.Add(result, term.Contribution)
+			if err != nil {
+				return nil, nil, err
+			}
+			explanation.Evidence, err = target.Add(explanation.Evidence, evidence)
+			if err != nil {
+				return nil, nil, err
+			}
+			explanation.Terms = append(explanation.Terms, term)
+		}
+	}
+	return result, explanation, nil
+}
+
+// DiseaseWeight is a disease of a risk profile with the weight of an affected parent
+type DiseaseWeight struct {
+	Index  int
+	Weight int
+}
+
+// ComputeProfile computes the risk of each disease as Compute does, in one traversal of
+// the ancestors: each ancestor's consent is checked, their record read and their key
+// fetched once for all the diseases. The risks are keyed by disease index. They are
+// fresh ciphertexts, not the ones Compute returns, but decrypt to the same risk Compute
+// gives for each disease on its own.
+func (s *RiskService) ComputeProfile(target *Pailler.PublicKey, nationalID string, generations [][]string, diseases []DiseaseWeight, consent ConsentFunc) (map[int]*big.Int, []string, error) {
+	initial, err := s.Randomness.Encrypt(target, 0, "profile/"+nationalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	risks := make(map[int]*big.Int, len(diseases))
+	for _, disease := range diseases {
+		risks[disease.Index] = initial
+	}
+
+	excluded := []string{}
+	for index, generation := range generations {
+		level := index + 1
+		for _, ancestorID := range generation {
+			if consent != nil {
+				consented, err := consent(ancestorID)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !consented {
+					excluded = append(excluded, ancestorID)
+					continue
+				}
+			}
+			relative, _, err := s.lookup(ancestorID)
+			if err != nil {
+				return nil, nil, err
+			}
+			var relativeKey *Pailler.PrivateKey
+			for _, disease := range diseases {
+				var term *big.Int
+				slot := riskSlot("profile", nationalID, disease.Index) + "/" + ancestorID
+				if relative == nil || relative.DiseaseTable[disease.Index] == nil {
+					term, err = s.Randomness.Encrypt(target, 0, slot)
+				} else {
+					if relativeKey == nil {
+						relativeKey, err = s.Keys.PatientKey(relative)
+						if err != nil {
+							return nil, nil, err
+						}
+					}
+					term, _, _, err = s.weigh(relativeKey, relative, ancestorID, target, disease.Weight, level, disease.Index, slot)
+				}
+				if err != nil {
+					return nil, nil, err
+				}
+				risks[disease.Index], err = target.Add(risks[disease.Index], term)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	return risks, excluded, nil
+}
+
+// relative looks up a relative for a risk computation and tells whether their record
+// contributes. Missing and erased relatives are returned as nil.
+func (s *RiskService) relative(nationalID string, diseaseIndex int) (*Patient, TermStatus, error) {
+	relative, status, err := s.lookup(nationalID)
+	if relative == nil || err != nil {
+		return nil, status, err
+	}
+	if relative.DiseaseTable[diseaseIndex] == nil {
+		return relative, TermNoDiseaseData, nil
+	}
+	return relative, TermIncluded, nil
+}
+
+// lookup reads a relative's record, returning nil with the reason for missing and erased relatives
+func (s *RiskService) lookup(nationalID string) (*Patient, TermStatus, error) {
+	relative, err := s.Patients.Get(nationalID)
+	var serviceError *Error
+	if errors.As(err, &serviceError) {
+		switch serviceError.Kind {
+		case KindNotFound:
+			return nil, TermNotFound, nil
+		case KindErased:
+			return nil, TermErased, nil
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return relative, TermIncluded, nil
+}
+
+// AncestorRelationship names the ancestors of a generation, parents being level 1
+func AncestorRelationship(level int) string {
+	switch {
+	case level <= 1:
+		return "parent"
+	case level == 2:
+		return "grandparent"
+	default:
+		return strings.Repeat("great-", level-2) + "grandparent"
+	}
+}
+
+// Contribution computes a relative's weighted contribution under their own key and
+// re-encrypts it under the target key when the two differ. Missing or erased relatives,
+// passed as nil, contribute nothing.
+func (s *RiskService) Contribution(relative *Patient, nationalID string, target *Pailler.PublicKey, weight int, level int, diseaseIndex int) (*big.Int, error) {
+	result, _, _, err := s.contribution(relative, nationalID, target, weight, level, diseaseIndex, riskSlot("contribution", nationalID, diseaseIndex))
+	return result, err
+}
+
+// contribution is Contribution, also returning the relative's family evidence and the
+// penetrance applied. The ciphertexts it draws are labelled under slot.
+func (s *RiskService) contribution(relative *Patient, nationalID string, target *Pailler.PublicKey, weight int, level int, diseaseIndex int, slot string) (*big.Int, *big.Int, int, error) {
+	if relative == nil || relative.DiseaseTable[diseaseIndex] == nil {
+		result, err := s.Randomness.Encrypt(target, 0, slot)
+		return result, result, 0, err
+	}
+
+	relativeKey, err := s.Keys.PatientKey(relative)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return s.weigh(relativeKey, relative, nationalID, target, weight, level, diseaseIndex, slot)
+}
+
+// weigh computes the contribution of a relative with data for the disease whose key has
+// already been fetched, and their family evidence. With a = weight/level and
+// u = a * penetrance / 100, the contribution is flag * a and the evidence
+// flag * (a + u) - u: a for an affected relative and -u for an unaffected one. The flag
+// stays encrypted; only the non-negative flag * a and flag * u are re-encrypted between
+// keys, and -u is added under the target key.
+func (s *RiskService) weigh(relativeKey *Pailler.PrivateKey, relative *Patient, nationalID string, target *Pailler.PublicKey, weight int, level int, diseaseIndex int, slot string) (*big.Int, *big.Int, int, error) {
+	penetrance := 0
+	if s.Penetrance != nil {
+		var err error
+		penetrance, err = s.Penetrance(relative, diseaseIndex)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if penetrance < 0 || penetrance > 100 {
+			return nil, nil, 0, fmt.Errorf("penetrance %d is not a percentage", penetrance)
+		}
+	}
+	affected := int64(weight / level)
+	unaffected := affected * int64(penetrance) / 100
+
+	contribution, err := s.weighFlag(relativeKey, relative.DiseaseTable[diseaseIndex], affected, target, slot+"/affected")
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if unaffected == 0 {
+		return contribution, contribution, penetrance, nil
+	}
+	evidence, err := s.weighFlag(relativeKey, relative.DiseaseTable[diseaseIndex], unaffected, target, slot+"/unaffected")
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	evidence, err = target.Add(contribution, evidence)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	evidence, err = target.AddSignedPlaintext(evidence, -unaffected)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return contribution, evidence, penetrance, nil
+}
+
+// weighFlag multiplies an encrypted flag by a non-negative weight under the relative's
+// key and re-encrypts the product under the target key when the two differ
+func (s *RiskService) weighFlag(relativeKey *Pailler.PrivateKey, flag *big.Int, weight int64, target *Pailler.PublicKey, slot string) (*big.Int, error) {
+	product, err := relativeKey.Pk.MultPlaintext(flag, weight)
+	if err != nil {
+		return nil, err
+	}
+	if relativeKey.Pk.N.Cmp(target.N) != 0 {
+		return s.Randomness.Reencrypt(relativeKey, product, target, slot)
+	}
+	return product, nil
+}
+
+// riskSlot labels the ciphertexts drawn for a patient's risk of a disease
+func riskSlot(computation string, nationalID string, diseaseIndex int) string {
+	return computation + "/" + nationalID + "/" + strconv.Itoa(diseaseIndex)
+}
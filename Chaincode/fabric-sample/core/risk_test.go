@@ -0,0 +1,183 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// riskFixture is a pedigree of parents 115 and 116 and grandparents 119 and 120. Parent
+// 115 and grandparent 119 have disease 0, grandparent 120 has disease 1. Grandparent 120
+// has their own key, the others use the family key.
+type riskFixture struct {
+	risk      *RiskService
+	familyKey *Pailler.PrivateKey
+}
+
+var testGenerations = [][]string{{"115", "116"}, {"119", "120"}}
+
+func newRiskFixture(t *testing.T) *riskFixture {
+	t.Helper()
+	familyKey := testKey(t, "family 22")
+	epoch := 1
+	keys := newKeyService(familyKey, &epoch)
+	patients := newPatientService()
+
+	flags := map[string][]int64{"115": {1, 0}, "116": {0, 0}, "119": {1, 0}, "120": {0, 1}}
+	for _, nationalID := range []string{"115", "116", "119", "120"} {
+		key := familyKey
+		patient := &Patient{NationalID: nationalID, FamilyID: "22"}
+		if nationalID == "120" {
+			key = testKey(t, "patient 120")
+			patient.KeyScope = KeyScopePatient
+			err := keys.PutPatientKey(nationalID, key, familyKey, epoch)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		patient.DiseaseTable = encryptAll(t, key.Pk, flags[nationalID]...)
+		patient.KeyFingerprint = key.Pk.Fingerprint()
+		err := patients.Put(patient, "2026-01-01T00:00:00Z")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &riskFixture{risk: &RiskService{Patients: patients, Keys: keys}, familyKey: familyKey}
+}
+
+// consentOf returns a consent check that turns down the listed relatives
+func consentOf(refused ...string) ConsentFunc {
+	return func(nationalID string) (bool, error) {
+		for _, id := range refused {
+			if id == nationalID {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+func TestRiskServiceCompute(t *testing.T) {
+	fixture := newRiskFixture(t)
+	err := fixture.risk.Patients.State.Put(NewKey(PatientNamespace, "116"), []byte(`{"erased":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		generations  [][]string
+		diseaseIndex int
+		consent      ConsentFunc
+		risk         int64
+		excluded     []string
+	}{
+		// 100/1 for parent 115 and 100/2 for grandparent 119
+		{name: "affected parent and grandparent", generations: testGenerations, risk: 150, excluded: []string{}},
+		// Grandparent 120 has their own key, so their contribution is re-encrypted
+		{name: "relative under their own key", generations: testGenerations, diseaseIndex: 1, risk: 50, excluded: []string{}},
+		{name: "consent refused", generations: testGenerations, consent: consentOf("115"), risk: 50, excluded: []string{"115"}},
+		{name: "missing relative", generations: [][]string{{"115", "999"}}, risk: 100, excluded: []string{}},
+		{name: "no generations", risk: 0, excluded: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, excluded, err := fixture.risk.Compute(fixture.familyKey.Pk, "130", test.generations, 100, test.diseaseIndex, test.consent)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := decrypt(t, fixture.familyKey, result); got != test.risk {
+				t.Errorf("risk = %d, want %d", got, test.risk)
+			}
+			if !reflect.DeepEqual(excluded, test.excluded) {
+				t.Errorf("excluded = %v, want %v", excluded, test.excluded)
+			}
+		})
+	}
+}
+
+func TestRiskServiceExplain(t *testing.T) {
+	fixture := newRiskFixture(t)
+	result, explanation, err := fixture.risk.Explain(fixture.familyKey.Pk, "130", testGenerations, 100, 0, consentOf("116"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := map[string]TermStatus{}
+	sum := decrypt(t, fixture.familyKey, explanation.Initial)
+	for _, term := range explanation.Terms {
+		statuses[term.NationalID] = term.Status
+		if term.Contribution != nil {
+			sum += decrypt(t, fixture.familyKey, term.Contribution)
+		}
+	}
+	want := map[string]TermStatus{"115": TermIncluded, "116": TermNoConsent, "119": TermIncluded, "120": TermIncluded}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if risk := decrypt(t, fixture.familyKey, result); sum != risk {
+		t.Errorf("contributions add up to %d, the risk is %d", sum, risk)
+	}
+}
+
+// Unaffected relatives take weight away from the evidence in proportion to the penetrance,
+// here 50% for everyone, but not from the risk
+func TestRiskServicePenetrance(t *testing.T) {
+	fixture := newRiskFixture(t)
+	fixture.risk.Penetrance = func(relative *Patient, diseaseIndex int) (int, error) {
+		return 50, nil
+	}
+	tests := []struct {
+		diseaseIndex int
+		risk         int64
+		evidence     int64
+	}{
+		// 115 and 119 add 100 and 50, 116 and 120 take 50 and 25 away
+		{diseaseIndex: 0, risk: 150, evidence: 75},
+		// 120 adds 50, 115, 116 and 119 take 50, 50 and 25 away
+		{diseaseIndex: 1, risk: 50, evidence: -75},
+	}
+	for _, test := range tests {
+		result, explanation, err := fixture.risk.Explain(fixture.familyKey.Pk, "130", testGenerations, 100, test.diseaseIndex, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if risk := decrypt(t, fixture.familyKey, result); risk != test.risk {
+			t.Errorf("disease %d: risk = %d, want %d", test.diseaseIndex, risk, test.risk)
+		}
+		if evidence := decrypt(t, fixture.familyKey, explanation.Evidence); evidence != test.evidence {
+			t.Errorf("disease %d: evidence = %d, want %d", test.diseaseIndex, evidence, test.evidence)
+		}
+	}
+}
+
+func TestRiskServiceComputeProfile(t *testing.T) {
+	fixture := newRiskFixture(t)
+	diseases := []DiseaseWeight{{Index: 0, Weight: 100}, {Index: 1, Weight: 70}}
+	risks, excluded, err := fixture.risk.ComputeProfile(fixture.familyKey.Pk, "130", testGenerations, diseases, consentOf("115"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(excluded, []string{"115"}) {
+		t.Errorf("excluded = %v", excluded)
+	}
+	for _, disease := range diseases {
+		single, _, err := fixture.risk.Compute(fixture.familyKey.Pk, "130", testGenerations, disease.Weight, disease.Index, consentOf("115"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		profile, want := decrypt(t, fixture.familyKey, risks[disease.Index]), decrypt(t, fixture.familyKey, single)
+		if profile != want {
+			t.Errorf("disease %d: profile risk %d, Compute risk %d", disease.Index, profile, want)
+		}
+	}
+}
+
+func TestAncestorRelationship(t *testing.T) {
+	for level, want := range map[int]string{1: "parent", 2: "grandparent", 4: "great-great-grandparent"} {
+		if got := AncestorRelationship(level); got != want {
+			t.Errorf("AncestorRelationship(%d) = %q, want %q", level, got, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package core
+
+import (
+	"os"
+	"strings"
+)
+
+// StateDatabaseEnv names the environment variable telling the chaincode which state
+// database its peer runs, set like the peer's CORE_LEDGER_STATE_STATEDATABASE to
+// "goleveldb" or "CouchDB". A peer does not tell chaincodes which one it has, and LevelDB
+// is the Fabric default, so rich queries are only run when it is set to CouchDB.
+const StateDatabaseEnv = "GENCHAIN_STATE_DATABASE"
+
+// RichQueries reports whether the peer's state database serves CouchDB queries
+func RichQueries() bool {
+	return strings.EqualFold(os.Getenv(StateDatabaseEnv), "CouchDB")
+}
@@ -0,0 +1,41 @@
+// Package core holds the patient model, the risk computation and the key handling
+// shared by the shim and the contract API chaincodes. It knows nothing about Fabric:
+// every read and write goes through a Store, and each chaincode supplies an adapter
+// over its stub together with a codec for its stored patient records.
+package core
+
+// Key addresses a value by namespace and attributes, the same way as a composite key
+type Key struct {
+	Namespace  string
+	Attributes []string
+}
+
+// NewKey returns the key of the attributes in the namespace
+func NewKey(namespace string, attributes ...string) Key {
+	return Key{Namespace: namespace, Attributes: attributes}
+}
+
+// Version is one modification of a key, oldest first in a history
+type Version struct {
+	TxID      string
+	Timestamp string
+	Value     []byte
+	IsDelete  bool
+}
+
+// Store is a transactional key-value store such as a channel's world state or a private
+// data collection. Get returns nil for a missing key. Purge deletes a key together with
+// every earlier copy of its value, which only private data supports. Range visits every
+// key that starts with the prefix's attributes, in key order, and stops at the first
+// error the visitor returns. RangePage visits at most pageSize of those keys, starting at
+// the bookmark of the previous page, and returns the number of keys it read and the
+// bookmark of the next page, which is empty after the last one.
+type Store interface {
+	Get(key Key) ([]byte, error)
+	Put(key Key, value []byte) error
+	Delete(key Key) error
+	Purge(key Key) error
+	Range(prefix Key, visit func(key Key, value []byte) error) error
+	RangePage(prefix Key, pageSize int32, bookmark string, visit func(key Key, value []byte) error) (int32, string, error)
+	History(key Key) ([]Version, error)
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// testKeyBits keeps the keys of the tests small enough to generate quickly
+const testKeyBits = 256
+
+// memStore is a Store over a map, keeping every version of each key under txID
+type memStore struct {
+	values  map[string][]byte
+	history map[string][]Version
+	txID    string
+}
+
+func newMemStore() *memStore {
+	return &memStore{values: map[string][]byte{}, history: map[string][]Version{}}
+}
+
+func storeKey(key Key) string {
+	return key.Namespace + "\x00" + strings.Join(key.Attributes, "\x00") + "\x00"
+}
+
+func (s *memStore) Get(key Key) ([]byte, error) {
+	return s.values[storeKey(key)], nil
+}
+
+func (s *memStore) Put(key Key, value []byte) error {
+	s.values[storeKey(key)] = value
+	s.history[storeKey(key)] = append(s.history[storeKey(key)], Version{TxID: s.txID, Value: value})
+	return nil
+}
+
+func (s *memStore) Delete(key Key) error {
+	delete(s.values, storeKey(key))
+	s.history[storeKey(key)] = append(s.history[storeKey(key)], Version{TxID: s.txID, IsDelete: true})
+	return nil
+}
+
+func (s *memStore) Purge(key Key) error {
+	delete(s.values, storeKey(key))
+	delete(s.history, storeKey(key))
+	return nil
+}
+
+func (s *memStore) Range(prefix Key, visit func(key Key, value []byte) error) error {
+	for _, id := range s.ids(prefix) {
+		err := visit(parseStoreKey(id), s.values[id])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RangePage bookmarks the first key of the next page
+func (s *memStore) RangePage(prefix Key, pageSize int32, bookmark string, visit func(key Key, value []byte) error) (int32, string, error) {
+	var fetched int32
+	for _, id := range s.ids(prefix) {
+		if id < bookmark {
+			continue
+		}
+		if fetched == pageSize {
+			return fetched, id, nil
+		}
+		err := visit(parseStoreKey(id), s.values[id])
+		if err != nil {
+			return 0, "", err
+		}
+		fetched++
+	}
+	return fetched, "", nil
+}
+
+// ids returns the stored keys under the prefix in key order
+func (s *memStore) ids(prefix Key) []string {
+	start := storeKey(prefix)
+	if len(prefix.Attributes) == 0 {
+		start = prefix.Namespace + "\x00"
+	}
+	var ids []string
+	for id := range s.values {
+		if strings.HasPrefix(id, start) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func parseStoreKey(id string) Key {
+	parts := strings.Split(strings.TrimSuffix(id, "\x00"), "\x00")
+	return NewKey(parts[0], parts[1:]...)
+}
+
+func (s *memStore) History(key Key) ([]Version, error) {
+	return s.history[storeKey(key)], nil
+}
+
+// jsonCodec stores core patients as they are
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(value []byte) (*Patient, error) {
+	patient := new(Patient)
+	return patient, json.Unmarshal(value, patient)
+}
+
+func (jsonCodec) Encode(patient *Patient) ([]byte, error) {
+	return json.Marshal(patient)
+}
+
+// testKey derives a small key pair from a name
+func testKey(t *testing.T, name string) *Pailler.PrivateKey {
+	t.Helper()
+	seed := sha256.Sum256([]byte(name))
+	_, privateKey, err := Pailler.GenerateKeyPairFromSeed(seed[:], testKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+// encryptAll encrypts plaintext values under the key
+func encryptAll(t *testing.T, key *Pailler.PublicKey, values ...int64) []*big.Int {
+	t.Helper()
+	ciphertexts := make([]*big.Int, len(values))
+	for index, value := range values {
+		ciphertext, err := key.Encrypt(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertexts[index] = ciphertext
+	}
+	return ciphertexts
+}
+
+// decrypt decrypts a ciphertext that may hold a negative value
+func decrypt(t *testing.T, key *Pailler.PrivateKey, ciphertext *big.Int) int64 {
+	t.Helper()
+	value, err := key.DecryptInRange(ciphertext, -1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
+)
+
+// Identity is the client identity of a transaction, such as the one cid reads from the
+// creator's certificate
+type Identity interface {
+	GetID() (string, error)
+	GetMSPID() (string, error)
+	GetAttributeValue(name string) (value string, found bool, err error)
+}
+
+// Transaction is the transaction the services run in. Each chaincode supplies an
+// adapter over its stub.
+type Transaction interface {
+	TxID() string
+	Time() (time.Time, error)
+	Client() (Identity, error)
+	Transient() (map[string][]byte, error)
+	SetEvent(name string, payload []byte) error
+}
+
+// Timestamp returns the transaction time in RFC 3339 form, in UTC
+func Timestamp(tx Transaction) (string, error) {
+	txTime, err := tx.Time()
+	if err != nil {
+		return "", err
+	}
+	return txTime.UTC().Format(time.RFC3339), nil
+}
+
+// Year returns the year of the transaction time
+func Year(tx Transaction) (int, error) {
+	txTime, err := tx.Time()
+	if err != nil {
+		return 0, err
+	}
+	return txTime.UTC().Year(), nil
+}
+
+// TransientSeed returns the key seed the client passed in the given transient field
+func TransientSeed(tx Transaction, field string) ([]byte, error) {
+	transient, err := tx.Transient()
+	if err != nil {
+		return nil, err
+	}
+	seed, ok := transient[field]
+	if !ok || len(seed) < 32 {
+		return nil, InvalidArgument(field, "a %s of at least 32 bytes must be passed in the transient map", field)
+	}
+	return seed, nil
+}
+
+// HasTransient reports whether the client passed the given transient field
+func HasTransient(tx Transaction, field string) (bool, error) {
+	transient, err := tx.Transient()
+	if err != nil {
+		return false, err
+	}
+	_, ok := transient[field]
+	return ok, nil
+}
+
+// EmitEvent stamps the event with the transaction and sets it as the chaincode event.
+// A transaction carries at most one event, so each transaction emits a single one.
+func EmitEvent(tx Transaction, event *events.Event) error {
+	timestamp, err := Timestamp(tx)
+	if err != nil {
+		return err
+	}
+	event.TxID = tx.TxID()
+	event.Timestamp = timestamp
+	payload, err := event.Marshal()
+	if err != nil {
+		return err
+	}
+	return tx.SetEvent(event.Type, payload)
+}
+
+// AddPatientCiphertexts adds the patient and references to their disease values to the event
+func AddPatientCiphertexts(event *events.Event, patient *Patient) {
+	event.PatientNationalIDs = append(event.PatientNationalIDs, patient.NationalID)
+	for index, value := range patient.DiseaseTable {
+		event.AddCiphertext(patient.NationalID, index, value)
+	}
+}
+
+// EmitRiskComputed announces encrypted risks under the key with the given fingerprint
+func EmitRiskComputed(tx Transaction, nationalID string, diseaseIndex int, keyFingerprint string, risks ...*big.Int) error {
+	event := events.New(events.RiskComputed).WithDiseaseIndex(diseaseIndex)
+	for _, risk := range risks {
+		event.AddCiphertext(nationalID, diseaseIndex, risk)
+	}
+	event.PatientNationalIDs = []string{nationalID}
+	event.KeyFingerprint = keyFingerprint
+	return EmitEvent(tx, event)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
+)
+
+// testTransaction is a Transaction at a fixed time that keeps the event it is given
+type testTransaction struct {
+	time      time.Time
+	client    attributes
+	transient map[string][]byte
+	eventName string
+	event     []byte
+}
+
+func newTestTransaction() *testTransaction {
+	return &testTransaction{time: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), client: attributes{}}
+}
+
+func (tx *testTransaction) TxID() string {
+	return "tx1"
+}
+
+func (tx *testTransaction) Time() (time.Time, error) {
+	return tx.time, nil
+}
+
+func (tx *testTransaction) Client() (Identity, error) {
+	return tx.client, nil
+}
+
+func (tx *testTransaction) Transient() (map[string][]byte, error) {
+	return tx.transient, nil
+}
+
+func (tx *testTransaction) SetEvent(name string, payload []byte) error {
+	tx.eventName, tx.event = name, payload
+	return nil
+}
+
+func TestTransientSeed(t *testing.T) {
+	tx := newTestTransaction()
+	tx.transient = map[string][]byte{"short": []byte("seed"), "keySeed": make([]byte, 32)}
+	for field, code := range map[string]string{"missing": CodeInvalidArgument, "short": CodeInvalidArgument, "keySeed": ""} {
+		if _, err := TransientSeed(tx, field); errorCode(err) != code {
+			t.Errorf("TransientSeed(%s) = %v, want %q", field, err, code)
+		}
+	}
+}
+
+// An emitted event carries the transaction ID and time, and decodes as it was built
+func TestEmitRiskComputed(t *testing.T) {
+	tx := newTestTransaction()
+	err := EmitRiskComputed(tx, "115", 1, "fingerprint", big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := events.Decode(tx.eventName, tx.event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.TxID != "tx1" || event.Timestamp != "2026-03-01T12:00:00Z" || event.KeyFingerprint != "fingerprint" {
+		t.Errorf("event = %+v", event)
+	}
+	if len(event.PatientNationalIDs) != 1 || len(event.Ciphertexts) != 1 || event.Ciphertexts[0].Digest != events.Digest(big.NewInt(7)) {
+		t.Errorf("event = %+v", event)
+	}
+}
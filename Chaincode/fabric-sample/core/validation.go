@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits of patient input
+const (
+	MaxIDDigits      = 11
+	MaxNameLength    = 256
+	MaxDiseaseValue  = 1
+	MinBirthYear     = 1900
+	DiseaseSlotCount = 3
+)
+
+// ValidateID checks that an ID is a positive decimal number of at most MaxIDDigits
+// digits without a sign or leading zeros
+func ValidateID(field string, id string) error {
+	if id == "" || len(id) > MaxIDDigits {
+		return InvalidArgument(field, "must have between 1 and %d digits", MaxIDDigits)
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return InvalidArgument(field, "%q is not a number", id)
+		}
+	}
+	if id[0] == '0' {
+		return InvalidArgument(field, "%q must be positive and have no leading zeros", id)
+	}
+	return nil
+}
+
+func ValidateName(name string) error {
+	if name == "" || !utf8.ValidString(name) || utf8.RuneCountInString(name) > MaxNameLength {
+		return InvalidArgument("patientName", "must be valid text of 1 to %d characters", MaxNameLength)
+	}
+	return nil
+}
+
+func ValidateDiseaseIndex(diseaseIndex int) error {
+	if diseaseIndex < 0 || diseaseIndex >= DiseaseSlotCount {
+		return InvalidArgument("diseaseIndex", "%d is not between 0 and %d", diseaseIndex, DiseaseSlotCount-1)
+	}
+	return nil
+}
+
+// ValidateGenotype checks a plaintext genotype is one of the core genotypes
+func ValidateGenotype(genotype int) error {
+	if genotype < GenotypeNonCarrier || genotype > GenotypeAffected {
+		return InvalidArgument("genotype", "%d is not 0 (non-carrier), 1 (carrier) or 2 (affected)", genotype)
+	}
+	return nil
+}
+
+// ValidateBirthYear checks a birth year lies between MinBirthYear and the current year
+func ValidateBirthYear(birthYear int, currentYear int) error {
+	if birthYear < MinBirthYear || birthYear > currentYear {
+		return InvalidArgument("birthYear", "%d is not between %d and %d", birthYear, MinBirthYear, currentYear)
+	}
+	return nil
+}
+
+// ValidateDiseaseValues checks the plaintext disease flags of a new patient
+func ValidateDiseaseValues(values ...int) error {
+	for index, value := range values {
+		if value < 0 || value > MaxDiseaseValue {
+			return InvalidArgument(fmt.Sprintf("disease[%d]", index), "%d is not between 0 and %d", value, MaxDiseaseValue)
+		}
+	}
+	return nil
+}
+
+// ValidateNew checks no patient, live or erased, holds the national ID. An erased
+// patient's tombstone keeps the ID taken so the erasure stays on record.
+func (s *PatientService) ValidateNew(nationalID string) error {
+	value, err := s.State.Get(NewKey(PatientNamespace, nationalID))
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	if IsTombstone(value) {
+		return AlreadyExists("patient %s has been erased and the ID cannot be reused", nationalID)
+	}
+	return AlreadyExists("patient %s already exists", nationalID)
+}
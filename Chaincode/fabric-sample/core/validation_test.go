@@ -0,0 +1,67 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateID(t *testing.T) {
+	for id, code := range map[string]string{
+		"115":          "",
+		"99999999999":  "",
+		"":             CodeInvalidArgument,
+		"0115":         CodeInvalidArgument,
+		"-115":         CodeInvalidArgument,
+		"11a":          CodeInvalidArgument,
+		"999999999999": CodeInvalidArgument,
+	} {
+		if err := ValidateID("patientNationalID", id); errorCode(err) != code {
+			t.Errorf("ValidateID(%q) = %v, want %q", id, err, code)
+		}
+	}
+}
+
+func TestValidatePatientInput(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code string
+	}{
+		{name: "name", err: ValidateName("Nusret")},
+		{name: "empty name", err: ValidateName(""), code: CodeInvalidArgument},
+		{name: "long name", err: ValidateName(strings.Repeat("a", MaxNameLength+1)), code: CodeInvalidArgument},
+		{name: "invalid text", err: ValidateName("\xff"), code: CodeInvalidArgument},
+		{name: "disease index", err: ValidateDiseaseIndex(DiseaseSlotCount - 1)},
+		{name: "negative disease index", err: ValidateDiseaseIndex(-1), code: CodeInvalidArgument},
+		{name: "genotype", err: ValidateGenotype(GenotypeAffected)},
+		{name: "unknown genotype", err: ValidateGenotype(3), code: CodeInvalidArgument},
+		{name: "birth year", err: ValidateBirthYear(1990, 2026)},
+		{name: "future birth year", err: ValidateBirthYear(2027, 2026), code: CodeInvalidArgument},
+		{name: "early birth year", err: ValidateBirthYear(MinBirthYear-1, 2026), code: CodeInvalidArgument},
+		{name: "disease values", err: ValidateDiseaseValues(0, 1, 0)},
+		{name: "disease value out of range", err: ValidateDiseaseValues(0, 2, 0), code: CodeInvalidArgument},
+	}
+	for _, test := range tests {
+		if code := errorCode(test.err); code != test.code {
+			t.Errorf("%s: %v, want %q", test.name, test.err, test.code)
+		}
+	}
+}
+
+// The national ID of a live or an erased patient cannot be taken by a new patient
+func TestPatientServiceValidateNew(t *testing.T) {
+	patients := newPatientService()
+	err := patients.Put(&Patient{Name: "Asli", NationalID: "116", FamilyID: "22"}, "2026-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = patients.State.Put(NewKey(PatientNamespace, "117"), []byte(`{"erased":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for nationalID, code := range map[string]string{"116": CodeAlreadyExists, "117": CodeAlreadyExists, "118": ""} {
+		if err := patients.ValidateNew(nationalID); errorCode(err) != code {
+			t.Errorf("ValidateNew(%s) = %v, want %q", nationalID, err, code)
+		}
+	}
+}
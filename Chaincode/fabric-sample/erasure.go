@@ -0,0 +1,203 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// keyCollection is the private data collection holding family private keys. Private
+// data never reaches the ordered blocks, so purging it leaves no copy on the ledger. The
+// keys are derived from seeds the client passes in the transient map, so whoever keeps
+// a seed can derive its key again: purging a key ends the ledger's copy, not the key.
+const keyCollection = "genchainKeys"
+
+// FamilyKeyRecord is the public half of a re-keyed family. The matching private key
+// for the current epoch is kept in keyCollection. Legacy marks the key MigrateLedger
+// gave a family from an earlier version of the contract, which anyone can derive from
+// the family ID with GenerateKeyPair.
+type FamilyKeyRecord struct {
+	PatientFamilyID int                `json:"patientFamilyID"`
+	Epoch           int                `json:"epoch"`
+	PublicKey       *Pailler.PublicKey `json:"publicKey"`
+	Erased          bool               `json:"erased"`
+	Legacy          bool               `json:"legacy,omitempty" metadata:",optional"`
+}
+
+// Tombstone replaces an erased patient record in the world state
+type Tombstone struct {
+	DocType           string `json:"docType"`
+	PatientNationalID int    `json:"patientNationalID"`
+	PatientFamilyID   int    `json:"patientFamilyID"`
+	Erased            bool   `json:"erased"`
+	ReasonCode        string `json:"reasonCode"`
+	ErasedAt          string `json:"erasedAt"`
+	TxID              string `json:"txID"`
+}
+
+// ErasureReceipt is stored on the ledger and emitted as an event for every erasure.
+// PurgedKey is the fingerprint of the key the erased records were encrypted under and
+// KeyPurged tells whether the erasure removed the ledger's copies of it, which it cannot
+// for a legacy key. Neither says the key is gone: a key can be derived again from the
+// seed it came from.
+type ErasureReceipt struct {
+	ReceiptID       string `json:"receiptID"`
+	Scope           string `json:"scope"`
+	PatientFamilyID int    `json:"patientFamilyID"`
+	ErasedPatients  []int  `json:"erasedPatients"`
+	ReKeyedPatients []int  `json:"reKeyedPatients"`
+	ReasonCode      string `json:"reasonCode"`
+	ErasedAt        string `json:"erasedAt"`
+	PurgedKey       string `json:"purgedKey"`
+	KeyPurged       bool   `json:"keyPurged"`
+	ReplacementKey  string `json:"replacementKey,omitempty" metadata:",optional"`
+}
+
+// ErasePatient tombstones a patient and purges the key their records were encrypted
+// under, so no peer can decrypt the patient's historical ciphertexts from the ledger. A
+// patient with their own key only loses that key. For a patient under the family key, the rest
+// of the family is re-keyed under a new family key generated from the "keySeed"
+// transient field and the previous family key is purged.
+func (s *SmartContract) ErasePatient(ctx contractapi.TransactionContextInterface, patientNationalID string, reasonCode string) (_ *ErasureReceipt, err error) {
+	defer catalogError(&err)
+	receipt, err := erasureService(ctx).ErasePatient(patientNationalID, reasonCode)
+	if err != nil {
+		return nil, err
+	}
+	return erasureReceiptFromCore(receipt)
+}
+
+// EraseFamily tombstones every member of a family and purges the family key
+func (s *SmartContract) EraseFamily(ctx contractapi.TransactionContextInterface, patientFamilyID int, reasonCode string) (_ *ErasureReceipt, err error) {
+	defer catalogError(&err)
+	receipt, err := erasureService(ctx).EraseFamily(strconv.Itoa(patientFamilyID), reasonCode)
+	if err != nil {
+		return nil, err
+	}
+	return erasureReceiptFromCore(receipt)
+}
+
+// isErased reports whether a world state value is a tombstone
+func isErased(value []byte) bool {
+	return core.IsTombstone(value)
+}
+
+func (record *FamilyKeyRecord) toCore() *core.FamilyKeyRecord {
+	return &core.FamilyKeyRecord{
+		PatientFamilyID: strconv.Itoa(record.PatientFamilyID),
+		Epoch:           record.Epoch,
+		PublicKey:       record.PublicKey,
+		Erased:          record.Erased,
+		Legacy:          record.Legacy,
+	}
+}
+
+func familyKeyRecordFromCore(record *core.FamilyKeyRecord) (*FamilyKeyRecord, error) {
+	familyID, err := strconv.Atoi(record.PatientFamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return &FamilyKeyRecord{
+		PatientFamilyID: familyID,
+		Epoch:           record.Epoch,
+		PublicKey:       record.PublicKey,
+		Erased:          record.Erased,
+		Legacy:          record.Legacy,
+	}, nil
+}
+
+func (tombstone *Tombstone) toCore() *core.Tombstone {
+	return &core.Tombstone{
+		DocType:           tombstone.DocType,
+		PatientNationalID: strconv.Itoa(tombstone.PatientNationalID),
+		PatientFamilyID:   strconv.Itoa(tombstone.PatientFamilyID),
+		Erased:            tombstone.Erased,
+		ReasonCode:        tombstone.ReasonCode,
+		ErasedAt:          tombstone.ErasedAt,
+		TxID:              tombstone.TxID,
+	}
+}
+
+func tombstoneFromCore(tombstone *core.Tombstone) (*Tombstone, error) {
+	nationalID, err := strconv.Atoi(tombstone.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	familyID, err := strconv.Atoi(tombstone.PatientFamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return &Tombstone{
+		DocType:           tombstone.DocType,
+		PatientNationalID: nationalID,
+		PatientFamilyID:   familyID,
+		Erased:            tombstone.Erased,
+		ReasonCode:        tombstone.ReasonCode,
+		ErasedAt:          tombstone.ErasedAt,
+		TxID:              tombstone.TxID,
+	}, nil
+}
+
+func (receipt *ErasureReceipt) toCore() *core.ErasureReceipt {
+	return &core.ErasureReceipt{
+		ReceiptID:       receipt.ReceiptID,
+		Scope:           receipt.Scope,
+		PatientFamilyID: strconv.Itoa(receipt.PatientFamilyID),
+		ErasedPatients:  idStrings(receipt.ErasedPatients),
+		ReKeyedPatients: idStrings(receipt.ReKeyedPatients),
+		ReasonCode:      receipt.ReasonCode,
+		ErasedAt:        receipt.ErasedAt,
+		PurgedKey:       receipt.PurgedKey,
+		KeyPurged:       receipt.KeyPurged,
+		ReplacementKey:  receipt.ReplacementKey,
+	}
+}
+
+func erasureReceiptFromCore(receipt *core.ErasureReceipt) (*ErasureReceipt, error) {
+	familyID, err := strconv.Atoi(receipt.PatientFamilyID)
+	if err != nil {
+		return nil, err
+	}
+	erased, err := idValues(receipt.ErasedPatients)
+	if err != nil {
+		return nil, err
+	}
+	reKeyed, err := idValues(receipt.ReKeyedPatients)
+	if err != nil {
+		return nil, err
+	}
+	return &ErasureReceipt{
+		ReceiptID:       receipt.ReceiptID,
+		Scope:           receipt.Scope,
+		PatientFamilyID: familyID,
+		ErasedPatients:  erased,
+		ReKeyedPatients: reKeyed,
+		ReasonCode:      receipt.ReasonCode,
+		ErasedAt:        receipt.ErasedAt,
+		PurgedKey:       receipt.PurgedKey,
+		KeyPurged:       receipt.KeyPurged,
+		ReplacementKey:  receipt.ReplacementKey,
+	}, nil
+}
+
+func idStrings(ids []int) []string {
+	values := make([]string, len(ids))
+	for index, id := range ids {
+		values[index] = strconv.Itoa(id)
+	}
+	return values
+}
+
+func idValues(ids []string) ([]int, error) {
+	values := make([]int, len(ids))
+	for index, id := range ids {
+		value, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, err
+		}
+		values[index] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,169 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+var testKeySeed = []byte("genchain test erasure key seed, 32 bytes or more")
+
+// compositeKey returns the ledger key of a record
+func compositeKey(t *testing.T, namespace string, attributes ...string) string {
+	t.Helper()
+	key, err := shim.CreateCompositeKey(namespace, attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// erase submits an erasure and decodes its receipt
+func (tb *testbed) erase(function string, args ...string) (ErasureReceipt, string) {
+	tb.t.Helper()
+	result := tb.invoke(map[string][]byte{core.KeySeedField: testKeySeed}, function, args...)
+	if !result.Committed {
+		tb.t.Fatalf("%s %v: status %d: %s", function, args, result.Response.Status, result.Response.Message)
+	}
+	var receipt ErasureReceipt
+	err := json.Unmarshal(result.Response.Payload, &receipt)
+	if err != nil {
+		tb.t.Fatal(err)
+	}
+	return receipt, result.TxID
+}
+
+// checkErased fails the test unless the patient's record is a tombstone of the
+// transaction and can no longer be read
+func (tb *testbed) checkErased(nationalID string, txID string) {
+	tb.t.Helper()
+	var tombstone Tombstone
+	err := json.Unmarshal(tb.ledger.State(compositeKey(tb.t, core.PatientNamespace, nationalID)), &tombstone)
+	if err != nil {
+		tb.t.Fatal(err)
+	}
+	if !tombstone.Erased || tombstone.ReasonCode != core.ReasonSubjectRequest || tombstone.TxID != txID {
+		tb.t.Errorf("tombstone of %s = %+v", nationalID, tombstone)
+	}
+	checkResult(tb.t, tb.invoke(nil, "ReadAsset", nationalID), "ERASED")
+}
+
+// Erasing a patient under the family key purges it and moves the rest of the family to
+// a key derived from the keySeed
+func TestErasePatientFamilyKey(t *testing.T) {
+	tb := newTestbed(t)
+	tb.mustInvoke(nil, "ChangeAsset", "115", "0", "lab result")
+	before := tb.diseaseValues(familyKey(t, "22"), "115")
+
+	receipt, txID := tb.erase("ErasePatient", "116", core.ReasonSubjectRequest)
+	_, newKey, err := Pailler.GenerateKeyPairFromSeed(testKeySeed, core.FamilyKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(receipt.ReKeyedPatients)
+	want := ErasureReceipt{
+		ReceiptID:       txID,
+		Scope:           "patient",
+		PatientFamilyID: 22,
+		ErasedPatients:  []int{116},
+		ReKeyedPatients: []int{115, 117, 118, 119, 120, 121},
+		ReasonCode:      core.ReasonSubjectRequest,
+		ErasedAt:        receipt.ErasedAt,
+		PurgedKey:       familyKey(t, "22").Pk.Fingerprint(),
+		KeyPurged:       true,
+		ReplacementKey:  newKey.Pk.Fingerprint(),
+	}
+	if !reflect.DeepEqual(receipt, want) {
+		t.Errorf("receipt = %+v, want %+v", receipt, want)
+	}
+
+	tb.checkErased("116", txID)
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.FamilyKeyNamespace, "22", "0")) != nil {
+		t.Error("the erased patient's family key is still in the key collection")
+	}
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.FamilyKeyNamespace, "22", "1")) == nil {
+		t.Error("the replacement family key was not stored")
+	}
+	if after := tb.diseaseValues(newKey, "115"); !reflect.DeepEqual(after, before) {
+		t.Errorf("re-keyed record of 115 decrypts to %v, want %v", after, before)
+	}
+	checkResult(t, tb.invoke(map[string][]byte{core.KeySeedField: testKeySeed}, "ErasePatient", "116", core.ReasonSubjectRequest), "ERASED")
+}
+
+// Erasing a patient with their own key purges only that key
+func TestErasePatientOwnKey(t *testing.T) {
+	tb := newTestbed(t)
+	patientSeed := []byte("genchain test patient key seed, 32 bytes or more")
+	tb.mustInvoke(map[string][]byte{core.KeySeedField: patientSeed}, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	_, patientKey, err := core.DerivePatientKey("130", patientSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receipt, txID := tb.erase("ErasePatient", "130", core.ReasonSubjectRequest)
+	want := ErasureReceipt{
+		ReceiptID:       txID,
+		Scope:           "patient",
+		PatientFamilyID: 22,
+		ErasedPatients:  []int{130},
+		ReKeyedPatients: []int{},
+		ReasonCode:      core.ReasonSubjectRequest,
+		ErasedAt:        receipt.ErasedAt,
+		PurgedKey:       patientKey.Pk.Fingerprint(),
+		KeyPurged:       true,
+	}
+	if !reflect.DeepEqual(receipt, want) {
+		t.Errorf("receipt = %+v, want %+v", receipt, want)
+	}
+
+	tb.checkErased("130", txID)
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.PatientKeyNamespace, "130")) != nil {
+		t.Error("the erased patient's key is still in the key collection")
+	}
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.FamilyKeyNamespace, "22", "0")) == nil {
+		t.Error("the family key was purged with the patient's own key")
+	}
+	if got := tb.diseaseValues(familyKey(t, "22"), "115"); !reflect.DeepEqual(got, []int64{0, 0, 0}) {
+		t.Errorf("record of 115 decrypts to %v under the family key", got)
+	}
+}
+
+// Erasing a family tombstones every member and purges the family key and their own keys
+func TestEraseFamily(t *testing.T) {
+	tb := newTestbed(t)
+	tb.mustInvoke(map[string][]byte{core.KeySeedField: testKeySeed}, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+
+	receipt, txID := tb.erase("EraseFamily", "22", core.ReasonSubjectRequest)
+	sort.Ints(receipt.ErasedPatients)
+	want := ErasureReceipt{
+		ReceiptID:       txID,
+		Scope:           "family",
+		PatientFamilyID: 22,
+		ErasedPatients:  []int{115, 116, 117, 118, 119, 120, 121, 130},
+		ReKeyedPatients: []int{},
+		ReasonCode:      core.ReasonSubjectRequest,
+		ErasedAt:        receipt.ErasedAt,
+		PurgedKey:       familyKey(t, "22").Pk.Fingerprint(),
+		KeyPurged:       true,
+	}
+	if !reflect.DeepEqual(receipt, want) {
+		t.Errorf("receipt = %+v, want %+v", receipt, want)
+	}
+
+	for _, nationalID := range []string{"115", "116", "117", "118", "119", "120", "121", "130"} {
+		tb.checkErased(nationalID, txID)
+	}
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.FamilyKeyNamespace, "22", "0")) != nil {
+		t.Error("the family key is still in the key collection")
+	}
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.PatientKeyNamespace, "130")) != nil {
+		t.Error("the key of member 130 is still in the key collection")
+	}
+	checkResult(t, tb.invoke(nil, "TransferAsset", "130", "0"), "ERASED")
+	checkResult(t, tb.invoke(nil, "ReadAsset", "114"), "")
+}
@@ -0,0 +1,14 @@
+package chaincode
+
+import (
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// catalogError replaces the error a transaction returns with its core.ChaincodeError
+// form. Every transaction defers it on its named error result. The contract API always
+// answers failures with status 500, so the code's status is carried in the JSON.
+func catalogError(err *error) {
+	if *err != nil {
+		*err = core.AsChaincodeError(*err)
+	}
+}
@@ -0,0 +1,147 @@
+// Package events defines the chaincode events of the patient and risk lifecycle and
+// decodes them for subscribers. Events carry only identifiers and digests of
+// ciphertexts, never names or disease values, so that anyone allowed to listen on the
+// channel learns nothing beyond what changed.
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SchemaVersion is the version written into every event. Decode rejects events of a
+// newer version than it knows.
+const SchemaVersion = 1
+
+// Event names, used both as the chaincode event name and as the event type
+const (
+	PatientCreated     = "PatientCreated"
+	DiseaseFlagChanged = "DiseaseFlagChanged"
+	PatientDeleted     = "PatientDeleted"
+	FamilyKeyRotated   = "FamilyKeyRotated"
+	RiskComputed       = "RiskComputed"
+	GenotypeChanged    = "GenotypeChanged"
+)
+
+// ErrUnknownEvent is returned by Decode for chaincode events that are not part of this
+// schema, such as the erasure receipts
+var ErrUnknownEvent = errors.New("unknown event")
+
+// CiphertextRef points at a ciphertext on the ledger without revealing it
+type CiphertextRef struct {
+	PatientNationalID string `json:"patientNationalID,omitempty"`
+	DiseaseIndex      int    `json:"diseaseIndex"`
+	Digest            string `json:"digest"`
+}
+
+// Event is the payload of every lifecycle event. Fields that do not apply to a type
+// are left out.
+type Event struct {
+	SchemaVersion      int             `json:"schemaVersion"`
+	Type               string          `json:"type"`
+	TxID               string          `json:"txID"`
+	Timestamp          string          `json:"timestamp"`
+	PatientNationalIDs []string        `json:"patientNationalIDs,omitempty"`
+	PatientFamilyID    string          `json:"patientFamilyID,omitempty"`
+	DiseaseIndex       *int            `json:"diseaseIndex,omitempty"`
+	KeyFingerprint     string          `json:"keyFingerprint,omitempty"`
+	ReceiptID          string          `json:"receiptID,omitempty"`
+	Ciphertexts        []CiphertextRef `json:"ciphertexts,omitempty"`
+}
+
+// New returns an event of the given type for the current schema version
+func New(eventType string) *Event {
+	return &Event{SchemaVersion: SchemaVersion, Type: eventType}
+}
+
+// Digest is the reference to a ciphertext: the hex SHA-256 of its big-endian bytes
+func Digest(ciphertext *big.Int) string {
+	if ciphertext == nil {
+		return ""
+	}
+	sum := sha256.Sum256(ciphertext.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// WithDiseaseIndex sets the disease the event is about
+func (e *Event) WithDiseaseIndex(diseaseIndex int) *Event {
+	e.DiseaseIndex = &diseaseIndex
+	return e
+}
+
+// AddCiphertext adds a reference to a patient's ciphertext of the disease
+func (e *Event) AddCiphertext(patientNationalID string, diseaseIndex int, ciphertext *big.Int) *Event {
+	e.Ciphertexts = append(e.Ciphertexts, CiphertextRef{
+		PatientNationalID: patientNationalID,
+		DiseaseIndex:      diseaseIndex,
+		Digest:            Digest(ciphertext),
+	})
+	return e
+}
+
+// Marshal validates the event and returns its payload
+func (e *Event) Marshal() ([]byte, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// Decode parses the payload of the chaincode event `name`
+func Decode(name string, payload []byte) (*Event, error) {
+	if !isKnownType(name) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownEvent, name)
+	}
+	event := new(Event)
+	err := json.Unmarshal(payload, event)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s event: %v", name, err)
+	}
+	if event.SchemaVersion < 1 || event.SchemaVersion > SchemaVersion {
+		return nil, fmt.Errorf("unsupported %s event schema version %d", name, event.SchemaVersion)
+	}
+	if event.Type != name {
+		return nil, fmt.Errorf("event %s carries a %s payload", name, event.Type)
+	}
+	if err := event.validate(); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// validate checks that the event has the fields its type requires
+func (e *Event) validate() error {
+	if !isKnownType(e.Type) {
+		return fmt.Errorf("%w %q", ErrUnknownEvent, e.Type)
+	}
+	if e.TxID == "" {
+		return fmt.Errorf("%s event has no transaction ID", e.Type)
+	}
+	switch e.Type {
+	case PatientCreated, PatientDeleted, RiskComputed:
+		if len(e.PatientNationalIDs) == 0 {
+			return fmt.Errorf("%s event names no patient", e.Type)
+		}
+	case DiseaseFlagChanged, GenotypeChanged:
+		if len(e.PatientNationalIDs) == 0 || e.DiseaseIndex == nil {
+			return fmt.Errorf("%s event needs a patient and a disease index", e.Type)
+		}
+	case FamilyKeyRotated:
+		if e.PatientFamilyID == "" || e.KeyFingerprint == "" {
+			return fmt.Errorf("%s event needs a family and the new key fingerprint", e.Type)
+		}
+	}
+	return nil
+}
+
+func isKnownType(eventType string) bool {
+	switch eventType {
+	case PatientCreated, DiseaseFlagChanged, PatientDeleted, FamilyKeyRotated, RiskComputed, GenotypeChanged:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testEvents holds a valid event of every type
+func testEvents() []*Event {
+	newEvent := func(eventType string) *Event {
+		event := New(eventType)
+		event.TxID = "tx1"
+		event.Timestamp = "2026-01-01T00:00:00Z"
+		return event
+	}
+	created := newEvent(PatientCreated)
+	created.PatientNationalIDs = []string{"115"}
+	created.AddCiphertext("115", 0, big.NewInt(12345))
+	changed := newEvent(DiseaseFlagChanged).WithDiseaseIndex(1)
+	changed.PatientNationalIDs = []string{"115"}
+	deleted := newEvent(PatientDeleted)
+	deleted.PatientNationalIDs = []string{"115"}
+	rotated := newEvent(FamilyKeyRotated)
+	rotated.PatientFamilyID = "22"
+	rotated.KeyFingerprint = "ab12"
+	rotated.ReceiptID = "receipt1"
+	computed := newEvent(RiskComputed).WithDiseaseIndex(0)
+	computed.PatientNationalIDs = []string{"130"}
+	genotype := newEvent(GenotypeChanged).WithDiseaseIndex(2)
+	genotype.PatientNationalIDs = []string{"115"}
+	return []*Event{created, changed, deleted, rotated, computed, genotype}
+}
+
+// Every event type round-trips through Marshal and Decode
+func TestDecode(t *testing.T) {
+	for _, event := range testEvents() {
+		t.Run(event.Type, func(t *testing.T) {
+			payload, err := event.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := Decode(event.Type, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, event) {
+				t.Errorf("decoded %+v, want %+v", decoded, event)
+			}
+		})
+	}
+}
+
+// Schema versions before the first and after SchemaVersion are refused
+func TestDecodeSchemaVersion(t *testing.T) {
+	for _, version := range []int{0, SchemaVersion + 1} {
+		for _, event := range testEvents() {
+			event.SchemaVersion = version
+			payload, err := json.Marshal(event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Decode(event.Type, payload); err == nil || !strings.Contains(err.Error(), "schema version") {
+				t.Errorf("%s version %d: got %v, want the version refused", event.Type, version, err)
+			}
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid, err := testEvents()[0].Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		want    string
+	}{
+		{name: "unknown event", event: "ErasureReceipt", payload: string(valid), want: "unknown event"},
+		{name: "malformed payload", event: PatientCreated, payload: "{", want: "invalid PatientCreated event"},
+		{name: "other type", event: PatientDeleted, payload: string(valid), want: "carries a PatientCreated payload"},
+		{name: "no transaction", event: PatientDeleted, payload: `{"schemaVersion":1,"type":"PatientDeleted","patientNationalIDs":["115"]}`, want: "no transaction ID"},
+		{name: "no patient", event: PatientDeleted, payload: `{"schemaVersion":1,"type":"PatientDeleted","txID":"tx1"}`, want: "names no patient"},
+		{name: "no disease index", event: DiseaseFlagChanged, payload: `{"schemaVersion":1,"type":"DiseaseFlagChanged","txID":"tx1","patientNationalIDs":["115"]}`, want: "disease index"},
+		{name: "no fingerprint", event: FamilyKeyRotated, payload: `{"schemaVersion":1,"type":"FamilyKeyRotated","txID":"tx1","patientFamilyID":"22"}`, want: "fingerprint"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(test.event, []byte(test.payload))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("got %v, want an error with %q", err, test.want)
+			}
+		})
+	}
+
+	if _, err := Decode("ErasureReceipt", valid); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("unknown event: got %v, want ErrUnknownEvent", err)
+	}
+	if _, err := New("ErasureReceipt").Marshal(); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Marshal of an unknown type: got %v, want ErrUnknownEvent", err)
+	}
+}
+
+// A digest names a ciphertext without revealing it
+func TestDigest(t *testing.T) {
+	first, second := Digest(big.NewInt(12345)), Digest(big.NewInt(12346))
+	if len(first) != 64 || first == second || first != Digest(big.NewInt(12345)) {
+		t.Errorf("digests %q and %q", first, second)
+	}
+	if Digest(nil) != "" {
+		t.Error("a nil ciphertext has a digest")
+	}
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Handler is called with every decoded event of the type it is registered for
+type Handler func(event *Event) error
+
+// Subscriber decodes chaincode events as they are delivered by a Fabric client and
+// passes them to the handlers registered for their type. It does not connect to the
+// network itself; feed it the event name and payload of each chaincode event.
+type Subscriber struct {
+	handlers map[string][]Handler
+	// IgnoreUnknown drops events that are not part of the schema instead of failing
+	IgnoreUnknown bool
+}
+
+// NewSubscriber returns a subscriber with no handlers that ignores unknown events
+func NewSubscriber() *Subscriber {
+	return &Subscriber{handlers: map[string][]Handler{}, IgnoreUnknown: true}
+}
+
+// On registers a handler for an event type
+func (s *Subscriber) On(eventType string, handler Handler) error {
+	if !isKnownType(eventType) {
+		return fmt.Errorf("%w %q", ErrUnknownEvent, eventType)
+	}
+	s.handlers[eventType] = append(s.handlers[eventType], handler)
+	return nil
+}
+
+// Deliver decodes one chaincode event and runs its handlers in registration order,
+// stopping at the first handler error
+func (s *Subscriber) Deliver(name string, payload []byte) error {
+	event, err := Decode(name, payload)
+	if errors.Is(err, ErrUnknownEvent) && s.IgnoreUnknown {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, handler := range s.handlers[event.Type] {
+		if err := handler(event); err != nil {
+			return fmt.Errorf("%s handler: %v", event.Type, err)
+		}
+	}
+	return nil
+}
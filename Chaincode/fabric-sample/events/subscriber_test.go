@@ -0,0 +1,85 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Each event reaches the handlers of its type, in registration order
+func TestSubscriberDispatch(t *testing.T) {
+	subscriber := NewSubscriber()
+	var calls []string
+	for _, eventType := range []string{PatientCreated, FamilyKeyRotated} {
+		eventType := eventType
+		for _, handler := range []string{"first", "second"} {
+			handler := handler
+			err := subscriber.On(eventType, func(event *Event) error {
+				if event.Type != eventType {
+					t.Errorf("%s handler got a %s event", eventType, event.Type)
+				}
+				calls = append(calls, eventType+" "+handler)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, event := range testEvents() {
+		payload, err := event.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := subscriber.Deliver(event.Type, payload); err != nil {
+			t.Errorf("%s: %v", event.Type, err)
+		}
+	}
+	want := []string{PatientCreated + " first", PatientCreated + " second", FamilyKeyRotated + " first", FamilyKeyRotated + " second"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("handlers ran as %v, want %v", calls, want)
+	}
+}
+
+// Events outside the schema are dropped unless IgnoreUnknown is off
+func TestSubscriberUnknownEvents(t *testing.T) {
+	subscriber := NewSubscriber()
+	if err := subscriber.On("ErasureReceipt", func(*Event) error { return nil }); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("On an unknown type: got %v, want ErrUnknownEvent", err)
+	}
+	if err := subscriber.Deliver("ErasureReceipt", []byte("{}")); err != nil {
+		t.Errorf("unknown event with IgnoreUnknown: %v", err)
+	}
+	subscriber.IgnoreUnknown = false
+	if err := subscriber.Deliver("ErasureReceipt", []byte("{}")); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("unknown event without IgnoreUnknown: got %v, want ErrUnknownEvent", err)
+	}
+
+	// Known events of an unsupported version are an error either way
+	subscriber.IgnoreUnknown = true
+	if err := subscriber.Deliver(PatientDeleted, []byte(`{"schemaVersion":2,"type":"PatientDeleted","txID":"tx1","patientNationalIDs":["115"]}`)); err == nil {
+		t.Error("delivered an event of schema version 2")
+	}
+}
+
+// The first failing handler stops the delivery
+func TestSubscriberHandlerError(t *testing.T) {
+	subscriber := NewSubscriber()
+	ran := false
+	subscriber.On(PatientDeleted, func(*Event) error { return errors.New("store unavailable") })
+	subscriber.On(PatientDeleted, func(*Event) error { ran = true; return nil })
+
+	payload, err := testEvents()[2].Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = subscriber.Deliver(PatientDeleted, payload)
+	if err == nil || !strings.Contains(err.Error(), "PatientDeleted handler: store unavailable") {
+		t.Errorf("got %v, want the handler error", err)
+	}
+	if ran {
+		t.Error("the handler after the failing one ran")
+	}
+}
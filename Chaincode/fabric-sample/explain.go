@@ -0,0 +1,68 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// ExplainRisk computes the same risk as TransferAsset and returns it broken down by
+// relative: their relationship and generation, the weight applied, whether they were
+// left out and why, and their encrypted contribution. Nothing is decrypted, so the
+// breakdown can only be read by the holder of the patient's key.
+func (s *SmartContract) ExplainRisk(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndex int) (_ *RiskExplanation, err error) {
+	defer catalogError(&err)
+	patient, err := readLivePatient(ctx, patientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return nil, err
+	}
+	disease, err := diseaseService(ctx).Get(diseaseIndex)
+	if err != nil {
+		return nil, err
+	}
+	generations, err := ancestorGenerations(ctx, patient.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	consent, err := consentService(ctx).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return nil, err
+	}
+
+	result, explanation, err := riskService(ctx).Explain(privateKey.Pk, patientNationalID, generations, disease.Weight, diseaseIndex, consent)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &RiskExplanation{
+		PatientNationalID: patient.PatientNationalID,
+		DiseaseIndex:      diseaseIndex,
+		Disease:           disease.Name,
+		Weight:            disease.Weight,
+		KeyFingerprint:    privateKey.Pk.Fingerprint(),
+		EncryptedRisk:     result.Text(16),
+		InitialCiphertext: explanation.Initial.Text(16),
+		Terms:             make([]RiskTerm, len(explanation.Terms)),
+	}
+	for index, term := range explanation.Terms {
+		nationalID, err := strconv.Atoi(term.NationalID)
+		if err != nil {
+			return nil, err
+		}
+		view.Terms[index] = RiskTerm{
+			PatientNationalID: nationalID,
+			Relationship:      term.Relationship,
+			Level:             term.Level,
+			Weight:            term.Weight,
+			Penetrance:        term.Penetrance,
+			Status:            string(term.Status),
+			Contribution:      ciphertextString(term.Contribution),
+		}
+	}
+	return view, nil
+}
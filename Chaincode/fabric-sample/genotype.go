@@ -0,0 +1,123 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
+)
+
+// SetGenotype records the patient's genotype for the disease, encrypted under their key:
+// 0 for a non-carrier, 1 for a carrier and 2 for an affected patient. The disease flag
+// is kept in step with it, set for an affected patient and cleared otherwise.
+func (s *SmartContract) SetGenotype(ctx contractapi.TransactionContextInterface, patientNationalID int, diseaseIndex int, genotype int, reason string) (err error) {
+	defer catalogError(&err)
+	err = core.ValidateDiseaseIndex(diseaseIndex)
+	if err != nil {
+		return err
+	}
+	err = core.ValidateGenotype(genotype)
+	if err != nil {
+		return err
+	}
+	patient, err := readLivePatient(ctx, strconv.Itoa(patientNationalID))
+	if err != nil {
+		return err
+	}
+
+	privateKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return err
+	}
+
+	nationalID := strconv.Itoa(patientNationalID)
+	patient.PatientGenotypes[diseaseIndex], err = encrypt(ctx, privateKey.Pk, int64(genotype), core.GenotypeSlot(nationalID, diseaseIndex))
+	if err != nil {
+		return err
+	}
+	affected := int64(0)
+	if genotype == core.GenotypeAffected {
+		affected = 1
+	}
+	patient.PatientDiseaseTable[diseaseIndex], err = encrypt(ctx, privateKey.Pk, affected, core.DiseaseSlot(nationalID, diseaseIndex))
+	if err != nil {
+		return err
+	}
+	patient.KeyFingerprint = privateKey.Pk.Fingerprint()
+
+	err = putPatient(ctx, patient)
+	if err != nil {
+		return err
+	}
+	err = auditService(ctx).Record(strconv.Itoa(patientNationalID), core.AuditGenotypeChange, diseaseIndex, reason)
+	if err != nil {
+		return err
+	}
+
+	event := events.New(events.GenotypeChanged).WithDiseaseIndex(diseaseIndex)
+	event.PatientNationalIDs = []string{strconv.Itoa(patientNationalID)}
+	event.PatientFamilyID = strconv.Itoa(patient.PatientFamilyID)
+	event.KeyFingerprint = patient.KeyFingerprint
+	event.AddCiphertext(strconv.Itoa(patientNationalID), diseaseIndex, patient.PatientGenotypes[diseaseIndex])
+	return core.EmitEvent(newTransaction(ctx), event)
+}
+
+// ComputeRecessiveRisk computes, for each of the patient's parents, the encrypted chance
+// in percent that they pass the allele of a recessive disease on: 50% per copy they
+// carry. Nothing is decrypted; the patient is affected with the product of the chances
+// over 100. The parents are the recorded ones, or the first generation of the fixed
+// pedigree. It fails unless both parents have consented and have a recorded genotype.
+func (s *SmartContract) ComputeRecessiveRisk(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndex int) (_ *RecessiveRiskResult, err error) {
+	defer catalogError(&err)
+	patient, err := readLivePatient(ctx, patientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return nil, err
+	}
+	disease, err := diseaseService(ctx).Get(diseaseIndex)
+	if err != nil {
+		return nil, err
+	}
+	generations, err := ancestorGenerations(ctx, patient.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	parentIDs := []string{}
+	if len(generations) > 0 {
+		parentIDs = generations[0]
+	}
+	consent, err := consentService(ctx).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return nil, err
+	}
+
+	transmissions, err := riskService(ctx).RecessiveRisk(privateKey.Pk, patientNationalID, parentIDs, diseaseIndex, consent)
+	if err != nil {
+		return nil, err
+	}
+	err = core.EmitRiskComputed(newTransaction(ctx), strconv.Itoa(patient.PatientNationalID), diseaseIndex, privateKey.Pk.Fingerprint(), transmissions...)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make([]int, len(parentIDs))
+	for index, nationalID := range parentIDs {
+		parents[index], _ = strconv.Atoi(nationalID)
+	}
+	encrypted := make([]string, len(transmissions))
+	for index, transmission := range transmissions {
+		encrypted[index] = transmission.Text(16)
+	}
+	return &RecessiveRiskResult{
+		PatientNationalID:      patient.PatientNationalID,
+		DiseaseIndex:           diseaseIndex,
+		Disease:                disease.Name,
+		Parents:                parents,
+		EncryptedTransmissions: encrypted,
+		KeyFingerprint:         privateKey.Pk.Fingerprint(),
+	}, nil
+}
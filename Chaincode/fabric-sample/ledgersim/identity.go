@@ -0,0 +1,85 @@
+package ledgersim
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// attributesOID is the certificate extension Fabric CA stores enrollment attributes in,
+// and the one the client identity library reads them from
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+// Identity is a client of the simulated channel. Its certificate is self-signed and
+// carries the attributes the way Fabric CA issues them, so chaincodes read the MSP ID,
+// the ID and the attributes through the client identity library as on a peer.
+type Identity struct {
+	MSPID      string
+	Name       string
+	Attributes map[string]string
+
+	creator []byte
+}
+
+// NewIdentity returns an identity of the organization with the given enrollment attributes
+func NewIdentity(mspID string, name string, attributes map[string]string) *Identity {
+	return &Identity{MSPID: mspID, Name: name, Attributes: attributes}
+}
+
+// Creator returns the serialized identity a transaction of this client carries
+func (id *Identity) Creator() ([]byte, error) {
+	if id.creator != nil {
+		return id.creator, nil
+	}
+	certificate, err := id.certificate()
+	if err != nil {
+		return nil, err
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: id.MSPID, IdBytes: certificate})
+	if err != nil {
+		return nil, err
+	}
+	id.creator = creator
+	return creator, nil
+}
+
+// certificate issues the identity's PEM encoded certificate
+func (id *Identity) certificate() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: id.Name, Organization: []string{id.MSPID}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if len(id.Attributes) > 0 {
+		attributes, err := json.Marshal(map[string]map[string]string{"attrs": id.Attributes})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: attributesOID, Value: attributes}}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
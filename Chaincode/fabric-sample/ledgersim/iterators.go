@@ -0,0 +1,67 @@
+package ledgersim
+
+import (
+	"errors"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+// stateIterator iterates over a snapshot of the keys taken when the query was made
+type stateIterator struct {
+	results []*queryresult.KV
+	closed  bool
+}
+
+func newStateIterator(values map[string][]byte, startKey, endKey string) *stateIterator {
+	iterator := &stateIterator{}
+	for _, key := range keysInRange(values, startKey, endKey) {
+		iterator.add(key, values[key])
+	}
+	return iterator
+}
+
+func (it *stateIterator) add(key string, value []byte) {
+	it.results = append(it.results, &queryresult.KV{Key: key, Value: value})
+}
+
+func (it *stateIterator) HasNext() bool {
+	return !it.closed && len(it.results) > 0
+}
+
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	result := it.results[0]
+	it.results = it.results[1:]
+	return result, nil
+}
+
+func (it *stateIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// historyIterator iterates over the modifications of a key, newest first
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+	closed        bool
+}
+
+func (it *historyIterator) HasNext() bool {
+	return !it.closed && len(it.modifications) > 0
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	modification := it.modifications[0]
+	it.modifications = it.modifications[1:]
+	return modification, nil
+}
+
+func (it *historyIterator) Close() error {
+	it.closed = true
+	return nil
+}
@@ -0,0 +1,284 @@
+// Package ledgersim runs chaincodes in process against an in-memory ledger, for
+// development and tests without peers or Docker. It simulates a single LevelDB peer:
+// world state with composite keys, range queries and pagination, key history, private
+// data collections, the transient map, chaincode events and client identities with
+// attributes. Rich queries are rejected the way LevelDB rejects them, unless the ledger
+// is set to simulate CouchDB.
+//
+// As on a peer, a transaction reads the committed state and not its own writes, and
+// its writes and event are only committed when it succeeds.
+package ledgersim
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultChannel is the channel ID reported to chaincodes
+const DefaultChannel = "genchain"
+
+// Event is a chaincode event of a committed transaction
+type Event struct {
+	TxID    string `json:"txID"`
+	Name    string `json:"name"`
+	Payload []byte `json:"payload"`
+}
+
+// Transaction is a proposal to a chaincode. Function and Args become the chaincode
+// arguments, Identity the creator and Transient the transient map. A client sets the
+// transaction ID and timestamp of its proposal; left empty, the ledger numbers the
+// transaction and reads its Clock.
+type Transaction struct {
+	Function  string
+	Args      []string
+	Identity  *Identity
+	Transient map[string][]byte
+	TxID      string
+	Timestamp time.Time
+}
+
+// Result is the outcome of a transaction. Committed is false when the chaincode
+// returned an error status, in which case nothing was written.
+type Result struct {
+	TxID      string
+	Response  pb.Response
+	Event     *Event
+	Committed bool
+}
+
+// Endorsement is what an endorsing peer returns for a proposal: the response, and the
+// writes and event the transaction would commit. Every peer a client sends a proposal to
+// must return the same endorsement, or the transaction is invalid.
+type Endorsement struct {
+	TxID          string
+	Response      pb.Response
+	Writes        map[string][]byte
+	PrivateWrites map[string]map[string][]byte
+	Event         *Event
+}
+
+// Ledger is the state of one channel with one chaincode installed
+type Ledger struct {
+	ChannelID string
+	// Clock returns the timestamp of each new transaction
+	Clock func() time.Time
+	// CouchDB makes the ledger serve rich queries as a CouchDB peer does, for the
+	// selectors parseQuery accepts
+	CouchDB bool
+
+	mu      sync.Mutex
+	state   map[string][]byte
+	history map[string][]*queryresult.KeyModification
+	private map[string]map[string][]byte
+	events  []Event
+	txCount int
+}
+
+// New returns an empty ledger on DefaultChannel with a wall clock
+func New() *Ledger {
+	return &Ledger{
+		ChannelID: DefaultChannel,
+		Clock:     time.Now,
+		state:     map[string][]byte{},
+		history:   map[string][]*queryresult.KeyModification{},
+		private:   map[string]map[string][]byte{},
+	}
+}
+
+// FixedClock returns a clock that starts at the given time and moves one second ahead on
+// every transaction, so that runs are reproducible
+func FixedClock(start time.Time) func() time.Time {
+	next := start
+	return func() time.Time {
+		now := next
+		next = next.Add(time.Second)
+		return now
+	}
+}
+
+// Init calls the chaincode's Init with the transaction
+func (l *Ledger) Init(cc shim.Chaincode, tx Transaction) Result {
+	return l.execute(tx, cc.Init)
+}
+
+// Invoke calls the chaincode's Invoke with the transaction
+func (l *Ledger) Invoke(cc shim.Chaincode, tx Transaction) Result {
+	return l.execute(tx, cc.Invoke)
+}
+
+// Endorse runs the transaction against the committed state as a peer endorsing it would,
+// without committing it. A transaction without an ID gets the ID the next committed one
+// will have.
+func (l *Ledger) Endorse(cc shim.Chaincode, tx Transaction) Endorsement {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stub, err := newStub(l, l.txID(tx, l.txCount+1), tx)
+	if err != nil {
+		return Endorsement{Response: shim.Error(err.Error())}
+	}
+	endorsement := Endorsement{TxID: stub.txID, Response: cc.Invoke(stub)}
+	if endorsement.Response.Status >= shim.ERRORTHRESHOLD {
+		return endorsement
+	}
+	endorsement.Writes = stub.writes
+	endorsement.PrivateWrites = stub.privateWrites
+	endorsement.Event = stub.event
+	return endorsement
+}
+
+func (l *Ledger) execute(tx Transaction, call func(shim.ChaincodeStubInterface) pb.Response) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.txCount++
+	stub, err := newStub(l, l.txID(tx, l.txCount), tx)
+	if err != nil {
+		return Result{Response: shim.Error(err.Error())}
+	}
+	result := Result{TxID: stub.txID, Response: call(stub)}
+	if result.Response.Status >= shim.ERRORTHRESHOLD {
+		return result
+	}
+	l.commit(stub)
+	result.Committed = true
+	result.Event = stub.event
+	return result
+}
+
+// txID returns the ID of the transaction, numbered when the client set none
+func (l *Ledger) txID(tx Transaction, number int) string {
+	if tx.TxID != "" {
+		return tx.TxID
+	}
+	return fmt.Sprintf("tx%06d", number)
+}
+
+// commit applies the transaction's writes and records them in the key history
+func (l *Ledger) commit(stub *Stub) {
+	for _, key := range sortedKeys(stub.writes) {
+		value := stub.writes[key]
+		if value == nil {
+			delete(l.state, key)
+		} else {
+			l.state[key] = value
+		}
+		l.history[key] = append(l.history[key], &queryresult.KeyModification{
+			TxId:      stub.txID,
+			Value:     value,
+			Timestamp: timestamppb.New(stub.timestamp),
+			IsDelete:  value == nil,
+		})
+	}
+	for collection, writes := range stub.privateWrites {
+		if l.private[collection] == nil {
+			l.private[collection] = map[string][]byte{}
+		}
+		for key, value := range writes {
+			if value == nil {
+				delete(l.private[collection], key)
+			} else {
+				l.private[collection][key] = value
+			}
+		}
+	}
+	if stub.event != nil {
+		l.events = append(l.events, *stub.event)
+	}
+}
+
+// State returns the committed world state value of a key, or nil
+func (l *Ledger) State(key string) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state[key]
+}
+
+// PrivateData returns the committed value of a key in a collection, or nil
+func (l *Ledger) PrivateData(collection string, key string) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.private[collection][key]
+}
+
+// Events returns the events of every committed transaction, oldest first
+func (l *Ledger) Events() []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Event(nil), l.events...)
+}
+
+// snapshot is the saved form of a ledger
+type snapshot struct {
+	ChannelID string                                    `json:"channelID"`
+	TxCount   int                                       `json:"txCount"`
+	State     map[string][]byte                         `json:"state"`
+	History   map[string][]*queryresult.KeyModification `json:"history"`
+	Private   map[string]map[string][]byte              `json:"private"`
+	Events    []Event                                   `json:"events"`
+}
+
+// Save writes the ledger as JSON, so that a later run can continue from it with Load
+func (l *Ledger) Save(w io.Writer) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshot{
+		ChannelID: l.ChannelID,
+		TxCount:   l.txCount,
+		State:     l.state,
+		History:   l.history,
+		Private:   l.private,
+		Events:    l.events,
+	})
+}
+
+// Load replaces the ledger with one written by Save
+func (l *Ledger) Load(r io.Reader) error {
+	var saved snapshot
+	err := json.NewDecoder(r).Decode(&saved)
+	if err != nil {
+		return fmt.Errorf("invalid ledger snapshot: %v", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ChannelID = saved.ChannelID
+	l.txCount = saved.TxCount
+	l.state = nonNil(saved.State)
+	l.history = saved.History
+	if l.history == nil {
+		l.history = map[string][]*queryresult.KeyModification{}
+	}
+	l.private = saved.Private
+	if l.private == nil {
+		l.private = map[string]map[string][]byte{}
+	}
+	l.events = saved.Events
+	return nil
+}
+
+func nonNil(values map[string][]byte) map[string][]byte {
+	if values == nil {
+		return map[string][]byte{}
+	}
+	return values
+}
+
+func sortedKeys(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
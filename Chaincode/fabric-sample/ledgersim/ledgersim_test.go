@@ -0,0 +1,427 @@
+package ledgersim
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// chaincodeFunc is a chaincode whose Init and Invoke both call the function
+type chaincodeFunc func(stub shim.ChaincodeStubInterface) pb.Response
+
+func (f chaincodeFunc) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return f(stub)
+}
+
+func (f chaincodeFunc) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return f(stub)
+}
+
+func newTestLedger() *Ledger {
+	ledger := New()
+	ledger.Clock = FixedClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	return ledger
+}
+
+// run invokes the function as a transaction and fails the test if it does not commit
+func run(t *testing.T, ledger *Ledger, f func(stub shim.ChaincodeStubInterface) error) {
+	t.Helper()
+	result := ledger.Invoke(chaincodeFunc(func(stub shim.ChaincodeStubInterface) pb.Response {
+		if err := f(stub); err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	}), Transaction{Function: "test"})
+	if !result.Committed {
+		t.Fatalf("transaction %s failed: %s", result.TxID, result.Response.Message)
+	}
+}
+
+// putAll commits the values in one transaction
+func putAll(t *testing.T, ledger *Ledger, values map[string]string) {
+	t.Helper()
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		for key, value := range values {
+			if err := stub.PutState(key, []byte(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// keysOf drains the iterator and returns the keys it returned
+func keysOf(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	t.Helper()
+	defer iterator.Close()
+	keys := []string{}
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, result.Key)
+	}
+	return keys
+}
+
+// compositeKey returns the composite key of the attributes under the "patient" type
+func compositeKey(t *testing.T, stub shim.ChaincodeStubInterface, attributes ...string) string {
+	t.Helper()
+	key, err := stub.CreateCompositeKey("patient", attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestCompositeKeys(t *testing.T) {
+	ledger := newTestLedger()
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		for _, attributes := range [][]string{{"22", "115"}, {"22", "116"}, {"21", "117"}, {"220", "118"}} {
+			if err := stub.PutState(compositeKey(t, stub, attributes...), []byte("{}")); err != nil {
+				return err
+			}
+		}
+		return stub.PutState("patient", []byte("simple"))
+	})
+
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		objectType, attributes, err := stub.SplitCompositeKey(compositeKey(t, stub, "22", "115"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if objectType != "patient" || !reflect.DeepEqual(attributes, []string{"22", "115"}) {
+			t.Errorf("SplitCompositeKey = %q, %v", objectType, attributes)
+		}
+		if _, _, err := stub.SplitCompositeKey("patient"); err == nil {
+			t.Error("SplitCompositeKey split a simple key")
+		}
+		for _, attribute := range []string{"a\x00b", "a" + string(maxUnicodeRune), "\xff"} {
+			if _, err := stub.CreateCompositeKey("patient", []string{attribute}); err == nil {
+				t.Errorf("CreateCompositeKey accepted the attribute %q", attribute)
+			}
+		}
+
+		tests := []struct {
+			attributes []string
+			want       []string
+		}{
+			{attributes: []string{"22"}, want: []string{compositeKey(t, stub, "22", "115"), compositeKey(t, stub, "22", "116")}},
+			{attributes: []string{"22", "116"}, want: []string{compositeKey(t, stub, "22", "116")}},
+			{attributes: []string{"23"}, want: []string{}},
+			{attributes: []string{}, want: []string{
+				compositeKey(t, stub, "21", "117"),
+				compositeKey(t, stub, "22", "115"),
+				compositeKey(t, stub, "22", "116"),
+				compositeKey(t, stub, "220", "118"),
+			}},
+		}
+		for _, test := range tests {
+			iterator, err := stub.GetStateByPartialCompositeKey("patient", test.attributes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := keysOf(t, iterator); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetStateByPartialCompositeKey(%v) = %q, want %q", test.attributes, got, test.want)
+			}
+		}
+		return nil
+	})
+}
+
+func TestRangeQueries(t *testing.T) {
+	ledger := newTestLedger()
+	putAll(t, ledger, map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"})
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		return stub.PutState(compositeKey(t, stub, "22"), []byte("{}"))
+	})
+
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		tests := []struct {
+			startKey, endKey string
+			want             []string
+		}{
+			// Composite keys never appear in a simple range, even an unbounded one
+			{startKey: "", endKey: "", want: []string{"a", "b", "c", "d"}},
+			{startKey: "b", endKey: "d", want: []string{"b", "c"}},
+			{startKey: "b", endKey: "", want: []string{"b", "c", "d"}},
+			{startKey: "x", endKey: "", want: []string{}},
+		}
+		for _, test := range tests {
+			iterator, err := stub.GetStateByRange(test.startKey, test.endKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := keysOf(t, iterator); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetStateByRange(%q, %q) = %q, want %q", test.startKey, test.endKey, got, test.want)
+			}
+		}
+
+		if _, err := stub.GetStateByRange(compositeKey(t, stub, "22"), ""); err == nil {
+			t.Error("GetStateByRange accepted a composite key")
+		}
+		if _, err := stub.GetQueryResult(`{"selector":{}}`); err != ErrRichQueryUnsupported {
+			t.Errorf("GetQueryResult = %v", err)
+		}
+		return nil
+	})
+}
+
+func TestRangeQueryPagination(t *testing.T) {
+	ledger := newTestLedger()
+	putAll(t, ledger, map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5"})
+
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		var pages [][]string
+		bookmark := ""
+		for {
+			iterator, metadata, err := stub.GetStateByRangeWithPagination("", "", 2, bookmark)
+			if err != nil {
+				t.Fatal(err)
+			}
+			page := keysOf(t, iterator)
+			if int(metadata.FetchedRecordsCount) != len(page) {
+				t.Errorf("FetchedRecordsCount = %d for %d keys", metadata.FetchedRecordsCount, len(page))
+			}
+			pages = append(pages, page)
+			bookmark = metadata.Bookmark
+			if bookmark == "" {
+				break
+			}
+		}
+		want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+		if !reflect.DeepEqual(pages, want) {
+			t.Errorf("pages = %q, want %q", pages, want)
+		}
+
+		if _, _, err := stub.GetStateByRangeWithPagination("a", "c", 2, "d"); err == nil {
+			t.Error("a bookmark outside the range was accepted")
+		}
+		if _, _, err := stub.GetStateByRangeWithPagination("", "", 0, ""); err == nil {
+			t.Error("a page size of zero was accepted")
+		}
+		return nil
+	})
+}
+
+// A ledger simulating CouchDB serves the selectors of rich queries
+func TestRichQueries(t *testing.T) {
+	ledger := newTestLedger()
+	ledger.CouchDB = true
+	putAll(t, ledger, map[string]string{
+		"a":     `{"docType":"patient","family":22,"createdAt":"2026-01-01T00:00:01Z"}`,
+		"b":     `{"docType":"patient","family":22,"createdAt":"2026-01-01T00:00:05Z"}`,
+		"c":     `{"docType":"patient","family":21,"createdAt":"2026-01-01T00:00:09Z","ciphertext":123456789012345678901234567890123456789012345678901234567890}`,
+		"d":     `{"docType":"consent","family":22}`,
+		"plain": "not a document",
+	})
+
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{query: `{"selector":{}}`, want: []string{"a", "b", "c", "d"}},
+			{query: `{"selector":{"docType":"patient"}}`, want: []string{"a", "b", "c"}},
+			{query: `{"selector":{"docType":"patient","family":22}}`, want: []string{"a", "b"}},
+			{query: `{"selector":{"family":"22"}}`, want: []string{}},
+			{query: `{"selector":{"createdAt":{"$gt":"2026-01-01T00:00:01Z"}}}`, want: []string{"b", "c"}},
+			{query: `{"selector":{"family":{"$gte":21,"$lt":22}}}`, want: []string{"c"}},
+			{query: `{"selector":{"ciphertext":{"$gt":123456789012345678901234567890123456789012345678901234567889}}}`, want: []string{"c"}},
+			{query: `{"selector":{"docType":{"$ne":"patient"}},"use_index":"docTypeIndex"}`, want: []string{"d"}},
+		}
+		for _, test := range tests {
+			iterator, err := stub.GetQueryResult(test.query)
+			if err != nil {
+				t.Fatalf("GetQueryResult(%s): %v", test.query, err)
+			}
+			if got := keysOf(t, iterator); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetQueryResult(%s) = %q, want %q", test.query, got, test.want)
+			}
+		}
+
+		iterator, metadata, err := stub.GetQueryResultWithPagination(`{"selector":{"docType":"patient"}}`, 2, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keysOf(t, iterator); !reflect.DeepEqual(got, []string{"a", "b"}) || metadata.Bookmark != "c" {
+			t.Errorf("first page = %q with bookmark %q", got, metadata.Bookmark)
+		}
+
+		for _, query := range []string{`not a query`, `{"fields":["family"]}`, `{"selector":{"$or":[]}}`, `{"selector":{"family":{"$in":[22]}}}`, `{"selector":{"a.b":1}}`} {
+			if _, err := stub.GetQueryResult(query); err == nil {
+				t.Errorf("GetQueryResult(%s) was served", query)
+			}
+		}
+		return nil
+	})
+
+	ledger.CouchDB = false
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		if _, err := stub.GetQueryResult(`{"selector":{}}`); err != ErrRichQueryUnsupported {
+			t.Errorf("GetQueryResult on LevelDB = %v", err)
+		}
+		return nil
+	})
+}
+
+func TestPurgePrivateData(t *testing.T) {
+	ledger := newTestLedger()
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		if err := stub.PutPrivateData("keys", "22", []byte("family key")); err != nil {
+			return err
+		}
+		return stub.PutPrivateData("keys", "21", []byte("kept key"))
+	})
+	if got := string(ledger.PrivateData("keys", "22")); got != "family key" {
+		t.Fatalf("PrivateData before the purge = %q", got)
+	}
+
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		// The purge is only visible once the transaction commits
+		if err := stub.PurgePrivateData("keys", "22"); err != nil {
+			return err
+		}
+		value, err := stub.GetPrivateData("keys", "22")
+		if err != nil || value == nil {
+			t.Errorf("GetPrivateData before commit = %q, %v", value, err)
+		}
+		return nil
+	})
+
+	if got := ledger.PrivateData("keys", "22"); got != nil {
+		t.Errorf("PrivateData after the purge = %q", got)
+	}
+	if got := string(ledger.PrivateData("keys", "21")); got != "kept key" {
+		t.Errorf("the purge removed another key: %q", got)
+	}
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		hash, err := stub.GetPrivateDataHash("keys", "22")
+		if err != nil || hash != nil {
+			t.Errorf("GetPrivateDataHash after the purge = %x, %v", hash, err)
+		}
+		iterator, err := stub.GetPrivateDataByRange("keys", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := keysOf(t, iterator); !reflect.DeepEqual(got, []string{"21"}) {
+			t.Errorf("GetPrivateDataByRange after the purge = %q", got)
+		}
+		return nil
+	})
+
+	// Nothing of the purged value survives in a saved ledger
+	var saved strings.Builder
+	if err := ledger.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(saved.String(), "ZmFtaWx5IGtleQ") {
+		t.Error("the saved ledger still holds the purged value")
+	}
+}
+
+func TestFailedTransactionIsNotCommitted(t *testing.T) {
+	ledger := newTestLedger()
+	result := ledger.Invoke(chaincodeFunc(func(stub shim.ChaincodeStubInterface) pb.Response {
+		stub.PutState("a", []byte("1"))
+		stub.PutPrivateData("keys", "22", []byte("family key"))
+		stub.SetEvent("Written", nil)
+		return shim.Error("rejected")
+	}), Transaction{Function: "test"})
+
+	if result.Committed || result.Event != nil {
+		t.Errorf("failed transaction result = %+v", result)
+	}
+	if ledger.State("a") != nil || ledger.PrivateData("keys", "22") != nil || len(ledger.Events()) != 0 {
+		t.Error("a failed transaction left writes on the ledger")
+	}
+}
+
+// An endorsement carries the writes and takes the client's ID and timestamp, and nothing
+// is committed
+func TestEndorse(t *testing.T) {
+	ledger := newTestLedger()
+	timestamp := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	chaincode := chaincodeFunc(func(stub shim.ChaincodeStubInterface) pb.Response {
+		now, err := stub.GetTxTimestamp()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		stub.PutState("a", []byte(stub.GetTxID()+" "+now.AsTime().Format(time.RFC3339)))
+		stub.PutPrivateData("keys", "22", []byte("family key"))
+		stub.SetEvent("Written", nil)
+		return shim.Success(nil)
+	})
+
+	endorsement := ledger.Endorse(chaincode, Transaction{Function: "test", TxID: "client-tx", Timestamp: timestamp})
+	if endorsement.TxID != "client-tx" || endorsement.Event == nil {
+		t.Errorf("endorsement = %+v", endorsement)
+	}
+	if got := string(endorsement.Writes["a"]); got != "client-tx 2026-06-01T00:00:00Z" {
+		t.Errorf("write = %q", got)
+	}
+	if got := string(endorsement.PrivateWrites["keys"]["22"]); got != "family key" {
+		t.Errorf("private write = %q", got)
+	}
+	if ledger.State("a") != nil || ledger.PrivateData("keys", "22") != nil || len(ledger.Events()) != 0 {
+		t.Error("an endorsement was committed")
+	}
+
+	if next := ledger.Endorse(chaincode, Transaction{Function: "test"}); next.TxID != "tx000001" {
+		t.Errorf("endorsement without an ID got %s, want the next transaction's", next.TxID)
+	}
+	if result := ledger.Invoke(chaincode, Transaction{Function: "test"}); result.TxID != "tx000001" {
+		t.Errorf("the transaction after the endorsements got %s", result.TxID)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	ledger := newTestLedger()
+	putAll(t, ledger, map[string]string{"a": "1"})
+	putAll(t, ledger, map[string]string{"a": "2"})
+	run(t, ledger, func(stub shim.ChaincodeStubInterface) error {
+		return stub.PutPrivateData("keys", "22", []byte("family key"))
+	})
+
+	var saved strings.Builder
+	if err := ledger.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded := newTestLedger()
+	if err := loaded.Load(strings.NewReader(saved.String())); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(loaded.State("a")); got != "2" {
+		t.Errorf("State after Load = %q", got)
+	}
+	if got := string(loaded.PrivateData("keys", "22")); got != "family key" {
+		t.Errorf("PrivateData after Load = %q", got)
+	}
+	run(t, loaded, func(stub shim.ChaincodeStubInterface) error {
+		iterator, err := stub.GetHistoryForKey("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer iterator.Close()
+		versions := 0
+		for iterator.HasNext() {
+			if _, err := iterator.Next(); err != nil {
+				t.Fatal(err)
+			}
+			versions++
+		}
+		if versions != 2 {
+			t.Errorf("history after Load has %d versions, want 2", versions)
+		}
+		return nil
+	})
+
+	if err := loaded.Load(strings.NewReader("not json")); err == nil {
+		t.Error("Load accepted an invalid snapshot")
+	}
+}
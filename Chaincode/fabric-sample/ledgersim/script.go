@@ -0,0 +1,314 @@
+package ledgersim
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// Main runs a transaction script against a chaincode and returns the exit status, so a
+// chaincode's local CLI is a one line main. The script is read from the file named by
+// the first argument, or from stdin. Each line is one command:
+//
+//	identity NAME MSPID [ATTRIBUTE=VALUE...]   register a client identity
+//	as NAME                                    submit the following transactions as NAME
+//	transient KEY VALUE                        add to the transient map of the next transaction
+//	time RFC3339                               start a fixed clock at the given time
+//	env NAME VALUE                             set an environment variable of the peer,
+//	                                           such as its key wrap secret
+//	init [ARGS...]                             call Init
+//	FUNCTION [ARGS...]                         invoke FUNCTION
+//	expect STATUS [TEXT]                       check the status of the last transaction and
+//	                                           that TEXT is in its payload or error message
+//
+// Arguments are separated by spaces and may be quoted with double quotes, and a
+// backslash takes the next character literally. Blank lines and lines starting with #
+// are skipped. With -state FILE the ledger is loaded from FILE when it exists and saved
+// back to it afterwards. The exit status is 1 when a transaction failed that no expect
+// line accounted for, or an expectation was not met.
+func Main(cc shim.Chaincode, args []string) int {
+	flags := flag.NewFlagSet("genchain-sim", flag.ContinueOnError)
+	statePath := flags.String("state", "", "file the ledger is loaded from and saved to")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	ledger := New()
+	if *statePath != "" {
+		err = loadFile(ledger, *statePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	input := io.Reader(os.Stdin)
+	if flags.NArg() > 0 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	failed, err := NewRunner(ledger, cc, os.Stdout).Run(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *statePath != "" {
+		err = saveFile(ledger, *statePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Runner executes script commands against a ledger and reports each transaction
+type Runner struct {
+	ledger     *Ledger
+	chaincode  shim.Chaincode
+	out        io.Writer
+	identities map[string]*Identity
+	current    *Identity
+	transient  map[string][]byte
+	last       *Result
+}
+
+// NewRunner returns a runner that writes its report to out
+func NewRunner(ledger *Ledger, cc shim.Chaincode, out io.Writer) *Runner {
+	return &Runner{ledger: ledger, chaincode: cc, out: out, identities: map[string]*Identity{}}
+}
+
+// Run executes every command of the script and returns the number of failures: failed
+// transactions not followed by an expect line, and unmet expectations. It stops at the
+// first malformed command.
+func (r *Runner) Run(script io.Reader) (int, error) {
+	failed := 0
+	unexpected := false
+	scanner := bufio.NewScanner(script)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields, err := splitLine(text)
+		if err != nil {
+			return failed, fmt.Errorf("line %d: %v", line, err)
+		}
+		if fields[0] == "expect" {
+			met, err := r.expect(fields[1:])
+			if err != nil {
+				return failed, fmt.Errorf("line %d: %v", line, err)
+			}
+			if !met {
+				failed++
+			}
+			unexpected = false
+			continue
+		}
+		if unexpected {
+			failed++
+		}
+		committed, err := r.execute(fields)
+		if err != nil {
+			return failed, fmt.Errorf("line %d: %v", line, err)
+		}
+		unexpected = !committed
+	}
+	if unexpected {
+		failed++
+	}
+	return failed, scanner.Err()
+}
+
+// expect checks the last transaction against an expect line and reports whether it matched
+func (r *Runner) expect(fields []string) (bool, error) {
+	if len(fields) < 1 || len(fields) > 2 {
+		return false, fmt.Errorf("usage: expect STATUS [TEXT]")
+	}
+	if r.last == nil {
+		return false, fmt.Errorf("expect without a transaction")
+	}
+	status, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false, fmt.Errorf("invalid status %q", fields[0])
+	}
+	if r.last.Response.Status != int32(status) {
+		fmt.Fprintf(r.out, "  expected status %d, got %d\n", status, r.last.Response.Status)
+		return false, nil
+	}
+	if len(fields) == 2 {
+		output := string(r.last.Response.Payload) + r.last.Response.Message
+		if !strings.Contains(output, fields[1]) {
+			fmt.Fprintf(r.out, "  expected %q in the output\n", fields[1])
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// execute runs one command and reports whether the transaction it submitted, if any, was committed
+func (r *Runner) execute(fields []string) (bool, error) {
+	switch fields[0] {
+	case "identity":
+		if len(fields) < 3 {
+			return true, fmt.Errorf("usage: identity NAME MSPID [ATTRIBUTE=VALUE...]")
+		}
+		attributes := map[string]string{}
+		for _, field := range fields[3:] {
+			name, value, found := strings.Cut(field, "=")
+			if !found {
+				return true, fmt.Errorf("attribute %q is not NAME=VALUE", field)
+			}
+			attributes[name] = value
+		}
+		r.identities[fields[1]] = NewIdentity(fields[2], fields[1], attributes)
+		return true, nil
+	case "as":
+		if len(fields) != 2 {
+			return true, fmt.Errorf("usage: as NAME")
+		}
+		identity, ok := r.identities[fields[1]]
+		if !ok {
+			return true, fmt.Errorf("unknown identity %s", fields[1])
+		}
+		r.current = identity
+		return true, nil
+	case "transient":
+		if len(fields) != 3 {
+			return true, fmt.Errorf("usage: transient KEY VALUE")
+		}
+		if r.transient == nil {
+			r.transient = map[string][]byte{}
+		}
+		r.transient[fields[1]] = []byte(fields[2])
+		return true, nil
+	case "env":
+		if len(fields) != 3 {
+			return true, fmt.Errorf("usage: env NAME VALUE")
+		}
+		return true, os.Setenv(fields[1], fields[2])
+	case "time":
+		if len(fields) != 2 {
+			return true, fmt.Errorf("usage: time RFC3339")
+		}
+		start, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return true, err
+		}
+		r.ledger.Clock = FixedClock(start)
+		return true, nil
+	}
+
+	tx := Transaction{Identity: r.current, Transient: r.transient}
+	r.transient = nil
+	var result Result
+	if fields[0] == "init" {
+		if len(fields) > 1 {
+			tx.Function, tx.Args = fields[1], fields[2:]
+		}
+		result = r.ledger.Init(r.chaincode, tx)
+	} else {
+		tx.Function, tx.Args = fields[0], fields[1:]
+		result = r.ledger.Invoke(r.chaincode, tx)
+	}
+	r.report(fields[0], result)
+	r.last = &result
+	return result.Committed, nil
+}
+
+func (r *Runner) report(function string, result Result) {
+	if !result.Committed {
+		fmt.Fprintf(r.out, "%s %s: status %d: %s\n", result.TxID, function, result.Response.Status, result.Response.Message)
+		return
+	}
+	fmt.Fprintf(r.out, "%s %s: status %d\n", result.TxID, function, result.Response.Status)
+	if len(result.Response.Payload) > 0 {
+		fmt.Fprintf(r.out, "  %s\n", result.Response.Payload)
+	}
+	if result.Event != nil {
+		fmt.Fprintf(r.out, "  event %s\n", result.Event.Name)
+	}
+}
+
+// splitLine splits a command into fields on spaces, keeping double quoted text together.
+// A backslash takes the next character literally.
+func splitLine(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField, quoted, escaped := false, false, false
+	for _, c := range line {
+		switch {
+		case escaped:
+			field.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+			inField = true
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func loadFile(ledger *Ledger, path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return ledger.Load(file)
+}
+
+func saveFile(ledger *Ledger, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = ledger.Save(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,80 @@
+package ledgersim
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: `CreateAsset "Zeynep Kaya" 130`, want: []string{"CreateAsset", "Zeynep Kaya", "130"}},
+		{line: `expect 200 "\"risk\":0,"`, want: []string{"expect", "200", `"risk":0,`}},
+		{line: "GrantConsent 115\t\"\"", want: []string{"GrantConsent", "115", ""}},
+		{line: `a\ b c`, want: []string{"a b", "c"}},
+	}
+	for _, test := range tests {
+		got, err := splitLine(test.line)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitLine(%s) = %q (%v), want %q", test.line, got, err, test.want)
+		}
+	}
+	for _, line := range []string{`"unterminated`, `trailing\`} {
+		if _, err := splitLine(line); err == nil {
+			t.Errorf("splitLine(%s) was accepted", line)
+		}
+	}
+}
+
+// The runner counts failed transactions no expect line accounts for and unmet
+// expectations, and passes identities, transient data and the environment on
+func TestRunner(t *testing.T) {
+	echo := chaincodeFunc(func(stub shim.ChaincodeStubInterface) pb.Response {
+		function, args := stub.GetFunctionAndParameters()
+		if function == "fail" {
+			return shim.Error("failed: " + strings.Join(args, " "))
+		}
+		transient, err := stub.GetTransient()
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		identity := "anonymous"
+		if creator, err := stub.GetCreator(); err == nil && strings.Contains(string(creator), "Org2MSP") {
+			identity = "Org2MSP"
+		}
+		return shim.Success([]byte(identity + " " + string(transient["seed"]) + " " + os.Getenv("GENCHAIN_SCRIPT_TEST")))
+	})
+
+	tests := []struct {
+		script string
+		failed int
+	}{
+		{script: "identity alice Org2MSP role=lab\nas alice\ntransient seed s1\nenv GENCHAIN_SCRIPT_TEST on\necho\nexpect 200 \"Org2MSP s1 on\"", failed: 0},
+		{script: "fail a b\nexpect 500 \"failed: a b\"", failed: 0},
+		{script: "fail\necho", failed: 1},
+		{script: "fail", failed: 1},
+		{script: "echo\nexpect 500", failed: 1},
+		{script: "# only a comment\n\necho\nexpect 200 missing", failed: 1},
+	}
+	for _, test := range tests {
+		var report strings.Builder
+		failed, err := NewRunner(newTestLedger(), echo, &report).Run(strings.NewReader(test.script))
+		if err != nil || failed != test.failed {
+			t.Errorf("script %q: %d failures (%v), want %d:\n%s", test.script, failed, err, test.failed, report.String())
+		}
+	}
+	os.Unsetenv("GENCHAIN_SCRIPT_TEST")
+
+	for _, script := range []string{"expect 200", "as nobody", "identity alice", "time yesterday", "env NAME", "echo \"open"} {
+		if _, err := NewRunner(newTestLedger(), echo, &strings.Builder{}).Run(strings.NewReader(script)); err == nil {
+			t.Errorf("script %q was run", script)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package ledgersim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// selectorOperators are the CouchDB condition operators the simulator evaluates
+var selectorOperators = map[string]func(order int) bool{
+	"$eq":  func(order int) bool { return order == 0 },
+	"$ne":  func(order int) bool { return order != 0 },
+	"$gt":  func(order int) bool { return order > 0 },
+	"$gte": func(order int) bool { return order >= 0 },
+	"$lt":  func(order int) bool { return order < 0 },
+	"$lte": func(order int) bool { return order <= 0 },
+}
+
+// condition is one field of a selector: the value of a top-level field compared with
+// an operand
+type condition struct {
+	field    string
+	operator string
+	operand  interface{}
+}
+
+// parseQuery reads the selector of a CouchDB query. Fields are matched by equality or
+// with the operators of selectorOperators; nested fields, combination operators and
+// the other parts of a query are not simulated and are rejected.
+func parseQuery(query string) ([]condition, error) {
+	var parsed map[string]json.RawMessage
+	err := json.Unmarshal([]byte(query), &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	var selector map[string]interface{}
+	for part, value := range parsed {
+		switch part {
+		case "selector":
+			err = decodeJSON(value, &selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector: %v", err)
+			}
+		case "use_index":
+			// The simulator has no indexes to choose from
+		default:
+			return nil, fmt.Errorf("query part %q is not simulated", part)
+		}
+	}
+	if selector == nil {
+		return nil, fmt.Errorf("the query has no selector")
+	}
+
+	var conditions []condition
+	for field, value := range selector {
+		if strings.HasPrefix(field, "$") || strings.Contains(field, ".") {
+			return nil, fmt.Errorf("selector field %q is not simulated", field)
+		}
+		operators, ok := value.(map[string]interface{})
+		if !ok {
+			conditions = append(conditions, condition{field: field, operator: "$eq", operand: value})
+			continue
+		}
+		for operator, operand := range operators {
+			if selectorOperators[operator] == nil {
+				return nil, fmt.Errorf("selector operator %q is not simulated", operator)
+			}
+			conditions = append(conditions, condition{field: field, operator: operator, operand: operand})
+		}
+	}
+	return conditions, nil
+}
+
+// matches reports whether a JSON document satisfies every condition. Values that are
+// not JSON objects never match, as CouchDB does not index them.
+func matches(conditions []condition, value []byte) bool {
+	var document map[string]interface{}
+	if decodeJSON(value, &document) != nil {
+		return false
+	}
+	for _, c := range conditions {
+		field, ok := document[c.field]
+		if !ok {
+			return false
+		}
+		order, ok := compare(field, c.operand)
+		if !ok || !selectorOperators[c.operator](order) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeJSON decodes numbers as json.Number, since documents hold numbers too large
+// for a float64
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// compare orders two JSON scalars of the same type. Values of different types, and
+// arrays and objects, are not comparable.
+func compare(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		x, okA := new(big.Rat).SetString(a.String())
+		y, okB := new(big.Rat).SetString(b.String())
+		if !okA || !okB {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	case bool:
+		b, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if a == b {
+			return 0, true
+		}
+		if b {
+			return -1, true
+		}
+		return 1, true
+	case nil:
+		return 0, b == nil
+	}
+	return 0, false
+}
+
+// queryValues returns the values matching the query, by key
+func queryValues(values map[string][]byte, query string) (map[string][]byte, error) {
+	conditions, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	matched := map[string][]byte{}
+	for key, value := range values {
+		if matches(conditions, value) {
+			matched[key] = value
+		}
+	}
+	return matched, nil
+}
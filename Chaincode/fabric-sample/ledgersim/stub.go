@@ -0,0 +1,438 @@
+package ledgersim
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Composite keys are laid out as Fabric lays them out, so that keys sort and split the
+// same way as on a peer
+const (
+	compositeKeyNamespace = "\x00"
+	emptyKeySubstitute    = "\x01"
+	maxUnicodeRune        = utf8.MaxRune
+)
+
+// ErrRichQueryUnsupported is returned for CouchDB queries unless the ledger simulates
+// CouchDB, with the message a LevelDB peer returns
+var ErrRichQueryUnsupported = errors.New("ExecuteQuery not supported for leveldb")
+
+// Stub is the shim.ChaincodeStubInterface of one transaction
+type Stub struct {
+	ledger    *Ledger
+	txID      string
+	args      [][]byte
+	transient map[string][]byte
+	creator   []byte
+	timestamp time.Time
+
+	writes            map[string][]byte
+	privateWrites     map[string]map[string][]byte
+	validation        map[string][]byte
+	privateValidation map[string][]byte
+	event             *Event
+}
+
+var _ shim.ChaincodeStubInterface = (*Stub)(nil)
+
+func newStub(ledger *Ledger, txID string, tx Transaction) (*Stub, error) {
+	stub := &Stub{
+		ledger:            ledger,
+		txID:              txID,
+		transient:         tx.Transient,
+		timestamp:         tx.Timestamp.UTC(),
+		writes:            map[string][]byte{},
+		privateWrites:     map[string]map[string][]byte{},
+		validation:        map[string][]byte{},
+		privateValidation: map[string][]byte{},
+	}
+	if tx.Timestamp.IsZero() {
+		stub.timestamp = ledger.Clock().UTC()
+	}
+	stub.args = append(stub.args, []byte(tx.Function))
+	for _, arg := range tx.Args {
+		stub.args = append(stub.args, []byte(arg))
+	}
+	if tx.Identity != nil {
+		creator, err := tx.Identity.Creator()
+		if err != nil {
+			return nil, err
+		}
+		stub.creator = creator
+	}
+	return stub, nil
+}
+
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *Stub) GetStringArgs() []string {
+	args := make([]string, len(s.args))
+	for index, arg := range s.args {
+		args[index] = string(arg)
+	}
+	return args
+}
+
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	var slice []byte
+	for _, arg := range s.args {
+		slice = append(slice, arg...)
+	}
+	return slice, nil
+}
+
+func (s *Stub) GetTxID() string {
+	return s.txID
+}
+
+func (s *Stub) GetChannelID() string {
+	return s.ledger.ChannelID
+}
+
+// InvokeChaincode is not simulated, since the ledger holds a single chaincode
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) pb.Response {
+	return shim.Error(fmt.Sprintf("cannot invoke chaincode %s: chaincode to chaincode calls are not simulated", chaincodeName))
+}
+
+func (s *Stub) GetState(key string) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("key must not be empty")
+	}
+	return s.ledger.state[key], nil
+}
+
+// PutState records a write. An empty value deletes the key, as on a peer.
+func (s *Stub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be empty")
+	}
+	if len(value) == 0 {
+		s.writes[key] = nil
+		return nil
+	}
+	s.writes[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *Stub) DelState(key string) error {
+	if key == "" {
+		return errors.New("key must not be empty")
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	s.validation[key] = ep
+	return nil
+}
+
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.validation[key], nil
+}
+
+func (s *Stub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	startKey, err := simpleRangeStart(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(s.ledger.state, startKey, endKey), nil
+}
+
+func (s *Stub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	startKey, err := simpleRangeStart(startKey, endKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginate(s.ledger.state, startKey, endKey, pageSize, bookmark)
+}
+
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(s.ledger.state, startKey, endKey), nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	startKey, endKey, err := partialCompositeRange(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginate(s.ledger.state, startKey, endKey, pageSize, bookmark)
+}
+
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return createCompositeKey(objectType, attributes)
+}
+
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	components := strings.Split(compositeKey[1:], "\x00")
+	if len(components) < 2 {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	return components[0], components[1 : len(components)-1], nil
+}
+
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	matched, err := s.richQuery(s.ledger.state, query)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(matched, "", ""), nil
+}
+
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	matched, err := s.richQuery(s.ledger.state, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	return paginate(matched, "", "", pageSize, bookmark)
+}
+
+// richQuery returns the values matching a CouchDB query, or ErrRichQueryUnsupported
+// when the ledger simulates LevelDB
+func (s *Stub) richQuery(values map[string][]byte, query string) (map[string][]byte, error) {
+	if !s.ledger.CouchDB {
+		return nil, ErrRichQueryUnsupported
+	}
+	return queryValues(values, query)
+}
+
+// GetHistoryForKey returns the committed modifications of the key, newest first
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	modifications := s.ledger.history[key]
+	iterator := &historyIterator{}
+	for index := len(modifications) - 1; index >= 0; index-- {
+		iterator.modifications = append(iterator.modifications, modifications[index])
+	}
+	return iterator, nil
+}
+
+func (s *Stub) GetPrivateData(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be empty")
+	}
+	return s.ledger.private[collection][key], nil
+}
+
+func (s *Stub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	value, err := s.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if collection == "" {
+		return errors.New("collection must not be empty")
+	}
+	if len(value) == 0 {
+		return errors.New("private data value must not be empty")
+	}
+	s.privateWrite(collection, key, append([]byte(nil), value...))
+	return nil
+}
+
+func (s *Stub) DelPrivateData(collection, key string) error {
+	if collection == "" {
+		return errors.New("collection must not be empty")
+	}
+	s.privateWrite(collection, key, nil)
+	return nil
+}
+
+// PurgePrivateData deletes the value. The simulator keeps no private data history, so
+// there is nothing more to purge.
+func (s *Stub) PurgePrivateData(collection, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+func (s *Stub) privateWrite(collection string, key string, value []byte) {
+	if s.privateWrites[collection] == nil {
+		s.privateWrites[collection] = map[string][]byte{}
+	}
+	s.privateWrites[collection][key] = value
+}
+
+func (s *Stub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	s.privateValidation[collection+"\x00"+key] = ep
+	return nil
+}
+
+func (s *Stub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return s.privateValidation[collection+"\x00"+key], nil
+}
+
+func (s *Stub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	startKey, err := simpleRangeStart(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(s.ledger.private[collection], startKey, endKey), nil
+}
+
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(s.ledger.private[collection], startKey, endKey), nil
+}
+
+func (s *Stub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	matched, err := s.richQuery(s.ledger.private[collection], query)
+	if err != nil {
+		return nil, err
+	}
+	return newStateIterator(matched, "", ""), nil
+}
+
+func (s *Stub) GetCreator() ([]byte, error) {
+	if s.creator == nil {
+		return nil, errors.New("the transaction has no identity")
+	}
+	return s.creator, nil
+}
+
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	if s.transient == nil {
+		return map[string][]byte{}, nil
+	}
+	return s.transient, nil
+}
+
+func (s *Stub) GetBinding() ([]byte, error) {
+	binding := sha256.Sum256([]byte(s.txID))
+	return binding[:], nil
+}
+
+func (s *Stub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+// GetSignedProposal is not simulated, transactions are not signed
+func (s *Stub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return nil, errors.New("signed proposals are not simulated")
+}
+
+func (s *Stub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(s.timestamp), nil
+}
+
+// SetEvent replaces the transaction's event, since a transaction carries at most one
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name must not be empty")
+	}
+	s.event = &Event{TxID: s.txID, Name: name, Payload: payload}
+	return nil
+}
+
+func createCompositeKey(objectType string, attributes []string) (string, error) {
+	if err := validateCompositeKeyAttribute(objectType); err != nil {
+		return "", err
+	}
+	key := compositeKeyNamespace + objectType + "\x00"
+	for _, attribute := range attributes {
+		if err := validateCompositeKeyAttribute(attribute); err != nil {
+			return "", err
+		}
+		key += attribute + "\x00"
+	}
+	return key, nil
+}
+
+func validateCompositeKeyAttribute(attribute string) error {
+	if !utf8.ValidString(attribute) {
+		return fmt.Errorf("composite key attribute %q is not valid UTF-8", attribute)
+	}
+	if strings.ContainsAny(attribute, "\x00"+string(maxUnicodeRune)) {
+		return fmt.Errorf("composite key attribute %q contains a reserved character", attribute)
+	}
+	return nil
+}
+
+// partialCompositeRange returns the range of every key that starts with the attributes
+func partialCompositeRange(objectType string, attributes []string) (string, string, error) {
+	startKey, err := createCompositeKey(objectType, attributes)
+	if err != nil {
+		return "", "", err
+	}
+	return startKey, startKey + string(maxUnicodeRune), nil
+}
+
+// simpleRangeStart rejects composite keys in a simple key range and substitutes an
+// empty start key, so that composite keys never appear in the results
+func simpleRangeStart(startKey, endKey string) (string, error) {
+	if strings.HasPrefix(startKey, compositeKeyNamespace) || strings.HasPrefix(endKey, compositeKeyNamespace) {
+		return "", errors.New("range queries take simple keys, use a partial composite key query instead")
+	}
+	if startKey == "" {
+		return emptyKeySubstitute, nil
+	}
+	return startKey, nil
+}
+
+// keysInRange returns the keys of values from startKey up to, but not including,
+// endKey in order. An empty endKey has no upper bound.
+func keysInRange(values map[string][]byte, startKey, endKey string) []string {
+	var keys []string
+	for key := range values {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// paginate returns a page of at most pageSize keys starting at the bookmark, and the
+// bookmark of the next page, which is empty after the last one
+func paginate(values map[string][]byte, startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *pb.QueryResponseMetadata, error) {
+	if pageSize <= 0 {
+		return nil, nil, errors.New("page size must be positive")
+	}
+	if bookmark != "" {
+		if bookmark < startKey || (endKey != "" && bookmark >= endKey) {
+			return nil, nil, fmt.Errorf("bookmark %q is outside the queried range", bookmark)
+		}
+		startKey = bookmark
+	}
+	keys := keysInRange(values, startKey, endKey)
+	next := ""
+	if len(keys) > int(pageSize) {
+		next = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+	iterator := &stateIterator{}
+	for _, key := range keys {
+		iterator.add(key, values[key])
+	}
+	return iterator, &pb.QueryResponseMetadata{FetchedRecordsCount: int32(len(keys)), Bookmark: next}, nil
+}
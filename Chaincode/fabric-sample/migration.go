@@ -0,0 +1,128 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// MigrationReport lists what MigrateLedger moved into the namespaced keys
+type MigrationReport struct {
+	Patients   []int    `json:"patients"`
+	FamilyKeys []int    `json:"familyKeys"`
+	Diseases   bool     `json:"diseases"`
+	Skipped    []string `json:"skipped"`
+}
+
+// MigrateLedger moves records written under flat keys by earlier versions of the
+// contract into their namespaces: patients and tombstones under "patient" with a
+// "family" membership entry, and the disease table under "disease". Earlier versions
+// encrypted a family's records under GenerateKeyPair of the family ID and stored no key,
+// so each family of a migrated patient without a key gets that one, as a legacy key.
+// Keys it does not recognise are left in place and reported. Running it again is a no-op.
+func (s *SmartContract) MigrateLedger(ctx contractapi.TransactionContextInterface) (_ *MigrationReport, err error) {
+	defer catalogError(&err)
+	// Range queries only return simple keys, which are exactly the legacy records
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	report := MigrationReport{Patients: []int{}, FamilyKeys: []int{}, Skipped: []string{}}
+	// A transaction does not read its own writes, so the keys given out are kept here
+	familyKeys := map[int]*Pailler.PublicKey{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if queryResponse.Key == "DiseaseTable" {
+			var table Diseases
+			err = json.Unmarshal(queryResponse.Value, &table)
+			if err != nil {
+				return nil, core.InternalError("disease table can't be fetched: %v", err)
+			}
+			err = diseaseService(ctx).PutTable(table.SickleCellDisease, table.Type2Diabetes, table.Achondroplasia)
+			if err != nil {
+				return nil, err
+			}
+			err = ctx.GetStub().DelState(queryResponse.Key)
+			if err != nil {
+				return nil, err
+			}
+			report.Diseases = true
+			continue
+		}
+
+		var patient Patient
+		if json.Unmarshal(queryResponse.Value, &patient) != nil || patient.PatientNationalID == 0 {
+			report.Skipped = append(report.Skipped, queryResponse.Key)
+			continue
+		}
+		err = migratePatient(ctx, &patient, queryResponse.Value, familyKeys, &report)
+		if err != nil {
+			return nil, err
+		}
+		err = ctx.GetStub().DelState(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		report.Patients = append(report.Patients, patient.PatientNationalID)
+	}
+	return &report, nil
+}
+
+// migratePatient stores a legacy patient or tombstone under the patient namespace.
+// Tombstones are copied as they are and are not added to the family index.
+func migratePatient(ctx contractapi.TransactionContextInterface, patient *Patient, value []byte, familyKeys map[int]*Pailler.PublicKey, report *MigrationReport) error {
+	if !isErased(value) {
+		publicKey, err := migrateFamilyKey(ctx, patient.PatientFamilyID, familyKeys, report)
+		if err != nil {
+			return err
+		}
+		patient.KeyFingerprint = publicKey.Fingerprint()
+		err = putPatient(ctx, patient)
+		if err != nil {
+			return err
+		}
+	} else {
+		patientID, err := ctx.GetStub().CreateCompositeKey(core.PatientNamespace, []string{strconv.Itoa(patient.PatientNationalID)})
+		if err != nil {
+			return err
+		}
+		err = ctx.GetStub().PutState(patientID, value)
+		if err != nil {
+			return err
+		}
+	}
+	return auditService(ctx).Record(strconv.Itoa(patient.PatientNationalID), core.AuditMigrate, core.NoDiseaseSlot, "")
+}
+
+// migrateFamilyKey returns the key the family's legacy records are encrypted under and
+// stores it as the family's legacy key, unless the family already has a key
+func migrateFamilyKey(ctx contractapi.TransactionContextInterface, familyID int, familyKeys map[int]*Pailler.PublicKey, report *MigrationReport) (*Pailler.PublicKey, error) {
+	if publicKey, ok := familyKeys[familyID]; ok {
+		return publicKey, nil
+	}
+	publicKey, privateKey, err := Pailler.GenerateKeyPair(familyID)
+	if err != nil {
+		return nil, err
+	}
+	familyKeys[familyID] = publicKey
+	keys := familyKeyService(ctx)
+	record, err := keys.Record(strconv.Itoa(familyID))
+	if err != nil || record != nil {
+		return publicKey, err
+	}
+	err = keys.PutLegacy(strconv.Itoa(familyID), privateKey)
+	if err != nil {
+		return nil, err
+	}
+	report.FamilyKeys = append(report.FamilyKeys, familyID)
+	return publicKey, nil
+}
@@ -0,0 +1,140 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// chaincodeFunc is a chaincode whose Init and Invoke both call the function
+type chaincodeFunc func(stub shim.ChaincodeStubInterface) pb.Response
+
+func (f chaincodeFunc) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return f(stub)
+}
+
+func (f chaincodeFunc) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return f(stub)
+}
+
+// legacyPatient is a patient as the first version of the contract stored it
+type legacyPatient struct {
+	PatientName         string      `json:"patientName"`
+	PatientNationalID   int         `json:"patientNationalID"`
+	PatientFamilyID     int         `json:"patientFamilyID"`
+	PatientDiseaseTable [3]*big.Int `json:"patientDiseaseTable"`
+}
+
+// seedLegacyLedger writes the family 22 of InitLedger and its disease table under flat
+// keys, encrypted under GenerateKeyPair of the family ID as the first version did
+func (tb *testbed) seedLegacyLedger() {
+	tb.t.Helper()
+	publicKey, _, err := Pailler.GenerateKeyPair(22)
+	if err != nil {
+		tb.t.Fatal(err)
+	}
+	seed := func(stub shim.ChaincodeStubInterface) pb.Response {
+		for nationalID := 115; nationalID <= 121; nationalID++ {
+			patient := legacyPatient{PatientName: "Patient " + strconv.Itoa(nationalID), PatientNationalID: nationalID, PatientFamilyID: 22}
+			for index := range patient.PatientDiseaseTable {
+				var flag int64
+				if index == 1 && (nationalID == 119 || nationalID == 120) {
+					flag = 1
+				}
+				patient.PatientDiseaseTable[index], err = publicKey.Encrypt(flag)
+				if err != nil {
+					return shim.Error(err.Error())
+				}
+			}
+			patientJSON, err := json.Marshal(patient)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+			err = stub.PutState(strconv.Itoa(nationalID), patientJSON)
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+		diseasesJSON, err := json.Marshal(Diseases{SickleCellDisease: 100, Type2Diabetes: 70, Achondroplasia: 50})
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		err = stub.PutState("DiseaseTable", diseasesJSON)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success(nil)
+	}
+	result := tb.ledger.Invoke(chaincodeFunc(seed), ledgersim.Transaction{Function: "seed"})
+	if !result.Committed {
+		tb.t.Fatalf("seeding the legacy ledger: %s", result.Response.Message)
+	}
+}
+
+// A ledger written by the first version is readable and computes risks once migrated,
+// under the family key it was encrypted with
+func TestMigrateLedger(t *testing.T) {
+	tb := newChannel(t)
+	tb.seedLegacyLedger()
+	_, legacyKey, err := Pailler.GenerateKeyPair(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report MigrationReport
+	tb.mustDecode(&report, "MigrateLedger")
+	sort.Ints(report.Patients)
+	want := MigrationReport{
+		Patients:   []int{115, 116, 117, 118, 119, 120, 121},
+		FamilyKeys: []int{22},
+		Diseases:   true,
+		Skipped:    []string{},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %+v, want %+v", report, want)
+	}
+	if tb.ledger.State("115") != nil || tb.ledger.State("DiseaseTable") != nil {
+		t.Error("the flat keys were left in place")
+	}
+	if tb.ledger.PrivateData(keyCollection, compositeKey(t, core.FamilyKeyNamespace, "22", "0")) == nil {
+		t.Error("the legacy family key was not stored")
+	}
+
+	var patient PatientView
+	tb.mustDecode(&patient, "ReadAsset", "119")
+	if patient.KeyFingerprint != legacyKey.Pk.Fingerprint() || patient.CreatedAt == "" || patient.UpdatedAt == "" {
+		t.Errorf("migrated patient = %+v", patient)
+	}
+	if got := tb.diseaseValues(legacyKey, "119"); !reflect.DeepEqual(got, []int64{0, 1, 0}) {
+		t.Errorf("record of 119 decrypts to %v, want [0 1 0]", got)
+	}
+
+	for _, nationalID := range []string{"119", "120"} {
+		tb.mustInvoke(nil, "GrantConsent", nationalID, core.PurposeRiskComputation, "Org1MSP", "")
+	}
+	var result RiskResult
+	tb.mustDecode(&result, "TransferAsset", "121", "1")
+	if result.Risk != 70 || decryptHex(t, legacyKey, []byte(result.EncryptedRisk)) != 70 {
+		t.Errorf("risk of 121 = %d, want 70", result.Risk)
+	}
+
+	tb.mustDecode(&report, "MigrateLedger")
+	want = MigrationReport{Patients: []int{}, FamilyKeys: []int{}, Skipped: []string{}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("second migration = %+v, want %+v", report, want)
+	}
+
+	receipt, _ := tb.erase("ErasePatient", "116", core.ReasonSubjectRequest)
+	if receipt.KeyPurged || receipt.PurgedKey != legacyKey.Pk.Fingerprint() {
+		t.Errorf("erasing under the legacy key: %+v", receipt)
+	}
+}
@@ -0,0 +1,32 @@
+package chaincode
+
+import "github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+// PatientPage is one page of a patient listing. Pass Bookmark to the next call to
+// continue; an empty bookmark means the listing is complete.
+type PatientPage struct {
+	Records             []*PatientView `json:"records"`
+	FetchedRecordsCount int32          `json:"fetchedRecordsCount"`
+	Bookmark            string         `json:"bookmark"`
+}
+
+// GetAssetsPage returns up to pageSize patients starting at the bookmark. When
+// patientFamilyID is not empty only members of that family are listed. Erased patients
+// are counted as fetched but left out of Records, so a page may hold fewer records than
+// pageSize while more remain.
+func (s *SmartContract) GetAssetsPage(ctx contractapi.TransactionContextInterface, pageSize int32, bookmark string, patientFamilyID string) (_ *PatientPage, err error) {
+	defer catalogError(&err)
+	result, err := patientService(ctx).Page(patientFamilyID, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	page := &PatientPage{Records: []*PatientView{}, FetchedRecordsCount: result.FetchedRecordsCount, Bookmark: result.Bookmark}
+	for _, record := range result.Patients {
+		patient, err := patientFromCore(record)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, newPatientView(patient))
+	}
+	return page, nil
+}
@@ -0,0 +1,312 @@
+package Pailler
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// KeyFormatVersion is the version written into every encoded key
+const KeyFormatVersion = 1
+
+// Algorithm names the scheme in encoded keys
+const Algorithm = "paillier"
+
+// PEM block types of encoded keys
+const (
+	PublicKeyPEMType  = "PAILLIER PUBLIC KEY"
+	PrivateKeyPEMType = "PAILLIER PRIVATE KEY"
+)
+
+// Prefixes of the compact base64url forms
+const (
+	compactPublicPrefix  = "ppk1."
+	compactPrivatePrefix = "psk1."
+)
+
+// publicKeyJSON is the versioned JSON form of a public key. Integers are hex strings.
+type publicKeyJSON struct {
+	Version   int    `json:"version"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	G         string `json:"g"`
+}
+
+// privateKeyJSON is the versioned JSON form of a private key. Integers are hex strings.
+type privateKeyJSON struct {
+	Version   int    `json:"version"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	G         string `json:"g"`
+	Lambda    string `json:"lambda"`
+	Mu        string `json:"mu"`
+}
+
+// publicKeyASN1 is the DER structure of a public key
+type publicKeyASN1 struct {
+	Version int
+	N       *big.Int
+	G       *big.Int
+}
+
+// privateKeyASN1 is the DER structure of a private key
+type privateKeyASN1 struct {
+	Version int
+	N       *big.Int
+	G       *big.Int
+	Lambda  *big.Int
+	Mu      *big.Int
+}
+
+// MarshalDER returns the canonical ASN.1 DER encoding of the public key
+func (pk *PublicKey) MarshalDER() ([]byte, error) {
+	if pk == nil || pk.N == nil || pk.G == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	return asn1.Marshal(publicKeyASN1{Version: KeyFormatVersion, N: pk.N, G: pk.G})
+}
+
+// ParsePublicKeyDER parses a public key encoded by MarshalDER
+func ParsePublicKeyDER(der []byte) (*PublicKey, error) {
+	var raw publicKeyASN1
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after public key")
+	}
+	if raw.Version != KeyFormatVersion {
+		return nil, fmt.Errorf("unsupported key version %d", raw.Version)
+	}
+	return newCheckedPublicKey(raw.N, raw.G)
+}
+
+// MarshalDER returns the ASN.1 DER encoding of the private key
+func (sk *PrivateKey) MarshalDER() ([]byte, error) {
+	if sk == nil || sk.Pk == nil || sk.Pk.N == nil || sk.Pk.G == nil || sk.Lambda == nil || sk.Mu == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	return asn1.Marshal(privateKeyASN1{Version: KeyFormatVersion, N: sk.Pk.N, G: sk.Pk.G, Lambda: sk.Lambda, Mu: sk.Mu})
+}
+
+// ParsePrivateKeyDER parses a private key encoded by MarshalDER
+func ParsePrivateKeyDER(der []byte) (*PrivateKey, error) {
+	var raw privateKeyASN1
+	rest, err := asn1.Unmarshal(der, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key encoding: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after private key")
+	}
+	if raw.Version != KeyFormatVersion {
+		return nil, fmt.Errorf("unsupported key version %d", raw.Version)
+	}
+	return newCheckedPrivateKey(raw.N, raw.G, raw.Lambda, raw.Mu)
+}
+
+// EncodePEM returns the public key as a PEM block
+func (pk *PublicKey) EncodePEM() ([]byte, error) {
+	der, err := pk.MarshalDER()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM parses the first PEM block of `data` as a public key
+func ParsePublicKeyPEM(data []byte) (*PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PublicKeyPEMType {
+		return nil, fmt.Errorf("no %s block found", PublicKeyPEMType)
+	}
+	return ParsePublicKeyDER(block.Bytes)
+}
+
+// EncodePEM returns the private key as a PEM block
+func (sk *PrivateKey) EncodePEM() ([]byte, error) {
+	der, err := sk.MarshalDER()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: PrivateKeyPEMType, Bytes: der}), nil
+}
+
+// ParsePrivateKeyPEM parses the first PEM block of `data` as a private key
+func ParsePrivateKeyPEM(data []byte) (*PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PrivateKeyPEMType {
+		return nil, fmt.Errorf("no %s block found", PrivateKeyPEMType)
+	}
+	return ParsePrivateKeyDER(block.Bytes)
+}
+
+// EncodeJSON returns the versioned JSON form of the public key
+func (pk *PublicKey) EncodeJSON() ([]byte, error) {
+	if pk == nil || pk.N == nil || pk.G == nil {
+		return nil, fmt.Errorf("invalid public key")
+	}
+	return json.Marshal(publicKeyJSON{Version: KeyFormatVersion, Algorithm: Algorithm, N: pk.N.Text(16), G: pk.G.Text(16)})
+}
+
+// ParsePublicKeyJSON parses a public key encoded by EncodeJSON
+func ParsePublicKeyJSON(data []byte) (*PublicKey, error) {
+	var raw publicKeyJSON
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeader(raw.Version, raw.Algorithm); err != nil {
+		return nil, err
+	}
+	values, err := parseHex(raw.N, raw.G)
+	if err != nil {
+		return nil, err
+	}
+	return newCheckedPublicKey(values[0], values[1])
+}
+
+// EncodeJSON returns the versioned JSON form of the private key
+func (sk *PrivateKey) EncodeJSON() ([]byte, error) {
+	if sk == nil || sk.Pk == nil || sk.Pk.N == nil || sk.Pk.G == nil || sk.Lambda == nil || sk.Mu == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	return json.Marshal(privateKeyJSON{
+		Version:   KeyFormatVersion,
+		Algorithm: Algorithm,
+		N:         sk.Pk.N.Text(16),
+		G:         sk.Pk.G.Text(16),
+		Lambda:    sk.Lambda.Text(16),
+		Mu:        sk.Mu.Text(16),
+	})
+}
+
+// ParsePrivateKeyJSON parses a private key encoded by EncodeJSON
+func ParsePrivateKeyJSON(data []byte) (*PrivateKey, error) {
+	var raw privateKeyJSON
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkHeader(raw.Version, raw.Algorithm); err != nil {
+		return nil, err
+	}
+	values, err := parseHex(raw.N, raw.G, raw.Lambda, raw.Mu)
+	if err != nil {
+		return nil, err
+	}
+	return newCheckedPrivateKey(values[0], values[1], values[2], values[3])
+}
+
+// EncodeCompact returns the public key as a single URL-safe token: a version prefix
+// followed by the unpadded base64url of the DER encoding
+func (pk *PublicKey) EncodeCompact() (string, error) {
+	der, err := pk.MarshalDER()
+	if err != nil {
+		return "", err
+	}
+	return compactPublicPrefix + base64.RawURLEncoding.EncodeToString(der), nil
+}
+
+// ParsePublicKeyCompact parses a public key encoded by EncodeCompact
+func ParsePublicKeyCompact(token string) (*PublicKey, error) {
+	if !strings.HasPrefix(token, compactPublicPrefix) {
+		return nil, fmt.Errorf("not a compact public key")
+	}
+	der, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, compactPublicPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compact public key: %v", err)
+	}
+	return ParsePublicKeyDER(der)
+}
+
+// EncodeCompact returns the private key as a single URL-safe token
+func (sk *PrivateKey) EncodeCompact() (string, error) {
+	der, err := sk.MarshalDER()
+	if err != nil {
+		return "", err
+	}
+	return compactPrivatePrefix + base64.RawURLEncoding.EncodeToString(der), nil
+}
+
+// ParsePrivateKeyCompact parses a private key encoded by EncodeCompact
+func ParsePrivateKeyCompact(token string) (*PrivateKey, error) {
+	if !strings.HasPrefix(token, compactPrivatePrefix) {
+		return nil, fmt.Errorf("not a compact private key")
+	}
+	der, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, compactPrivatePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid compact private key: %v", err)
+	}
+	return ParsePrivateKeyDER(der)
+}
+
+// Fingerprint returns the hex SHA-256 of the DER encoding of the public key. Two keys
+// have the same fingerprint exactly when they have the same N and g, so it is used to
+// tag ciphertexts with the key they were produced under.
+func (pk *PublicKey) Fingerprint() string {
+	der, err := pk.MarshalDER()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func checkHeader(version int, algorithm string) error {
+	if version != KeyFormatVersion {
+		return fmt.Errorf("unsupported key version %d", version)
+	}
+	if algorithm != Algorithm {
+		return fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+	return nil
+}
+
+func parseHex(values ...string) ([]*big.Int, error) {
+	parsed := make([]*big.Int, len(values))
+	for i, value := range values {
+		number, ok := new(big.Int).SetString(value, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid hex value in key")
+		}
+		parsed[i] = number
+	}
+	return parsed, nil
+}
+
+// newCheckedPublicKey builds a public key after checking that N > 1 and 0 < g < N²
+func newCheckedPublicKey(n, g *big.Int) (*PublicKey, error) {
+	if n == nil || g == nil || n.Cmp(one) != 1 {
+		return nil, fmt.Errorf("invalid modulus N")
+	}
+	nn := new(big.Int).Mul(n, n)
+	if g.Sign() != 1 || g.Cmp(nn) != -1 {
+		return nil, fmt.Errorf("invalid generator g")
+	}
+	return &PublicKey{N: n, G: g, N2: nn}, nil
+}
+
+// newCheckedPrivateKey builds a private key after checking that mu is the inverse of
+// L(g^lambda mod N²) modulo N, i.e. that the key actually decrypts
+func newCheckedPrivateKey(n, g, lambda, mu *big.Int) (*PrivateKey, error) {
+	pk, err := newCheckedPublicKey(n, g)
+	if err != nil {
+		return nil, err
+	}
+	if lambda == nil || mu == nil || lambda.Sign() != 1 || mu.Sign() != 1 {
+		return nil, fmt.Errorf("invalid private exponent")
+	}
+	expected := new(big.Int).ModInverse(L(new(big.Int).Exp(g, lambda, pk.N2), n), n)
+	if expected == nil || expected.Cmp(mu) != 0 {
+		return nil, fmt.Errorf("private key does not match public key")
+	}
+	return &PrivateKey{Mu: mu, Lambda: lambda, Pk: pk}, nil
+}
@@ -0,0 +1,239 @@
+package Pailler
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// publicFormats are the encodings of a public key, each with its parser
+var publicFormats = []struct {
+	name   string
+	encode func(pk *PublicKey) ([]byte, error)
+	parse  func(data []byte) (*PublicKey, error)
+}{
+	{name: "DER", encode: (*PublicKey).MarshalDER, parse: ParsePublicKeyDER},
+	{name: "PEM", encode: (*PublicKey).EncodePEM, parse: ParsePublicKeyPEM},
+	{name: "JSON", encode: (*PublicKey).EncodeJSON, parse: ParsePublicKeyJSON},
+	{
+		name: "compact",
+		encode: func(pk *PublicKey) ([]byte, error) {
+			token, err := pk.EncodeCompact()
+			return []byte(token), err
+		},
+		parse: func(data []byte) (*PublicKey, error) { return ParsePublicKeyCompact(string(data)) },
+	},
+}
+
+// privateFormats are the encodings of a private key, each with its parser
+var privateFormats = []struct {
+	name   string
+	encode func(sk *PrivateKey) ([]byte, error)
+	parse  func(data []byte) (*PrivateKey, error)
+}{
+	{name: "DER", encode: (*PrivateKey).MarshalDER, parse: ParsePrivateKeyDER},
+	{name: "PEM", encode: (*PrivateKey).EncodePEM, parse: ParsePrivateKeyPEM},
+	{name: "JSON", encode: (*PrivateKey).EncodeJSON, parse: ParsePrivateKeyJSON},
+	{
+		name: "compact",
+		encode: func(sk *PrivateKey) ([]byte, error) {
+			token, err := sk.EncodeCompact()
+			return []byte(token), err
+		},
+		parse: func(data []byte) (*PrivateKey, error) { return ParsePrivateKeyCompact(string(data)) },
+	},
+}
+
+func TestPublicKeyRoundTrip(t *testing.T) {
+	key := testKey(t, "family 22")
+	for _, format := range publicFormats {
+		t.Run(format.name, func(t *testing.T) {
+			encoded, err := format.encode(key.Pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := format.parse(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.N.Cmp(key.Pk.N) != 0 || parsed.G.Cmp(key.Pk.G) != 0 || parsed.N2.Cmp(key.Pk.N2) != 0 {
+				t.Errorf("parsed %v, want %v", parsed, key.Pk)
+			}
+		})
+	}
+}
+
+func TestPrivateKeyRoundTrip(t *testing.T) {
+	key := testKey(t, "family 22")
+	ciphertext, err := key.Pk.Encrypt(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range privateFormats {
+		t.Run(format.name, func(t *testing.T) {
+			encoded, err := format.encode(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := format.parse(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed.Lambda.Cmp(key.Lambda) != 0 || parsed.Mu.Cmp(key.Mu) != 0 || parsed.Pk.Fingerprint() != key.Pk.Fingerprint() {
+				t.Error("parsed key differs from the encoded one")
+			}
+			if got, err := parsed.Decrypt(ciphertext); err != nil || got != 42 {
+				t.Errorf("parsed key decrypts %d (%v), want 42", got, err)
+			}
+		})
+	}
+}
+
+// The fingerprint depends on N and g only, and is the same however the key was encoded
+func TestFingerprint(t *testing.T) {
+	key := testKey(t, "family 22")
+	fingerprint := key.Pk.Fingerprint()
+	if len(fingerprint) != 64 {
+		t.Fatalf("fingerprint %q is not a hex SHA-256", fingerprint)
+	}
+	if testKey(t, "family 22").Pk.Fingerprint() != fingerprint {
+		t.Error("the same key has two fingerprints")
+	}
+	if testKey(t, "family 23").Pk.Fingerprint() == fingerprint {
+		t.Error("two keys share a fingerprint")
+	}
+	for _, format := range publicFormats {
+		encoded, err := format.encode(key.Pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := format.parse(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Fingerprint() != fingerprint {
+			t.Errorf("%s: fingerprint changed across the round trip", format.name)
+		}
+	}
+	otherG := &PublicKey{N: key.Pk.N, G: new(big.Int).Add(key.Pk.G, one), N2: key.Pk.N2}
+	if otherG.Fingerprint() == fingerprint {
+		t.Error("a key with another g has the same fingerprint")
+	}
+	if (&PublicKey{}).Fingerprint() != "" {
+		t.Error("an empty key has a fingerprint")
+	}
+}
+
+// Encodings of another version are refused by every parser
+func TestUnsupportedKeyVersion(t *testing.T) {
+	key := testKey(t, "family 22")
+	publicDER, err := asn1.Marshal(publicKeyASN1{Version: KeyFormatVersion + 1, N: key.Pk.N, G: key.Pk.G})
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := asn1.Marshal(privateKeyASN1{Version: KeyFormatVersion + 1, N: key.Pk.N, G: key.Pk.G, Lambda: key.Lambda, Mu: key.Mu})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicJSON := `{"version":2,"alg":"paillier","n":"` + key.Pk.N.Text(16) + `","g":"` + key.Pk.G.Text(16) + `"}`
+	privateJSON := `{"version":2,"alg":"paillier","n":"` + key.Pk.N.Text(16) + `","g":"` + key.Pk.G.Text(16) +
+		`","lambda":"` + key.Lambda.Text(16) + `","mu":"` + key.Mu.Text(16) + `"}`
+
+	tests := []struct {
+		name  string
+		parse func() error
+	}{
+		{name: "public DER", parse: func() error { _, err := ParsePublicKeyDER(publicDER); return err }},
+		{name: "public PEM", parse: func() error {
+			_, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: publicDER}))
+			return err
+		}},
+		{name: "public compact", parse: func() error {
+			_, err := ParsePublicKeyCompact(compactPublicPrefix + base64.RawURLEncoding.EncodeToString(publicDER))
+			return err
+		}},
+		{name: "public JSON", parse: func() error { _, err := ParsePublicKeyJSON([]byte(publicJSON)); return err }},
+		{name: "private DER", parse: func() error { _, err := ParsePrivateKeyDER(privateDER); return err }},
+		{name: "private JSON", parse: func() error { _, err := ParsePrivateKeyJSON([]byte(privateJSON)); return err }},
+	}
+	for _, test := range tests {
+		if err := test.parse(); err == nil || !strings.Contains(err.Error(), "unsupported key version 2") {
+			t.Errorf("%s: got %v, want the version refused", test.name, err)
+		}
+	}
+}
+
+// Malformed input is an error, never a key
+func TestMalformedKeys(t *testing.T) {
+	key := testKey(t, "family 22")
+	publicDER, err := key.Pk.MarshalDER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePEM, err := key.EncodePEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey := testKey(t, "family 23")
+	n, g := key.Pk.N.Text(16), key.Pk.G.Text(16)
+
+	publicTests := []struct {
+		name  string
+		parse func() (*PublicKey, error)
+	}{
+		{name: "empty DER", parse: func() (*PublicKey, error) { return ParsePublicKeyDER(nil) }},
+		{name: "truncated DER", parse: func() (*PublicKey, error) { return ParsePublicKeyDER(publicDER[:len(publicDER)-1]) }},
+		{name: "trailing DER", parse: func() (*PublicKey, error) { return ParsePublicKeyDER(append(publicDER, 0)) }},
+		{name: "private PEM", parse: func() (*PublicKey, error) { return ParsePublicKeyPEM(privatePEM) }},
+		{name: "no PEM", parse: func() (*PublicKey, error) { return ParsePublicKeyPEM([]byte("not a key")) }},
+		{name: "private compact", parse: func() (*PublicKey, error) { return ParsePublicKeyCompact(compactPrivatePrefix + "AAAA") }},
+		{name: "bad base64", parse: func() (*PublicKey, error) { return ParsePublicKeyCompact(compactPublicPrefix + "!!") }},
+		{name: "JSON syntax", parse: func() (*PublicKey, error) { return ParsePublicKeyJSON([]byte("{")) }},
+		{name: "JSON algorithm", parse: func() (*PublicKey, error) {
+			return ParsePublicKeyJSON([]byte(`{"version":1,"alg":"rsa","n":"` + n + `","g":"` + g + `"}`))
+		}},
+		{name: "JSON hex", parse: func() (*PublicKey, error) {
+			return ParsePublicKeyJSON([]byte(`{"version":1,"alg":"paillier","n":"xyz","g":"` + g + `"}`))
+		}},
+		{name: "modulus 1", parse: func() (*PublicKey, error) {
+			return ParsePublicKeyJSON([]byte(`{"version":1,"alg":"paillier","n":"1","g":"1"}`))
+		}},
+		{name: "generator 0", parse: func() (*PublicKey, error) {
+			return ParsePublicKeyJSON([]byte(`{"version":1,"alg":"paillier","n":"` + n + `","g":"0"}`))
+		}},
+		{name: "generator N²", parse: func() (*PublicKey, error) {
+			return ParsePublicKeyJSON([]byte(`{"version":1,"alg":"paillier","n":"` + n + `","g":"` + key.Pk.N2.Text(16) + `"}`))
+		}},
+	}
+	for _, test := range publicTests {
+		if parsed, err := test.parse(); err == nil {
+			t.Errorf("%s: parsed %v", test.name, parsed)
+		}
+	}
+
+	// A private key whose secret values belong to another key does not decrypt
+	mismatched := `{"version":1,"alg":"paillier","n":"` + n + `","g":"` + g +
+		`","lambda":"` + otherKey.Lambda.Text(16) + `","mu":"` + otherKey.Mu.Text(16) + `"}`
+	privateTests := []struct {
+		name  string
+		parse func() (*PrivateKey, error)
+	}{
+		{name: "public DER", parse: func() (*PrivateKey, error) { return ParsePrivateKeyDER(publicDER) }},
+		{name: "public PEM", parse: func() (*PrivateKey, error) {
+			return ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: publicDER}))
+		}},
+		{name: "public compact", parse: func() (*PrivateKey, error) { return ParsePrivateKeyCompact(compactPublicPrefix + "AAAA") }},
+		{name: "mismatched secret", parse: func() (*PrivateKey, error) { return ParsePrivateKeyJSON([]byte(mismatched)) }},
+		{name: "missing secret", parse: func() (*PrivateKey, error) {
+			return ParsePrivateKeyJSON([]byte(`{"version":1,"alg":"paillier","n":"` + n + `","g":"` + g + `","lambda":"0","mu":"0"}`))
+		}},
+	}
+	for _, test := range privateTests {
+		if parsed, err := test.parse(); err == nil {
+			t.Errorf("%s: parsed %v", test.name, parsed)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package Pailler
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// MinWrapSecretLength is the shortest secret WrapKey and UnwrapKey accept
+const MinWrapSecretLength = 32
+
+// WrapKey seals `inner` with AES-256-GCM under a key-encryption key hashed from `secret`
+// and the secret values of `sk`. Anyone who can read `sk` but not the secret cannot
+// recover `inner`; anyone with both can, so the secret is only as safe as the place it
+// is kept. The `label` is authenticated with the ciphertext and must be given again to
+// UnwrapKey. The nonce is derived from the key-encryption key, the label and the
+// plaintext rather than drawn at random, which keeps the output identical on every
+// endorsing peer with the same secret; the price is that wrapping the same key under the
+// same label twice gives the same bytes.
+func (sk *PrivateKey) WrapKey(inner *PrivateKey, label string, secret []byte) ([]byte, error) {
+	if inner == nil || inner.Pk == nil {
+		return nil, fmt.Errorf("invalid key")
+	}
+	plaintext, err := json.Marshal(inner)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := sk.keyEncryptionKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.New()
+	digest.Write(kek)
+	digest.Write([]byte(label))
+	digest.Write(plaintext)
+	nonce := digest.Sum(nil)[:aead.NonceSize()]
+
+	return aead.Seal(nonce, nonce, plaintext, []byte(label)), nil
+}
+
+// UnwrapKey opens a key sealed by WrapKey with the same secret
+func (sk *PrivateKey) UnwrapKey(wrapped []byte, label string, secret []byte) (*PrivateKey, error) {
+	kek, err := sk.keyEncryptionKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid wrapped key")
+	}
+
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(label))
+	if err != nil {
+		return nil, fmt.Errorf("wrapped key cannot be opened with this key and secret")
+	}
+
+	inner := new(PrivateKey)
+	err = json.Unmarshal(plaintext, inner)
+	if err != nil {
+		return nil, err
+	}
+	return inner, nil
+}
+
+// Reencrypt decrypts a ciphertext produced under the public key of `sk` and encrypts the
+// plaintext under `target`. It is neither proxy re-encryption nor key switching: it needs
+// the private key, and the plaintext is in the clear in the memory of whoever calls it,
+// which for chaincode is every endorsing peer. It only moves a value to another key.
+// The plaintext must be smaller than the target modulus.
+func (sk *PrivateKey) Reencrypt(ct *big.Int, target *PublicKey) (*big.Int, error) {
+	return sk.ReencryptFrom(rand.Reader, ct, target)
+}
+
+// ReencryptFrom moves a ciphertext to `target` as Reencrypt does, drawing the randomness
+// of the new ciphertext from `random`
+func (sk *PrivateKey) ReencryptFrom(random io.Reader, ct *big.Int, target *PublicKey) (*big.Int, error) {
+	if target == nil {
+		return nil, fmt.Errorf("invalid target key")
+	}
+	msg, err := sk.Decrypt(ct)
+	if err != nil {
+		return nil, err
+	}
+	return target.EncryptFrom(random, msg)
+}
+
+// keyEncryptionKey derives the AES-256 key used by WrapKey from the secret and the
+// secret values of sk
+func (sk *PrivateKey) keyEncryptionKey(secret []byte) ([]byte, error) {
+	if len(secret) < MinWrapSecretLength {
+		return nil, fmt.Errorf("key wrap secret must be at least %d bytes", MinWrapSecretLength)
+	}
+	digest := sha256.New()
+	digest.Write([]byte("genchain key wrap"))
+	digest.Write(secret)
+	digest.Write(sk.Lambda.Bytes())
+	digest.Write(sk.Mu.Bytes())
+	return digest.Sum(nil), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,61 @@
+package Pailler
+
+import "testing"
+
+var testWrapSecret = []byte("a key wrap secret of thirty-two bytes")
+
+func TestWrapKey(t *testing.T) {
+	familyKey, patientKey := testKey(t, "family 22"), testKey(t, "patient 130")
+	wrapped, err := familyKey.WrapKey(patientKey, "patientkey/130", testWrapSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unwrapped, err := familyKey.UnwrapKey(wrapped, "patientkey/130", testWrapSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unwrapped.Pk.Fingerprint() != patientKey.Pk.Fingerprint() || unwrapped.Lambda.Cmp(patientKey.Lambda) != 0 {
+		t.Error("UnwrapKey returned another key")
+	}
+
+	tests := []struct {
+		name   string
+		key    *PrivateKey
+		label  string
+		secret []byte
+	}{
+		{name: "other family key", key: testKey(t, "family 21"), label: "patientkey/130", secret: testWrapSecret},
+		{name: "other label", key: familyKey, label: "patientkey/131", secret: testWrapSecret},
+		{name: "other secret", key: familyKey, label: "patientkey/130", secret: []byte("another secret of at least thirty-two bytes")},
+		// The family key alone, as anyone reading the key collection holds it, is not enough
+		{name: "no secret", key: familyKey, label: "patientkey/130"},
+		{name: "short secret", key: familyKey, label: "patientkey/130", secret: []byte("short")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.key.UnwrapKey(wrapped, test.label, test.secret); err == nil {
+				t.Error("UnwrapKey opened the key")
+			}
+		})
+	}
+
+	if _, err := familyKey.WrapKey(patientKey, "patientkey/130", nil); err == nil {
+		t.Error("WrapKey accepted an empty secret")
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	source, target := testKey(t, "patient 120"), testKey(t, "family 22")
+	ciphertext, err := source.Pk.Encrypt(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reencrypted, err := source.Reencrypt(ciphertext, target.Pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := target.Decrypt(reencrypted); err != nil || got != 42 {
+		t.Errorf("Decrypt(Reencrypt(Encrypt(42))) = %d, %v", got, err)
+	}
+}
@@ -9,14 +9,18 @@ import (
 // the corresponding plaintext messages (`m1`, `m2`) ciphered to (`ct1`, `ct2`)
 // (i.e if ct1 = Enc(m1) and ct2 = Enc(m2), then Dec(Add(ct1, ct2)) = m1 + m2 mod N)
 func (pk *PublicKey) Add(ct1, ct2 *big.Int) (*big.Int, error) {
+	if !pk.isCiphertext(ct1) || !pk.isCiphertext(ct2) {
+		return nil, fmt.Errorf("invalid input")
+	}
 	z := new(big.Int).Mul(ct1, ct2)
 	return z.Mod(z, pk.N2), nil
 }
 
 // MultPlaintext returns the ciphertext the will decipher to multiplication
-// of the plaintexts (i.e. if ct = Enc(m1), then Dec(MultPlaintext(ct, m2)) = m1 * m2 mod N)
+// of the plaintexts (i.e. if ct = Enc(m1), then Dec(MultPlaintext(ct, m2)) = m1 * m2 mod N).
+// A negative `msg` is allowed and gives N - |m1 * m2| mod N.
 func (pk *PublicKey) MultPlaintext(ct *big.Int, msg int64) (*big.Int, error) {
-	if ct == nil || ct.Cmp(zero) != 1 {
+	if !pk.isCiphertext(ct) {
 		return nil, fmt.Errorf("invalid input")
 	}
 	return new(big.Int).Exp(ct, new(big.Int).SetInt64(msg), pk.N2), nil
@@ -25,7 +29,7 @@ func (pk *PublicKey) MultPlaintext(ct *big.Int, msg int64) (*big.Int, error) {
 // AddPlaintext returns the ciphertext the will decipher to addition
 // of the plaintexts (i.e if ct = Enc(m1), then Dec(AddPlaintext(ct, m2)) = m1 + m2 mod N)
 func (pk *PublicKey) AddPlaintext(ct *big.Int, msg int64) (*big.Int, error) {
-	if ct == nil || ct.Cmp(zero) != 1 || msg < 0 {
+	if !pk.isCiphertext(ct) || msg < 0 {
 		return nil, fmt.Errorf("invalid input")
 	}
 
@@ -33,37 +37,69 @@ func (pk *PublicKey) AddPlaintext(ct *big.Int, msg int64) (*big.Int, error) {
 	return ct2.Mod(ct2.Mul(ct2, ct), pk.N2), nil
 }
 
+// AddSignedPlaintext is AddPlaintext for a `msg` that may be negative, which is added
+// as N - |msg| (i.e. Dec(AddSignedPlaintext(ct, m2)) = m1 + m2 mod N). DecryptInRange
+// reads such sums back.
+func (pk *PublicKey) AddSignedPlaintext(ct *big.Int, msg int64) (*big.Int, error) {
+	if !pk.isCiphertext(ct) {
+		return nil, fmt.Errorf("invalid input")
+	}
+
+	m := new(big.Int).Mod(new(big.Int).SetInt64(msg), pk.N)
+	ct2 := new(big.Int).Exp(pk.G, m, pk.N2)
+	return ct2.Mod(ct2.Mul(ct2, ct), pk.N2), nil
+}
+
 // BatchAdd optmizes the homomorphic addition of a list of ciphertexts. That
 // is, it computes a ciphertext that will decipher to the sum of all
 // corresponding plaintext messages.
-func (pk *PublicKey) BatchAdd(cts ...*big.Int) *big.Int {
+func (pk *PublicKey) BatchAdd(cts ...*big.Int) (*big.Int, error) {
 	total := new(big.Int).SetInt64(1)
 	for i, ct := range cts {
+		if !pk.isCiphertext(ct) {
+			return nil, fmt.Errorf("invalid input")
+		}
 		total.Mul(total, ct)
 		if i%5 == 0 {
 			total.Mod(total, pk.N2)
 		}
 	}
-	return total.Mod(total, pk.N2)
+	return total.Mod(total, pk.N2), nil
 }
 
 // Sub executes homomorphic subtraction, which corresponds to the addition
 // with the modular inverse. That is, it computes a ciphertext ct3 that will
 // decipher to the subtration of the corresponding plaintexts. So, if ct1 = Enc(m1)
-// and ct2 = Enc(m2), and m1 > m2, then Dec(Sub(ct1, ct2)) = ct1 - ct2 mod N.
-// Note that the ciphertext produced by this operation will only make sense if m1>m2.
-func (pk *PublicKey) Sub(ct1, ct2 *big.Int) *big.Int {
+// and ct2 = Enc(m2), then Dec(Sub(ct1, ct2)) = m1 - m2 mod N.
+// Note that when m1 < m2 the result wraps around to N - (m2 - m1).
+func (pk *PublicKey) Sub(ct1, ct2 *big.Int) (*big.Int, error) {
+	if !pk.isCiphertext(ct1) || !pk.isCiphertext(ct2) {
+		return nil, fmt.Errorf("invalid input")
+	}
 	neg := new(big.Int).ModInverse(ct2, pk.N2)
 	neg.Mul(ct1, neg)
-	return neg.Mod(neg, pk.N2)
+	return neg.Mod(neg, pk.N2), nil
 }
 
 // DivPlaintext returns the ciphertext the will decipher to division of the plaintexts
-// (i.e if ct = Enc(m1), then Dec(DivPlaintext(ct, m2)) = m1 / m2 mod N)
+// (i.e if ct = Enc(m1), then Dec(DivPlaintext(ct, m2)) = m1 * m2^-1 mod N). This is
+// m1 / m2 only when m2 divides m1, and m2 must be invertible modulo N.
 func (pk *PublicKey) DivPlaintext(ct *big.Int, msg int64) (*big.Int, error) {
-	if ct == nil || ct.Cmp(zero) != 1 {
+	if !pk.isCiphertext(ct) {
 		return nil, fmt.Errorf("invalid input")
 	}
 	m := new(big.Int).SetInt64(msg)
-	return new(big.Int).Exp(ct, m.ModInverse(m, pk.N2), pk.N2), nil
+	if m.ModInverse(m, pk.N) == nil {
+		return nil, fmt.Errorf("%d is not invertible modulo N", msg)
+	}
+	return new(big.Int).Exp(ct, m, pk.N2), nil
+}
+
+// isCiphertext reports whether ct is in Z*_{N²}, the range every ciphertext under the
+// key falls in
+func (pk *PublicKey) isCiphertext(ct *big.Int) bool {
+	if ct == nil || ct.Sign() != 1 || ct.Cmp(pk.N2) != -1 {
+		return false
+	}
+	return new(big.Int).GCD(nil, nil, ct, pk.N).Cmp(one) == 0
 }
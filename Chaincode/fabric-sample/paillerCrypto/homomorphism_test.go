@@ -0,0 +1,289 @@
+package Pailler
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+// encryptResidue encrypts m mod N, which covers negative messages and N - 1
+func encryptResidue(t testing.TB, pk *PublicKey, m int64) *big.Int {
+	t.Helper()
+	zero, err := pk.Encrypt(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := pk.AddSignedPlaintext(zero, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+// checkResidue fails the test unless ct decrypts to want mod N
+func checkResidue(t testing.TB, sk *PrivateKey, ct *big.Int, want *big.Int, what string) {
+	t.Helper()
+	got, err := sk.decrypt(ct)
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+	want = new(big.Int).Mod(want, sk.Pk.N)
+	if got.Cmp(want) != 0 {
+		t.Errorf("%s decrypts to %v, want %v", what, got, want)
+	}
+}
+
+func TestHomomorphisms(t *testing.T) {
+	key := testKey(t, "family 22")
+	pk := key.Pk
+	values := []int64{0, 1, 2, 100, -1, -100, 1 << 40, math.MaxInt64, math.MinInt64}
+	for _, a := range values {
+		for _, b := range values {
+			ctA, ctB := encryptResidue(t, pk, a), encryptResidue(t, pk, b)
+			bigA, bigB := big.NewInt(a), big.NewInt(b)
+
+			sum, err := pk.Add(ctA, ctB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResidue(t, key, sum, new(big.Int).Add(bigA, bigB), "Add")
+
+			difference, err := pk.Sub(ctA, ctB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResidue(t, key, difference, new(big.Int).Sub(bigA, bigB), "Sub")
+
+			product, err := pk.MultPlaintext(ctA, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResidue(t, key, product, new(big.Int).Mul(bigA, bigB), "MultPlaintext")
+
+			signedSum, err := pk.AddSignedPlaintext(ctA, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkResidue(t, key, signedSum, new(big.Int).Add(bigA, bigB), "AddSignedPlaintext")
+
+			if b >= 0 {
+				plainSum, err := pk.AddPlaintext(ctA, b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkResidue(t, key, plainSum, new(big.Int).Add(bigA, bigB), "AddPlaintext")
+			} else if _, err := pk.AddPlaintext(ctA, b); err == nil {
+				t.Errorf("AddPlaintext accepted the negative plaintext %d", b)
+			}
+
+			if b != 0 {
+				quotient, err := pk.DivPlaintext(product, b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkResidue(t, key, quotient, bigA, "DivPlaintext of a multiple")
+			}
+		}
+	}
+
+	// N - 1 is the largest plaintext, and adding one to it wraps around to zero
+	top := encryptResidue(t, pk, -1)
+	checkResidue(t, key, top, new(big.Int).Sub(pk.N, one), "Enc(N-1)")
+	wrapped, err := pk.AddPlaintext(top, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := key.Decrypt(wrapped); err != nil || got != 0 {
+		t.Errorf("Dec(Enc(N-1) + 1) = %d, %v", got, err)
+	}
+	if got, err := key.DecryptInRange(top, -10); err != nil || got != -1 {
+		t.Errorf("DecryptInRange(Enc(N-1), -10) = %d, %v", got, err)
+	}
+}
+
+func TestBatchAdd(t *testing.T) {
+	key := testKey(t, "family 22")
+	for _, count := range []int{0, 1, 5, 6, 12} {
+		cts := make([]*big.Int, count)
+		want := big.NewInt(0)
+		for i := range cts {
+			m := int64(i*i) - 7
+			cts[i] = encryptResidue(t, key.Pk, m)
+			want.Add(want, big.NewInt(m))
+		}
+		sum, err := key.Pk.BatchAdd(cts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == 0 {
+			// The empty sum is the ciphertext 1, an encryption of zero with r = 1
+			if sum.Cmp(one) != 0 {
+				t.Errorf("BatchAdd() = %v, want 1", sum)
+			}
+			continue
+		}
+		checkResidue(t, key, sum, want, "BatchAdd")
+	}
+}
+
+func TestDivPlaintextNotInvertible(t *testing.T) {
+	key := testKey(t, "family 22")
+	ct := encryptResidue(t, key.Pk, 10)
+	if _, err := key.Pk.DivPlaintext(ct, 0); err == nil {
+		t.Error("DivPlaintext divided by zero")
+	}
+}
+
+// Every operation rejects values that are not in Z*_{N²}, the range of the ciphertexts
+// under the key
+func TestInvalidCiphertexts(t *testing.T) {
+	key := testKey(t, "family 22")
+	pk := key.Pk
+	valid := encryptResidue(t, pk, 1)
+	invalid := map[string]*big.Int{
+		"nil":                  nil,
+		"zero":                 big.NewInt(0),
+		"negative":             big.NewInt(-5),
+		"N squared":            new(big.Int).Set(pk.N2),
+		"above N squared":      new(big.Int).Add(pk.N2, one),
+		"shares a factor of N": new(big.Int).Mul(pk.N, big.NewInt(3)),
+	}
+	operations := map[string]func(ct *big.Int) error{
+		"Add left":           func(ct *big.Int) error { _, err := pk.Add(ct, valid); return err },
+		"Add right":          func(ct *big.Int) error { _, err := pk.Add(valid, ct); return err },
+		"Sub left":           func(ct *big.Int) error { _, err := pk.Sub(ct, valid); return err },
+		"Sub right":          func(ct *big.Int) error { _, err := pk.Sub(valid, ct); return err },
+		"MultPlaintext":      func(ct *big.Int) error { _, err := pk.MultPlaintext(ct, 2); return err },
+		"AddPlaintext":       func(ct *big.Int) error { _, err := pk.AddPlaintext(ct, 2); return err },
+		"AddSignedPlaintext": func(ct *big.Int) error { _, err := pk.AddSignedPlaintext(ct, -2); return err },
+		"DivPlaintext":       func(ct *big.Int) error { _, err := pk.DivPlaintext(ct, 2); return err },
+		"BatchAdd":           func(ct *big.Int) error { _, err := pk.BatchAdd(valid, ct); return err },
+		"Decrypt":            func(ct *big.Int) error { _, err := key.Decrypt(ct); return err },
+		"DecryptInRange":     func(ct *big.Int) error { _, err := key.DecryptInRange(ct, -10); return err },
+	}
+	for name, ct := range invalid {
+		for operation, f := range operations {
+			if err := f(ct); err == nil {
+				t.Errorf("%s accepted the %s ciphertext", operation, name)
+			}
+		}
+	}
+}
+
+// fuzzFamilies are the families whose keys seed the fuzz corpora
+var fuzzFamilies = []uint8{22, 7, 255}
+
+// fuzzKeys caches the keys of fuzzKey, which every fuzz input would otherwise generate again
+var fuzzKeys = map[uint8]*PrivateKey{}
+
+// fuzzKey returns the test key of a fuzzed family, so the fuzzer varies the modulus as
+// well as the plaintexts
+func fuzzKey(t testing.TB, family uint8) *PrivateKey {
+	t.Helper()
+	key, ok := fuzzKeys[family]
+	if !ok {
+		key = testKey(t, fmt.Sprintf("family %d", family))
+		fuzzKeys[family] = key
+	}
+	return key
+}
+
+func FuzzAdd(f *testing.F) {
+	for _, family := range fuzzFamilies {
+		for _, seed := range [][2]int64{{0, 0}, {1, 2}, {-1, 1}, {math.MaxInt64, math.MaxInt64}, {math.MinInt64, -1}} {
+			f.Add(family, seed[0], seed[1])
+		}
+	}
+	f.Fuzz(func(t *testing.T, family uint8, a int64, b int64) {
+		key := fuzzKey(t, family)
+		sum, err := key.Pk.Add(encryptResidue(t, key.Pk, a), encryptResidue(t, key.Pk, b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResidue(t, key, sum, new(big.Int).Add(big.NewInt(a), big.NewInt(b)), "Add")
+	})
+}
+
+func FuzzSub(f *testing.F) {
+	for _, family := range fuzzFamilies {
+		for _, seed := range [][2]int64{{0, 0}, {2, 1}, {1, 2}, {0, -1}, {math.MinInt64, math.MaxInt64}} {
+			f.Add(family, seed[0], seed[1])
+		}
+	}
+	f.Fuzz(func(t *testing.T, family uint8, a int64, b int64) {
+		key := fuzzKey(t, family)
+		difference, err := key.Pk.Sub(encryptResidue(t, key.Pk, a), encryptResidue(t, key.Pk, b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResidue(t, key, difference, new(big.Int).Sub(big.NewInt(a), big.NewInt(b)), "Sub")
+	})
+}
+
+func FuzzMultPlaintext(f *testing.F) {
+	for _, family := range fuzzFamilies {
+		for _, seed := range [][2]int64{{0, 5}, {3, 0}, {7, -1}, {-2, -3}, {math.MaxInt64, 2}} {
+			f.Add(family, seed[0], seed[1])
+		}
+	}
+	f.Fuzz(func(t *testing.T, family uint8, a int64, b int64) {
+		key := fuzzKey(t, family)
+		product, err := key.Pk.MultPlaintext(encryptResidue(t, key.Pk, a), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResidue(t, key, product, new(big.Int).Mul(big.NewInt(a), big.NewInt(b)), "MultPlaintext")
+	})
+}
+
+// Decrypting arbitrary bytes never panics, and values outside Z*_{N²} are rejected
+func FuzzDecrypt(f *testing.F) {
+	for _, family := range fuzzFamilies {
+		key := fuzzKey(f, family)
+		f.Add(family, []byte{})
+		f.Add(family, []byte{1})
+		f.Add(family, key.Pk.N.Bytes())
+		f.Add(family, key.Pk.N2.Bytes())
+		f.Add(family, encryptResidue(f, key.Pk, 42).Bytes())
+	}
+	f.Fuzz(func(t *testing.T, family uint8, data []byte) {
+		key := fuzzKey(t, family)
+		ct := new(big.Int).SetBytes(data)
+		_, err := key.DecryptInRange(ct, 0)
+		if err == nil && !key.Pk.isCiphertext(ct) {
+			t.Errorf("%x was decrypted although it is not a ciphertext", data)
+		}
+	})
+}
+
+// Parsing a public key from hex never panics, and the keys it returns survive the round
+// trip through the key encoding
+func FuzzNewPublicKey(f *testing.F) {
+	for _, family := range fuzzFamilies {
+		n, g := fuzzKey(f, family).Pk.ToString()
+		f.Add(n, g)
+		f.Add(n, "0")
+		f.Add("-"+n, g)
+		f.Add(n, "zz")
+	}
+	f.Add("", "")
+	f.Add("0", "1")
+	f.Fuzz(func(t *testing.T, n string, g string) {
+		pk, err := NewPublicKey(n, g)
+		if err != nil {
+			return
+		}
+		if pk.N.Cmp(one) != 1 || pk.G.Sign() != 1 || pk.G.Cmp(pk.N2) != -1 || pk.N2.Cmp(new(big.Int).Mul(pk.N, pk.N)) != 0 {
+			t.Fatalf("NewPublicKey(%q, %q) returned an invalid key", n, g)
+		}
+		encoded, err := pk.EncodeJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := ParsePublicKeyJSON(encoded)
+		if err != nil || decoded.N.Cmp(pk.N) != 0 || decoded.G.Cmp(pk.G) != 0 {
+			t.Errorf("NewPublicKey(%q, %q) returned a key that does not round trip: %v", n, g, err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package Pailler
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ExportFormatVersion is the version written into encrypted key files
+const ExportFormatVersion = 1
+
+// ErrWrongPassword is returned by ImportEncrypted when the password does not open the key
+var ErrWrongPassword = errors.New("wrong password or corrupted key file")
+
+// ScryptParams are the cost parameters of the password based key derivation
+type ScryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+// maxScryptMemory bounds the memory scrypt may use for a key file, 128·N·r bytes
+const maxScryptMemory = 256 << 20
+
+// DefaultScryptParams are the recommended interactive-login costs (about 100ms, 32MB)
+var DefaultScryptParams = ScryptParams{N: 1 << 15, R: 8, P: 1}
+
+// encryptedKeyFile is the on-disk form of a password protected private key. Every field
+// except the ciphertext is authenticated as additional data, so tampering with the
+// header is detected the same way as a wrong password.
+type encryptedKeyFile struct {
+	Version    int          `json:"version"`
+	Algorithm  string       `json:"alg"`
+	KDF        string       `json:"kdf"`
+	KDFParams  ScryptParams `json:"kdfParams"`
+	Salt       []byte       `json:"salt"`
+	Nonce      []byte       `json:"nonce"`
+	Ciphertext []byte       `json:"ciphertext"`
+}
+
+// ExportEncrypted seals the private key under a key derived from `password` with scrypt
+// and AES-256-GCM, and returns a versioned JSON document that can be written to disk
+func (sk *PrivateKey) ExportEncrypted(password []byte) ([]byte, error) {
+	return sk.ExportEncryptedWithParams(password, DefaultScryptParams)
+}
+
+// ExportEncryptedWithParams is ExportEncrypted with explicit scrypt costs
+func (sk *PrivateKey) ExportEncryptedWithParams(password []byte, params ScryptParams) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, fmt.Errorf("password must not be empty")
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	plaintext, err := sk.MarshalDER()
+	if err != nil {
+		return nil, err
+	}
+
+	file := encryptedKeyFile{
+		Version:   ExportFormatVersion,
+		Algorithm: Algorithm,
+		KDF:       "scrypt",
+		KDFParams: params,
+		Salt:      make([]byte, 16),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := file.aead(password)
+	if err != nil {
+		return nil, err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return nil, err
+	}
+	additionalData, err := file.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, additionalData)
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// ImportEncrypted opens a key file produced by ExportEncrypted. A wrong password or a
+// modified file yields ErrWrongPassword.
+func ImportEncrypted(data []byte, password []byte) (*PrivateKey, error) {
+	var file encryptedKeyFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key file: %v", err)
+	}
+	if file.Version != ExportFormatVersion {
+		return nil, fmt.Errorf("unsupported key file version %d", file.Version)
+	}
+	if file.Algorithm != Algorithm {
+		return nil, fmt.Errorf("unsupported key algorithm %q", file.Algorithm)
+	}
+	if file.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation %q", file.KDF)
+	}
+	if err := file.KDFParams.validate(); err != nil {
+		return nil, err
+	}
+	if len(file.Salt) < 16 {
+		return nil, fmt.Errorf("invalid key file salt")
+	}
+
+	aead, err := file.aead(password)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid key file nonce")
+	}
+	additionalData, err := file.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return ParsePrivateKeyDER(plaintext)
+}
+
+// aead derives the file key from the password and returns the cipher
+func (file *encryptedKeyFile) aead(password []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(password, file.Salt, file.KDFParams.N, file.KDFParams.R, file.KDFParams.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	return newGCM(key)
+}
+
+// additionalData is the header of the file without the ciphertext
+func (file *encryptedKeyFile) additionalData() ([]byte, error) {
+	header := *file
+	header.Ciphertext = nil
+	return json.Marshal(header)
+}
+
+// validate bounds the costs so that a crafted file cannot exhaust memory on import
+func (params ScryptParams) validate() error {
+	if params.N < 1<<14 || params.N > 1<<20 || params.N&(params.N-1) != 0 {
+		return fmt.Errorf("scrypt N must be a power of two between 2^14 and 2^20")
+	}
+	if params.R < 1 || params.R > 32 || params.P < 1 || params.P > 16 {
+		return fmt.Errorf("scrypt r or p out of range")
+	}
+	if 128*params.N*params.R > maxScryptMemory {
+		return fmt.Errorf("scrypt N and r need more than %d MiB", maxScryptMemory>>20)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package Pailler
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testScryptParams are the cheapest costs validate accepts, to keep the tests fast
+var testScryptParams = ScryptParams{N: 1 << 14, R: 1, P: 1}
+
+func TestExportEncrypted(t *testing.T) {
+	key := testKey(t, "family 22")
+	exported, err := key.ExportEncryptedWithParams([]byte("correct horse"), testScryptParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportEncrypted(exported, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Pk.Fingerprint() != key.Pk.Fingerprint() || imported.Lambda.Cmp(key.Lambda) != 0 || imported.Mu.Cmp(key.Mu) != 0 {
+		t.Error("the imported key differs from the exported one")
+	}
+
+	if _, err := ImportEncrypted(exported, []byte("wrong horse")); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("wrong password: got %v, want ErrWrongPassword", err)
+	}
+	if _, err := key.ExportEncryptedWithParams(nil, testScryptParams); err == nil {
+		t.Error("exported under an empty password")
+	}
+}
+
+// A file whose ciphertext or authenticated header was changed does not open
+func TestImportEncryptedTampered(t *testing.T) {
+	key := testKey(t, "family 22")
+	exported, err := key.ExportEncryptedWithParams([]byte("correct horse"), testScryptParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		tamper func(file *encryptedKeyFile)
+	}{
+		{name: "ciphertext", tamper: func(file *encryptedKeyFile) { file.Ciphertext[0] ^= 1 }},
+		{name: "salt", tamper: func(file *encryptedKeyFile) { file.Salt[0] ^= 1 }},
+		{name: "nonce", tamper: func(file *encryptedKeyFile) { file.Nonce[0] ^= 1 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var file encryptedKeyFile
+			if err := json.Unmarshal(exported, &file); err != nil {
+				t.Fatal(err)
+			}
+			test.tamper(&file)
+			tampered, err := json.Marshal(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ImportEncrypted(tampered, []byte("correct horse")); !errors.Is(err, ErrWrongPassword) {
+				t.Errorf("got %v, want ErrWrongPassword", err)
+			}
+		})
+	}
+}
+
+// Costs that would let a crafted file exhaust memory or CPU are refused before scrypt runs
+func TestScryptParamsValidate(t *testing.T) {
+	tests := []struct {
+		params ScryptParams
+		want   string
+	}{
+		{params: DefaultScryptParams},
+		{params: ScryptParams{N: 1 << 20, R: 2, P: 1}},
+		{params: ScryptParams{N: 1 << 20, R: 4, P: 1}, want: "MiB"},
+		{params: ScryptParams{N: 1 << 17, R: 32, P: 1}, want: "MiB"},
+		{params: ScryptParams{N: 1 << 20, R: 32, P: 16}, want: "MiB"},
+		{params: ScryptParams{N: 1 << 21, R: 1, P: 1}, want: "power of two"},
+		{params: ScryptParams{N: 3 << 14, R: 1, P: 1}, want: "power of two"},
+		{params: ScryptParams{N: 1 << 14, R: 33, P: 1}, want: "out of range"},
+		{params: ScryptParams{N: 1 << 14, R: 1, P: 17}, want: "out of range"},
+	}
+	key := testKey(t, "family 22")
+	for _, test := range tests {
+		err := test.params.validate()
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%+v: %v", test.params, err)
+		case test.want != "" && (err == nil || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("%+v: got %v, want an error with %q", test.params, err, test.want)
+		}
+		if test.want == "" {
+			continue
+		}
+
+		// A file that claims the costs is refused on import too
+		exported, err := key.ExportEncryptedWithParams([]byte("correct horse"), testScryptParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file encryptedKeyFile
+		if err := json.Unmarshal(exported, &file); err != nil {
+			t.Fatal(err)
+		}
+		file.KDFParams = test.params
+		crafted, err := json.Marshal(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ImportEncrypted(crafted, []byte("correct horse")); err == nil || errors.Is(err, ErrWrongPassword) {
+			t.Errorf("%+v: import got %v, want the costs refused", test.params, err)
+		}
+	}
+}
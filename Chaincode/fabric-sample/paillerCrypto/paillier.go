@@ -1,9 +1,12 @@
 package Pailler
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"math/big"
-	"math/rand"
 )
 
 // PublicKey is used to perform encryption and homomorphic operations
@@ -32,8 +35,9 @@ func isPrime(number int) bool {
 	return true
 }
 
-// GenerateKeyPair returns a Paillier key pair such that the squared modulus `N2`, in the
-// public key, has a bit length equivalent to the value informed in the `bitlen` parameter
+// GenerateKeyPair returns the Paillier key pair of a family. Its primes are the first two
+// primes from `patientFamilyID` on, so the key is the same on every peer but far too small
+// to keep the records secret; GenerateKeyPairFromSeed makes keys of a real size.
 func GenerateKeyPair(patientFamilyID int) (*PublicKey, *PrivateKey, error) {
 
 	nextPrimeNumber := patientFamilyID
@@ -80,7 +84,89 @@ func GenerateKeyPair(patientFamilyID int) (*PublicKey, *PrivateKey, error) {
 	return pk, sk, nil
 }
 
-// NewPublicKey creates a public key with the parameters
+// GenerateKeyPairFromSeed returns a Paillier key pair whose modulus `N` has a bit
+// length of `bitlen`. The primes are derived deterministically from `seed`, so every
+// endorsing peer that receives the same seed produces the same key pair.
+func GenerateKeyPairFromSeed(seed []byte, bitlen int) (*PublicKey, *PrivateKey, error) {
+	if len(seed) < 32 {
+		return nil, nil, fmt.Errorf("seed must be at least 32 bytes")
+	}
+	if bitlen < 64 || bitlen%16 != 0 {
+		return nil, nil, fmt.Errorf("invalid bit length %d", bitlen)
+	}
+
+	stream := &seedStream{seed: seed}
+	p := seededPrime(stream, bitlen/2)
+	q := seededPrime(stream, bitlen/2)
+	for p.Cmp(q) == 0 {
+		q = seededPrime(stream, bitlen/2)
+	}
+
+	n := new(big.Int).Mul(p, q)
+	nn := new(big.Int).Mul(n, n)
+
+	lambda := phi(p, q)
+	mu := new(big.Int).ModInverse(lambda, n)
+	if mu == nil {
+		return nil, nil, fmt.Errorf("seed produced a degenerate modulus")
+	}
+	g := new(big.Int).Add(n, one)
+
+	pk := &PublicKey{
+		N:  n,
+		G:  g,
+		N2: nn,
+	}
+
+	sk := &PrivateKey{
+		Mu:     mu,
+		Lambda: lambda,
+		Pk:     pk,
+	}
+
+	return pk, sk, nil
+}
+
+// seedStream expands a seed into an endless byte stream by hashing the seed with a counter
+type seedStream struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (s *seedStream) Read(out []byte) (int, error) {
+	for len(s.buf) < len(out) {
+		var ctr [8]byte
+		binary.BigEndian.PutUint64(ctr[:], s.counter)
+		s.counter++
+		block := sha256.Sum256(append(append([]byte{}, s.seed...), ctr[:]...))
+		s.buf = append(s.buf, block[:]...)
+	}
+	n := copy(out, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// seededPrime reads candidates of `bits` length from the stream and returns the first
+// probable prime at or above one of them
+func seededPrime(stream *seedStream, bits int) *big.Int {
+	buf := make([]byte, (bits+7)/8)
+	for {
+		_, _ = stream.Read(buf)
+		candidate := new(big.Int).SetBytes(buf)
+		candidate.SetBit(candidate, bits-1, 1)
+		candidate.SetBit(candidate, bits-2, 1)
+		candidate.SetBit(candidate, 0, 1)
+		for i := 0; i < 1000 && candidate.BitLen() == bits; i++ {
+			if candidate.ProbablyPrime(20) {
+				return candidate
+			}
+			candidate.Add(candidate, big.NewInt(2))
+		}
+	}
+}
+
+// NewPublicKey creates a public key from the hexadecimal parameters ToString exports
 func NewPublicKey(N, g string) (*PublicKey, error) {
 	n, ok := new(big.Int).SetString(N, 16)
 	if !ok {
@@ -88,13 +174,9 @@ func NewPublicKey(N, g string) (*PublicKey, error) {
 	}
 	g2, ok2 := new(big.Int).SetString(g, 16)
 	if !ok2 {
-		return nil, fmt.Errorf("Invalid value for the modulus N")
+		return nil, fmt.Errorf("Invalid value for the generator g")
 	}
-	return &PublicKey{
-		N:  n,
-		G:  g2,
-		N2: new(big.Int).Mul(n, n),
-	}, nil
+	return newCheckedPublicKey(n, g2)
 }
 
 // ToString exports the public key values to hexadecimal strings
@@ -102,38 +184,89 @@ func (pk *PublicKey) ToString() (string, string) {
 	return pk.N.Text(16), pk.G.Text(16)
 }
 
-// Encrypt returns a IND-CPA secure ciphertext for the message `msg`
-func (pk *PublicKey) Encrypt(msg int64, patientNationalID int64) (*big.Int, error) {
+// Encrypt returns a IND-CPA secure ciphertext for the message `msg`. The randomness is
+// fresh for every call, so encrypting the same message twice gives two ciphertexts.
+func (pk *PublicKey) Encrypt(msg int64) (*big.Int, error) {
+	return pk.EncryptFrom(rand.Reader, msg)
+}
+
+// EncryptFrom encrypts `msg` as Encrypt does, drawing the randomness from `random`.
+// The ciphertext is as secret as the stream is unpredictable; chaincode uses it to give
+// every endorsing peer the same ciphertext from a stream derived from a shared secret.
+func (pk *PublicKey) EncryptFrom(random io.Reader, msg int64) (*big.Int, error) {
+	r, err := getRandom(random, pk.N)
+	if err != nil {
+		return nil, err
+	}
+	return pk.EncryptWithR(msg, r)
+}
+
+// EncryptWithR returns the ciphertext g^msg * r^N mod N² for a caller chosen `r`, with
+// 0 < r < N and gcd(r, N) = 1. It makes encryption reproducible across implementations;
+// anything else should use Encrypt.
+func (pk *PublicKey) EncryptWithR(msg int64, r *big.Int) (*big.Int, error) {
 	m := new(big.Int).SetInt64(msg)
 
 	if msg < 0 || m.Cmp(zero) == -1 || m.Cmp(pk.N) != -1 {
 		return nil, fmt.Errorf("invalid plaintext")
 	}
+	if r == nil || r.Sign() != 1 || r.Cmp(pk.N) != -1 || new(big.Int).GCD(nil, nil, r, pk.N).Cmp(one) != 0 {
+		return nil, fmt.Errorf("invalid randomness")
+	}
 
-	r := getRandom(pk.N, patientNationalID)
-	r.Exp(r, pk.N, pk.N2)
+	rn := new(big.Int).Exp(r, pk.N, pk.N2)
 
 	m.Exp(pk.G, m, pk.N2)
 
-	c := new(big.Int).Mul(m, r)
+	c := new(big.Int).Mul(m, rn)
 	return c.Mod(c, pk.N2), nil
 }
 
 // Decrypt returns the plaintext corresponding to the ciphertext (ct)
-// passed in the parameter
+// passed in the parameter. Plaintexts that do not fit an int64 are an error.
 func (sk *PrivateKey) Decrypt(ct *big.Int) (int64, error) {
-	if ct == nil || ct.Cmp(zero) != 1 {
-		return 0, fmt.Errorf("invalid ciphertext")
+	m, err := sk.decrypt(ct)
+	if err != nil {
+		return 0, err
+	}
+	if !m.IsInt64() {
+		return 0, fmt.Errorf("plaintext does not fit in 64 bits")
 	}
 
-	// m = L(c^lambda mod n^2)*mu mod n
-	// where L(x) = (x-1)/n
-	m := L(new(big.Int).Exp(ct, sk.Lambda, sk.Pk.N2), sk.Pk.N)
-	m.Mul(m, sk.Mu)
+	return m.Int64(), nil
+
+}
+
+// DecryptInRange returns the plaintext of a ciphertext whose message is known to lie in
+// [min, min + N). Plaintexts are residues mod N, so this is how a negative message,
+// encrypted as N - |m|, is read back.
+func (sk *PrivateKey) DecryptInRange(ct *big.Int, min int64) (int64, error) {
+	m, err := sk.decrypt(ct)
+	if err != nil {
+		return 0, err
+	}
+	lower := new(big.Int).SetInt64(min)
+	m.Sub(m, lower)
 	m.Mod(m, sk.Pk.N)
+	m.Add(m, lower)
+	if !m.IsInt64() {
+		return 0, fmt.Errorf("plaintext does not fit in 64 bits")
+	}
 
 	return m.Int64(), nil
+}
+
+// decrypt returns the plaintext residue mod N of the ciphertext
+func (sk *PrivateKey) decrypt(ct *big.Int) (*big.Int, error) {
+	if !sk.Pk.isCiphertext(ct) {
+		return nil, fmt.Errorf("invalid ciphertext")
+	}
 
+	// m = L(c^lambda mod n^2)*mu mod n
+	// where L(x) = (x-1)/n
+	m := L(new(big.Int).Exp(ct, sk.Lambda, sk.Pk.N2), sk.Pk.N)
+	m.Mul(m, sk.Mu)
+	return m.Mod(m, sk.Pk.N), nil
 }
 
 // L (x,n) = (x-1)/n is the largest integer quocient `q` to satisfy (x-1) >= q*n
@@ -141,17 +274,18 @@ func L(x, n *big.Int) *big.Int {
 	return new(big.Int).Div(new(big.Int).Sub(x, one), n)
 }
 
-// getRandom generates a random Int `r` such that `r < n` and `gcd(r,n) = 1`
-func getRandom(n *big.Int, patientNationalID int64) *big.Int {
-	rand.Seed(patientNationalID)
+// getRandom draws an Int `r` from `random` such that `0 < r < n` and `gcd(r,n) = 1`
+func getRandom(random io.Reader, n *big.Int) (*big.Int, error) {
 	gcd := new(big.Int)
-	r := new(big.Int)
-
-	for gcd.Cmp(one) != 0 {
-		r = big.NewInt(rand.Int63())
-		gcd = new(big.Int).GCD(nil, nil, r, n)
+	for {
+		r, err := rand.Int(random, n)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read randomness: %v", err)
+		}
+		if r.Sign() == 1 && gcd.GCD(nil, nil, r, n).Cmp(one) == 0 {
+			return r, nil
+		}
 	}
-	return r
 }
 
 // Computes Carmichael's function on `n`, `λ(n) = lcm(p-1, q-1)`
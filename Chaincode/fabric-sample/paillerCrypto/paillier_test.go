@@ -0,0 +1,127 @@
+package Pailler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// testKey derives a small key pair from a name, large enough for the test plaintexts
+func testKey(t testing.TB, name string) *PrivateKey {
+	t.Helper()
+	seed := sha256.Sum256([]byte(name))
+	_, privateKey, err := GenerateKeyPairFromSeed(seed[:], 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := testKey(t, "family 22")
+	for _, msg := range []int64{0, 1, 2, 100, 1 << 40} {
+		ciphertext, err := key.Pk.Encrypt(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := key.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != msg {
+			t.Errorf("Decrypt(Encrypt(%d)) = %d", msg, got)
+		}
+	}
+
+	if _, err := key.Pk.Encrypt(-1); err == nil {
+		t.Error("Encrypt accepted a negative plaintext")
+	}
+}
+
+// Each encryption draws fresh randomness, so nothing about the patient or the previous
+// ciphertext gives away which plaintext a ciphertext holds
+func TestEncryptIsRandomized(t *testing.T) {
+	key := testKey(t, "family 22")
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		ciphertext, err := key.Pk.Encrypt(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[ciphertext.Text(16)] {
+			t.Fatalf("Encrypt(1) repeated a ciphertext after %d encryptions", i)
+		}
+		seen[ciphertext.Text(16)] = true
+	}
+}
+
+// The same stream gives the same ciphertext, and the stream is all that is drawn from
+func TestEncryptFrom(t *testing.T) {
+	key := testKey(t, "family 22")
+	stream := bytes.Repeat([]byte{7}, 4096)
+	first, err := key.Pk.EncryptFrom(bytes.NewReader(stream), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := key.Pk.EncryptFrom(bytes.NewReader(stream), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Cmp(second) != 0 {
+		t.Error("the same stream gave two ciphertexts")
+	}
+	if got, err := key.Decrypt(first); err != nil || got != 1 {
+		t.Errorf("Decrypt = %d (%v), want 1", got, err)
+	}
+	other, err := key.Pk.EncryptFrom(bytes.NewReader(bytes.Repeat([]byte{8}, 4096)), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Cmp(first) == 0 {
+		t.Error("two streams gave the same ciphertext")
+	}
+	if _, err := key.Pk.EncryptFrom(bytes.NewReader(nil), 1); err == nil {
+		t.Error("an exhausted stream gave a ciphertext")
+	}
+}
+
+// The family key is the same on every call, and a real if tiny Paillier key
+func TestGenerateKeyPair(t *testing.T) {
+	pk, sk, err := GenerateKeyPair(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.N.Int64() != 23*29 {
+		t.Errorf("N = %v, want the product of 23 and 29", pk.N)
+	}
+	other, _, err := GenerateKeyPair(22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.N.Cmp(pk.N) != 0 {
+		t.Error("the same family ID generated two keys")
+	}
+	ciphertext, err := pk.Encrypt(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sk.Decrypt(ciphertext); err != nil || got != 1 {
+		t.Errorf("Decrypt = %d (%v), want 1", got, err)
+	}
+}
+
+func TestGenerateKeyPairFromSeed(t *testing.T) {
+	first, second := testKey(t, "family 22"), testKey(t, "family 22")
+	if first.Pk.N.Cmp(second.Pk.N) != 0 {
+		t.Error("the same seed generated two keys")
+	}
+	if other := testKey(t, "family 21"); other.Pk.N.Cmp(first.Pk.N) == 0 {
+		t.Error("two seeds generated the same key")
+	}
+	if bits := first.Pk.N.BitLen(); bits != 256 {
+		t.Errorf("modulus has %d bits, want 256", bits)
+	}
+	if _, _, err := GenerateKeyPairFromSeed([]byte("short"), 256); err == nil {
+		t.Error("a seed shorter than 32 bytes was accepted")
+	}
+}
@@ -0,0 +1,170 @@
+package Pailler
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+)
+
+// vectorFile is the test vector file shared with the Python demo, written by
+// cmd/paillier-vectors
+const vectorFile = "../../../Paillier Example/testvectors.json"
+
+// testVectors mirrors the format of cmd/paillier-vectors
+type testVectors struct {
+	Keys []struct {
+		Name   string   `json:"name"`
+		P      *big.Int `json:"p"`
+		Q      *big.Int `json:"q"`
+		N      *big.Int `json:"n"`
+		G      *big.Int `json:"g"`
+		Lambda *big.Int `json:"lambda"`
+		Mu     *big.Int `json:"mu"`
+	} `json:"keys"`
+	Encryptions []struct {
+		Key        string   `json:"key"`
+		Plaintext  int64    `json:"plaintext"`
+		R          *big.Int `json:"r"`
+		Ciphertext *big.Int `json:"ciphertext"`
+	} `json:"encryptions"`
+	Homomorphic []struct {
+		Key        string   `json:"key"`
+		Operation  string   `json:"operation"`
+		A          int64    `json:"a"`
+		RA         *big.Int `json:"rA"`
+		B          int64    `json:"b"`
+		RB         *big.Int `json:"rB"`
+		Ciphertext *big.Int `json:"ciphertext"`
+		Plaintext  int64    `json:"plaintext"`
+	} `json:"homomorphic"`
+	Pedigrees []struct {
+		Key       string   `json:"key"`
+		Weight    int64    `json:"weight"`
+		R0        *big.Int `json:"r0"`
+		Relatives []struct {
+			Level    int64    `json:"level"`
+			Affected int64    `json:"affected"`
+			R        *big.Int `json:"r"`
+		} `json:"relatives"`
+		Ciphertext *big.Int `json:"ciphertext"`
+		Risk       int64    `json:"risk"`
+	} `json:"pedigrees"`
+}
+
+// vectorKey rebuilds a key of the vector file from its primes: the chaincode key the way
+// GenerateKeyPairFromSeed builds it, and the demo key with λ = lcm(p-1, q-1) and its g
+func vectorKey(t *testing.T, name string, p, q, g *big.Int) *PrivateKey {
+	t.Helper()
+	n := new(big.Int).Mul(p, q)
+	nn := new(big.Int).Mul(n, n)
+	pk := &PublicKey{N: n, G: new(big.Int).Add(n, one), N2: nn}
+	l := phi(p, q)
+	if name != "chaincode" {
+		pk.G = g
+		l = lambda(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+	}
+	mu := new(big.Int).ModInverse(L(new(big.Int).Exp(pk.G, l, nn), n), n)
+	if mu == nil {
+		t.Fatalf("key %s: g = %v is not a valid generator", name, pk.G)
+	}
+	return &PrivateKey{Mu: mu, Lambda: l, Pk: pk}
+}
+
+// The library reproduces every ciphertext and result of the vectors the Python demo checks
+func TestVectors(t *testing.T) {
+	data, err := os.ReadFile(vectorFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors testVectors
+	err = json.Unmarshal(data, &vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string]*PrivateKey{}
+	for _, vector := range vectors.Keys {
+		key := vectorKey(t, vector.Name, vector.P, vector.Q, vector.G)
+		if key.Pk.N.Cmp(vector.N) != 0 || key.Pk.G.Cmp(vector.G) != 0 || key.Lambda.Cmp(vector.Lambda) != 0 || key.Mu.Cmp(vector.Mu) != 0 {
+			t.Errorf("key %s: got n=%v g=%v λ=%v μ=%v, want n=%v g=%v λ=%v μ=%v", vector.Name,
+				key.Pk.N, key.Pk.G, key.Lambda, key.Mu, vector.N, vector.G, vector.Lambda, vector.Mu)
+		}
+		keys[vector.Name] = key
+	}
+	lookup := func(name string) *PrivateKey {
+		t.Helper()
+		key, ok := keys[name]
+		if !ok {
+			t.Fatalf("unknown key %q", name)
+		}
+		return key
+	}
+	encrypt := func(key *PrivateKey, msg int64, r *big.Int) *big.Int {
+		t.Helper()
+		ct, err := key.Pk.EncryptWithR(msg, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ct
+	}
+
+	for i, vector := range vectors.Encryptions {
+		key := lookup(vector.Key)
+		if ct := encrypt(key, vector.Plaintext, vector.R); ct.Cmp(vector.Ciphertext) != 0 {
+			t.Errorf("encryption %d: Enc(%d, %v) = %v, want %v", i, vector.Plaintext, vector.R, ct, vector.Ciphertext)
+		}
+		if got, err := key.Decrypt(vector.Ciphertext); err != nil || got != vector.Plaintext {
+			t.Errorf("decryption %d: got %d (%v), want %d", i, got, err, vector.Plaintext)
+		}
+	}
+
+	for i, vector := range vectors.Homomorphic {
+		key := lookup(vector.Key)
+		a := encrypt(key, vector.A, vector.RA)
+		var ct *big.Int
+		switch vector.Operation {
+		case "add":
+			ct, err = key.Pk.Add(a, encrypt(key, vector.B, vector.RB))
+		case "sub":
+			ct, err = key.Pk.Sub(a, encrypt(key, vector.B, vector.RB))
+		case "multPlaintext":
+			ct, err = key.Pk.MultPlaintext(a, vector.B)
+		case "addPlaintext":
+			ct, err = key.Pk.AddPlaintext(a, vector.B)
+		default:
+			t.Fatalf("%d: unknown operation %q", i, vector.Operation)
+		}
+		if err != nil {
+			t.Fatalf("%s %d: %v", vector.Operation, i, err)
+		}
+		if ct.Cmp(vector.Ciphertext) != 0 {
+			t.Errorf("%s %d: got %v, want %v", vector.Operation, i, ct, vector.Ciphertext)
+		}
+		if got, err := key.Decrypt(ct); err != nil || got != vector.Plaintext {
+			t.Errorf("%s %d: decrypts to %d (%v), want %d", vector.Operation, i, got, err, vector.Plaintext)
+		}
+	}
+
+	// The family risk the chaincode computes: Enc(0) plus each relative's flag times weight/level
+	for i, pedigree := range vectors.Pedigrees {
+		key := lookup(pedigree.Key)
+		risk := encrypt(key, 0, pedigree.R0)
+		for _, relative := range pedigree.Relatives {
+			contribution, err := key.Pk.MultPlaintext(encrypt(key, relative.Affected, relative.R), pedigree.Weight/relative.Level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			risk, err = key.Pk.Add(risk, contribution)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if risk.Cmp(pedigree.Ciphertext) != 0 {
+			t.Errorf("pedigree %d: got %v, want %v", i, risk, pedigree.Ciphertext)
+		}
+		if got, err := key.Decrypt(risk); err != nil || got != pedigree.Risk {
+			t.Errorf("pedigree %d: risk %d (%v), want %d", i, got, err, pedigree.Risk)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// keyScopePatient marks a patient whose disease table is encrypted under their own key.
+// Records without a key scope are encrypted under the family key.
+const keyScopePatient = core.KeyScopePatient
+
+// PatientKeyRecord is the public half of a patient's own key
+type PatientKeyRecord struct {
+	PatientNationalID int                `json:"patientNationalID"`
+	PatientFamilyID   int                `json:"patientFamilyID"`
+	PublicKey         *Pailler.PublicKey `json:"publicKey"`
+}
+
+// ComputeRiskForKey computes the patient's encrypted risk and returns it, as a hex
+// string, under a one-time computation key supplied by the caller. The endorsing peer
+// re-encrypts each relative's contribution from the relative's own key under the
+// computation key, so the caller never needs the family key or any relative's key; the
+// peer itself sees the contributions in the clear. Relatives that have not consented to
+// risk computation by the requesting organization are left out.
+func (s *SmartContract) ComputeRiskForKey(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndex int, computationN string, computationG string) (_ string, err error) {
+	defer catalogError(&err)
+	target, err := Pailler.NewPublicKey(computationN, computationG)
+	if err != nil {
+		return "", err
+	}
+
+	patient, err := readLivePatient(ctx, patientNationalID)
+	if err != nil {
+		return "", err
+	}
+
+	diseaseProbability, err := diseaseWeight(ctx, diseaseIndex)
+	if err != nil {
+		return "", err
+	}
+	generations, err := ancestorGenerations(ctx, patient.PatientNationalID)
+	if err != nil {
+		return "", err
+	}
+
+	consent, err := consentService(ctx).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return "", err
+	}
+	result, _, err := riskService(ctx).Compute(target, patientNationalID, generations, diseaseProbability, diseaseIndex, consent)
+	if err != nil {
+		return "", err
+	}
+
+	err = core.EmitRiskComputed(newTransaction(ctx), strconv.Itoa(patient.PatientNationalID), diseaseIndex, target.Fingerprint(), result)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(16), nil
+}
+
+// getPatientKey returns the key the patient's disease table is encrypted under
+func getPatientKey(ctx contractapi.TransactionContextInterface, patient *Patient) (*Pailler.PrivateKey, error) {
+	return keyService(ctx).PatientKey(patient.toCore())
+}
+
+// diseaseWeight returns the weight applied to relatives affected by the disease
+func diseaseWeight(ctx contractapi.TransactionContextInterface, diseaseIndex int) (int, error) {
+	disease, err := diseaseService(ctx).Get(diseaseIndex)
+	if err != nil {
+		return 0, err
+	}
+	return disease.Weight, nil
+}
+
+func (record *PatientKeyRecord) toCore() *core.PatientKeyRecord {
+	return &core.PatientKeyRecord{
+		PatientNationalID: strconv.Itoa(record.PatientNationalID),
+		PatientFamilyID:   strconv.Itoa(record.PatientFamilyID),
+		PublicKey:         record.PublicKey,
+	}
+}
+
+func patientKeyRecordFromCore(record *core.PatientKeyRecord) (*PatientKeyRecord, error) {
+	nationalID, err := strconv.Atoi(record.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	familyID, err := strconv.Atoi(record.PatientFamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return &PatientKeyRecord{PatientNationalID: nationalID, PatientFamilyID: familyID, PublicKey: record.PublicKey}, nil
+}
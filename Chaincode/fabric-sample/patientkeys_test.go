@@ -0,0 +1,39 @@
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// A peer without the key wrap secret, or with another one, cannot open patient keys.
+// Without one it cannot wrap a new patient key either; with another one it wraps a key
+// that the peers holding the right secret cannot open.
+func TestPatientKeyWrapSecret(t *testing.T) {
+	tb := newTestbed(t)
+	patientSeed := map[string][]byte{core.KeySeedField: []byte("genchain test patient key seed, 32 bytes or more")}
+	tb.mustInvoke(patientSeed, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	tb.mustInvoke(nil, "CalculateCrossFamilyRisk", "130", "0")
+
+	tests := []struct {
+		name       string
+		secret     string
+		nationalID string
+		create     string
+	}{
+		{name: "no secret", nationalID: "131", create: "KEY_UNAVAILABLE"},
+		{name: "short secret", secret: "short", nationalID: "132", create: "KEY_UNAVAILABLE"},
+		{name: "other secret", secret: "another key wrap secret, 32 bytes or more", nationalID: "133"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(core.KeyWrapSecretEnv, test.secret)
+			checkResult(t, tb.invoke(nil, "CalculateCrossFamilyRisk", "130", "0"), "KEY_UNAVAILABLE")
+			checkResult(t, tb.invoke(patientSeed, "CreateAsset", "Emre Kaya", test.nationalID, "22", "0", "0", "0"), test.create)
+		})
+	}
+
+	// Back on the secret 130's key was wrapped with, it opens again, and 133's does not
+	tb.mustInvoke(nil, "CalculateCrossFamilyRisk", "130", "0")
+	checkResult(t, tb.invoke(nil, "CalculateCrossFamilyRisk", "133", "0"), "KEY_UNAVAILABLE")
+}
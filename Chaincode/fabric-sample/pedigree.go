@@ -0,0 +1,110 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// maxGenerations is how far up the pedigree risk computations walk
+const maxGenerations = 2
+
+// Edge links a child to one of their parents. The two may belong to different
+// families and therefore have their records encrypted under different keys.
+type Edge struct {
+	ChildNationalID  int    `json:"childNationalID"`
+	ParentNationalID int    `json:"parentNationalID"`
+	Relation         string `json:"relation"`
+}
+
+// AddParent records that the parent is the child's father or mother
+func (s *SmartContract) AddParent(ctx contractapi.TransactionContextInterface, childNationalID string, parentNationalID string, relation string) (err error) {
+	defer catalogError(&err)
+	if relation != "father" && relation != "mother" {
+		return core.InvalidArgument("relation", "must be father or mother")
+	}
+	if childNationalID == parentNationalID {
+		return core.InvalidArgument("parentNationalID", "a patient cannot be their own parent")
+	}
+
+	child, err := readLivePatient(ctx, childNationalID)
+	if err != nil {
+		return err
+	}
+	parent, err := readLivePatient(ctx, parentNationalID)
+	if err != nil {
+		return err
+	}
+
+	edge := Edge{ChildNationalID: child.PatientNationalID, ParentNationalID: parent.PatientNationalID, Relation: relation}
+	edgeJSON, err := json.Marshal(edge)
+	if err != nil {
+		return err
+	}
+	edgeID, err := ctx.GetStub().CreateCompositeKey(core.EdgeNamespace, []string{childNationalID, parentNationalID})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(edgeID, edgeJSON)
+}
+
+// CalculateCrossFamilyRisk computes the patient's encrypted risk over their recorded
+// ancestors, whichever families they belong to, and returns it as a hex string under
+// the patient's own key. Contributions from ancestors under another key are re-encrypted
+// under the patient's key by the endorsing peer, which sees them in the clear, before
+// they are added. Relatives that have not consented to risk computation by the
+// requesting organization are left out.
+func (s *SmartContract) CalculateCrossFamilyRisk(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndex int) (_ string, err error) {
+	defer catalogError(&err)
+	patient, err := readLivePatient(ctx, patientNationalID)
+	if err != nil {
+		return "", err
+	}
+	patientKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return "", err
+	}
+	target := patientKey.Pk
+
+	diseaseProbability, err := diseaseWeight(ctx, diseaseIndex)
+	if err != nil {
+		return "", err
+	}
+	generations, err := ancestorGenerations(ctx, patient.PatientNationalID)
+	if err != nil {
+		return "", err
+	}
+
+	consent, err := consentService(ctx).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return "", err
+	}
+	result, _, err := riskService(ctx).Compute(target, patientNationalID, generations, diseaseProbability, diseaseIndex, consent)
+	if err != nil {
+		return "", err
+	}
+
+	err = core.EmitRiskComputed(newTransaction(ctx), strconv.Itoa(patient.PatientNationalID), diseaseIndex, target.Fingerprint(), result)
+	if err != nil {
+		return "", err
+	}
+	return result.Text(16), nil
+}
+
+// ancestorGenerations walks the parent edges up to maxGenerations and returns the
+// ancestors of each generation, parents first. Patients without recorded parents fall
+// back to the fixed ancestorIds pedigree.
+func ancestorGenerations(ctx contractapi.TransactionContextInterface, nationalID int) ([][]string, error) {
+	return patientService(ctx).Generations(strconv.Itoa(nationalID), maxGenerations, core.FixedGenerations(ancestorIds))
+}
+
+// readLivePatient returns the patient stored under the ID, failing for missing or erased records
+func readLivePatient(ctx contractapi.TransactionContextInterface, nationalID string) (*Patient, error) {
+	record, err := patientService(ctx).Get(nationalID)
+	if err != nil {
+		return nil, err
+	}
+	return patientFromCore(record)
+}
@@ -0,0 +1,85 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// ComputeRiskProfile computes the patient's encrypted risk of several diseases in one
+// traversal of their relatives, each risk being the one TransferAsset would compute.
+// An empty list of disease indexes selects every registered disease. Nothing is
+// decrypted: the risks are returned under the patient's key, keyed by disease index.
+func (s *SmartContract) ComputeRiskProfile(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndexes []int) (_ *RiskProfile, err error) {
+	defer catalogError(&err)
+	patient, err := readLivePatient(ctx, patientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return nil, err
+	}
+	diseases, err := profileDiseases(ctx, diseaseIndexes)
+	if err != nil {
+		return nil, err
+	}
+	generations, err := ancestorGenerations(ctx, patient.PatientNationalID)
+	if err != nil {
+		return nil, err
+	}
+	consent, err := consentService(ctx).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make([]core.DiseaseWeight, len(diseases))
+	for index, disease := range diseases {
+		weights[index] = core.DiseaseWeight{Index: disease.Index, Weight: disease.Weight}
+	}
+	risks, excludedIDs, err := riskService(ctx).ComputeProfile(privateKey.Pk, patientNationalID, generations, weights, consent)
+	if err != nil {
+		return nil, err
+	}
+	excluded := make([]int, len(excludedIDs))
+	for index, nationalID := range excludedIDs {
+		excluded[index], _ = strconv.Atoi(nationalID)
+	}
+
+	profile := &RiskProfile{
+		PatientNationalID: patient.PatientNationalID,
+		KeyFingerprint:    privateKey.Pk.Fingerprint(),
+		Diseases:          make(map[string]string, len(diseases)),
+		EncryptedRisks:    make(map[string]string, len(diseases)),
+		ExcludedRelatives: excluded,
+	}
+	for _, disease := range diseases {
+		diseaseIndex := strconv.Itoa(disease.Index)
+		profile.Diseases[diseaseIndex] = disease.Name
+		profile.EncryptedRisks[diseaseIndex] = risks[disease.Index].Text(16)
+	}
+	return profile, nil
+}
+
+// profileDiseases returns the diseases of the given indexes, or every registered disease
+// when there are none
+func profileDiseases(ctx contractapi.TransactionContextInterface, diseaseIndexes []int) ([]*core.Disease, error) {
+	if len(diseaseIndexes) == 0 {
+		return diseaseService(ctx).List()
+	}
+	diseases := make([]*core.Disease, len(diseaseIndexes))
+	seen := map[int]bool{}
+	for index, diseaseIndex := range diseaseIndexes {
+		if seen[diseaseIndex] {
+			return nil, core.InvalidArgument("diseaseIndexes", "disease %d is listed twice", diseaseIndex)
+		}
+		seen[diseaseIndex] = true
+		disease, err := diseaseService(ctx).Get(diseaseIndex)
+		if err != nil {
+			return nil, err
+		}
+		diseases[index] = disease
+	}
+	return diseases, nil
+}
@@ -0,0 +1,179 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// PatientFilter is the restricted selector accepted by QueryPatients. Empty fields do
+// not filter. HasConsent selects the patients with, or without, a valid research
+// aggregation consent for the requesting organization.
+type PatientFilter struct {
+	PatientFamilyID *int   `json:"patientFamilyID,omitempty"`
+	CreatedAfter    string `json:"createdAfter,omitempty"`
+	HasConsent      *bool  `json:"hasConsent,omitempty"`
+}
+
+// QueryPatients returns the live patients matching the filter, given as JSON, e.g.
+// {"patientFamilyID":22,"createdAfter":"2024-01-01T00:00:00Z","hasConsent":true}.
+// On a peer whose core.StateDatabaseEnv is CouchDB the filter is turned into a selector
+// served by the indexes in META-INF/statedb/couchdb/indexes; on LevelDB, which has no
+// rich queries, the same filter is applied to a range scan. Consent lives in the consent registry rather than
+// on the patient record, so the hasConsent filter is applied to the results.
+func (s *SmartContract) QueryPatients(ctx contractapi.TransactionContextInterface, filterJSON string) (_ []*PatientView, err error) {
+	defer catalogError(&err)
+	filter, err := parsePatientFilter(filterJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if !core.RichQueries() {
+		assets, err := queryPatientsByRange(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return filter.consented(ctx, assets)
+	}
+	selector, err := filter.selector()
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetQueryResult(selector)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	assets := []*PatientView{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		asset, err := unmarshalPatientView(queryResponse.Value)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	return filter.consented(ctx, assets)
+}
+
+func parsePatientFilter(filterJSON string) (*PatientFilter, error) {
+	filter := new(PatientFilter)
+	if filterJSON == "" {
+		return filter, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(filterJSON))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(filter)
+	if err != nil {
+		return nil, core.InvalidArgument("filter", "invalid patient filter: %v", err)
+	}
+	if filter.CreatedAfter != "" {
+		createdAfter, err := time.Parse(time.RFC3339, filter.CreatedAfter)
+		if err != nil {
+			return nil, core.InvalidArgument("createdAfter", "must be an RFC3339 time")
+		}
+		// Stored times are UTC without fractions, which compare correctly as strings
+		filter.CreatedAfter = createdAfter.UTC().Format(time.RFC3339)
+	}
+	return filter, nil
+}
+
+// selector builds the CouchDB query for the filter. Only the fields of the filter can
+// reach the selector, so clients cannot run arbitrary queries.
+func (filter *PatientFilter) selector() (string, error) {
+	selector := map[string]interface{}{"docType": core.PatientDocType}
+	if filter.PatientFamilyID != nil {
+		selector["patientFamilyID"] = *filter.PatientFamilyID
+	}
+	if filter.CreatedAfter != "" {
+		selector["createdAt"] = map[string]string{"$gt": filter.CreatedAfter}
+	}
+	query, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return "", err
+	}
+	return string(query), nil
+}
+
+// matches applies the filter to a patient read by a range scan
+func (filter *PatientFilter) matches(patient *Patient) bool {
+	if filter.PatientFamilyID != nil && patient.PatientFamilyID != *filter.PatientFamilyID {
+		return false
+	}
+	if filter.CreatedAfter != "" && patient.CreatedAt <= filter.CreatedAfter {
+		return false
+	}
+	return true
+}
+
+// consented applies the hasConsent filter to patients read by a query or a range scan
+func (filter *PatientFilter) consented(ctx contractapi.TransactionContextInterface, assets []*PatientView) ([]*PatientView, error) {
+	if filter.HasConsent == nil {
+		return assets, nil
+	}
+	hasConsent, err := consentService(ctx).ClientConsent(core.PurposeResearchAggregation)
+	if err != nil {
+		return nil, err
+	}
+	consented := []*PatientView{}
+	for _, asset := range assets {
+		valid, err := hasConsent(strconv.Itoa(asset.PatientNationalID))
+		if err != nil {
+			return nil, err
+		}
+		if valid == *filter.HasConsent {
+			consented = append(consented, asset)
+		}
+	}
+	return consented, nil
+}
+
+// queryPatientsByRange serves QueryPatients on LevelDB. A family filter scans only the
+// family's membership index.
+func queryPatientsByRange(ctx contractapi.TransactionContextInterface, filter *PatientFilter) ([]*PatientView, error) {
+	var patients []*Patient
+	if filter.PatientFamilyID != nil {
+		members, err := familyMembers(ctx, *filter.PatientFamilyID)
+		if err != nil {
+			return nil, err
+		}
+		patients = members
+	} else {
+		resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(core.PatientNamespace, []string{})
+		if err != nil {
+			return nil, err
+		}
+		defer resultsIterator.Close()
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				return nil, err
+			}
+			if isErased(queryResponse.Value) {
+				continue
+			}
+			patient := new(Patient)
+			err = json.Unmarshal(queryResponse.Value, patient)
+			if err != nil {
+				return nil, err
+			}
+			patients = append(patients, patient)
+		}
+	}
+
+	assets := []*PatientView{}
+	for _, patient := range patients {
+		if filter.matches(patient) {
+			assets = append(assets, newPatientView(patient))
+		}
+	}
+	return assets, nil
+}
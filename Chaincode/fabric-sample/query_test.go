@@ -0,0 +1,98 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Each field of the filter reaches the selector, except hasConsent, which is applied to
+// the results
+func TestPatientFilterSelector(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{filter: ``, want: `{"selector":{"docType":"patient"}}`},
+		{filter: `{"patientFamilyID":22}`, want: `{"selector":{"docType":"patient","patientFamilyID":22}}`},
+		{filter: `{"createdAfter":"2026-01-01T03:00:00+03:00"}`, want: `{"selector":{"createdAt":{"$gt":"2026-01-01T00:00:00Z"},"docType":"patient"}}`},
+		{filter: `{"hasConsent":true}`, want: `{"selector":{"docType":"patient"}}`},
+		{filter: `{"patientFamilyID":22,"createdAfter":"2026-01-01T00:00:00Z","hasConsent":false}`, want: `{"selector":{"createdAt":{"$gt":"2026-01-01T00:00:00Z"},"docType":"patient","patientFamilyID":22}}`},
+	}
+	for _, test := range tests {
+		filter, err := parsePatientFilter(test.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", test.filter, err)
+		}
+		selector, err := filter.selector()
+		if err != nil || selector != test.want {
+			t.Errorf("selector of %s = %s (%v), want %s", test.filter, selector, err, test.want)
+		}
+	}
+
+	for _, filter := range []string{`{"selector":{}}`, `{"patientFamilyID":"22"}`, `{"createdAfter":"yesterday"}`} {
+		if _, err := parsePatientFilter(filter); err == nil {
+			t.Errorf("filter %s was accepted", filter)
+		}
+	}
+}
+
+// queryIDs runs QueryPatients and returns the national IDs it found, in order
+func (tb *testbed) queryIDs(filter string) []int {
+	tb.t.Helper()
+	var patients []*PatientView
+	err := json.Unmarshal(tb.mustInvoke(nil, "QueryPatients", filter), &patients)
+	if err != nil {
+		tb.t.Fatal(err)
+	}
+	nationalIDs := []int{}
+	for _, patient := range patients {
+		nationalIDs = append(nationalIDs, patient.PatientNationalID)
+	}
+	sort.Ints(nationalIDs)
+	return nationalIDs
+}
+
+// QueryPatients finds the same patients with a CouchDB selector and with the range scan
+// it falls back to on LevelDB
+func TestQueryPatients(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []int
+	}{
+		{filter: ``, want: []int{111, 112, 113, 114, 115, 116, 117, 119, 120, 121, 130}},
+		{filter: `{"patientFamilyID":20}`, want: []int{111, 112, 113}},
+		{filter: `{"patientFamilyID":22}`, want: []int{115, 116, 117, 119, 120, 121, 130}},
+		{filter: `{"patientFamilyID":23}`, want: []int{}},
+		{filter: `{"createdAfter":"2026-01-01T00:00:00Z"}`, want: []int{130}},
+		{filter: `{"hasConsent":true}`, want: []int{119}},
+		{filter: `{"patientFamilyID":22,"hasConsent":false}`, want: []int{115, 116, 117, 120, 121, 130}},
+		{filter: `{"patientFamilyID":20,"createdAfter":"2026-01-01T00:00:00Z"}`, want: []int{}},
+	}
+	for _, database := range []string{"goleveldb", "CouchDB"} {
+		t.Run(database, func(t *testing.T) {
+			tb := newTestbed(t)
+			t.Setenv(core.StateDatabaseEnv, database)
+			tb.ledger.CouchDB = database == "CouchDB"
+			tb.mustInvoke(nil, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+			tb.mustInvoke(nil, "GrantConsent", "119", core.PurposeResearchAggregation, "Org1MSP", "")
+			tb.erase("ErasePatient", "118", core.ReasonSubjectRequest)
+
+			for _, test := range tests {
+				if got := tb.queryIDs(test.filter); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("QueryPatients(%s) = %v, want %v", test.filter, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+// A peer set to CouchDB reports a failed rich query instead of scanning the ledger
+func TestQueryPatientsWithoutCouchDB(t *testing.T) {
+	tb := newTestbed(t)
+	t.Setenv(core.StateDatabaseEnv, "CouchDB")
+	checkResult(t, tb.invoke(nil, "QueryPatients", `{"patientFamilyID":22}`), "not supported for leveldb")
+}
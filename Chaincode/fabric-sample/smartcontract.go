@@ -2,8 +2,9 @@ package chaincode
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
 	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
 	"math/big"
 	"strconv"
@@ -19,8 +20,18 @@ type Patient struct {
 	PatientNationalID   int         `json:"patientNationalID"`
 	PatientFamilyID     int         `json:"patientFamilyID"`
 	PatientDiseaseTable [3]*big.Int `json:"patientDiseaseTable"`
+	PatientGenotypes    [3]*big.Int `json:"patientGenotypes"`
+	PatientBirthYear    int         `json:"patientBirthYear,omitempty"`
+	KeyScope            string      `json:"keyScope,omitempty"`
+	KeyFingerprint      string      `json:"keyFingerprint,omitempty"`
+	DocType             string      `json:"docType"`
+	CreatedAt           string      `json:"createdAt,omitempty"`
+	UpdatedAt           string      `json:"updatedAt,omitempty"`
 }
 
+// ancestorIds lists the father and mother of each generation, nearest first
+var ancestorIds = []string{"115", "116", "119", "120"}
+
 type Diseases struct {
 	SickleCellDisease int `json:"sickleCellDisease"`
 	Type2Diabetes     int `json:"type2Diabetes"`
@@ -28,7 +39,8 @@ type Diseases struct {
 }
 
 // InitLedger adds a base set of assets to the ledger
-func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) error {
+func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface) (err error) {
+	defer catalogError(&err)
 
 	var zero = new(big.Int).SetInt64(0)
 	var one = new(big.Int).SetInt64(1)
@@ -47,136 +59,205 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 		{PatientName: "Hamza", PatientNationalID: 121, PatientFamilyID: 22, PatientDiseaseTable: [3]*big.Int{zero, zero, zero}},
 	}
 
+	seed, err := core.TransientSeed(newTransaction(ctx), core.FamilyKeySeedField)
+	if err != nil {
+		return err
+	}
+	// A transaction does not read its own writes, so the new family keys are kept here
+	familyKeys := map[int]*Pailler.PrivateKey{}
+
+	created := events.New(events.PatientCreated)
 	for _, asset := range patients {
-		publicKey, _, _ := Pailler.GenerateKeyPair(asset.PatientFamilyID)
-		N, g := publicKey.ToString()
-		publicKey2, _ := Pailler.NewPublicKey(N, g)
+		familyKey, ok := familyKeys[asset.PatientFamilyID]
+		if !ok {
+			familyKey, err = familyKeyService(ctx).New(strconv.Itoa(asset.PatientFamilyID), seed)
+			if err != nil {
+				return err
+			}
+			familyKeys[asset.PatientFamilyID] = familyKey
+		}
 
 		for index := range asset.PatientDiseaseTable {
-			asset.PatientDiseaseTable[index], _ = publicKey2.Encrypt(asset.PatientDiseaseTable[index].Int64(), int64(asset.PatientNationalID))
+			slot := core.DiseaseSlot(strconv.Itoa(asset.PatientNationalID), index)
+			asset.PatientDiseaseTable[index], err = encrypt(ctx, familyKey.Pk, asset.PatientDiseaseTable[index].Int64(), slot)
+			if err != nil {
+				return err
+			}
 		}
+		asset.KeyFingerprint = familyKey.Pk.Fingerprint()
 
-		assetJSON, err := json.Marshal(asset)
+		err := putPatient(ctx, &asset)
 		if err != nil {
 			return err
 		}
-
-		keyNationalID := strconv.Itoa(asset.PatientNationalID)
-		err = ctx.GetStub().PutState(keyNationalID, assetJSON)
+		err = auditService(ctx).Record(strconv.Itoa(asset.PatientNationalID), core.AuditCreate, core.NoDiseaseSlot, "")
 		if err != nil {
-			return fmt.Errorf("failed to put to world state. %v", err)
+			return err
 		}
+		core.AddPatientCiphertexts(created, asset.toCore())
 	}
 
 	disease := Diseases{SickleCellDisease: 100, Type2Diabetes: 70, Achondroplasia: 50}
-	diseaseJSON, err := json.Marshal(disease)
-	if err != nil {
-		return err
-	}
-	err = ctx.GetStub().PutState("DiseaseTable", diseaseJSON)
+	err = diseaseService(ctx).PutTable(disease.SickleCellDisease, disease.Type2Diabetes, disease.Achondroplasia)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return core.EmitEvent(newTransaction(ctx), created)
 }
 
 // ReadAsset returns the asset stored in the world state with given id.
-func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, patientNationalID string) error {
-	assetJSON, err := ctx.GetStub().GetState(patientNationalID)
+func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, patientNationalID string) (_ *PatientView, err error) {
+	defer catalogError(&err)
+	assetJSON, err := getPatientState(ctx, patientNationalID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if assetJSON == nil {
-		return err
+		return nil, core.NotFound("the asset %s does not exist", patientNationalID)
 	}
-
-	var asset Patient
-	err = json.Unmarshal(assetJSON, &asset)
-	if err != nil {
-		return err
+	if isErased(assetJSON) {
+		return nil, core.Erased("the asset %s has been erased", patientNationalID)
 	}
 
-	fmt.Println(asset)
-
-	return err
+	return unmarshalPatientView(assetJSON)
 }
 
 // AssetExists returns true when asset with given ID exists in world state
-func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
-	assetJSON, err := ctx.GetStub().GetState(id)
+func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (_ bool, err error) {
+	defer catalogError(&err)
+	assetJSON, err := getPatientState(ctx, id)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, err
 	}
 	return assetJSON != nil, nil
 }
 
-// ChangeAsset returns true when asset with given ID exists in world state
-func (s *SmartContract) ChangeAsset(ctx contractapi.TransactionContextInterface, patientNationalID int, diseaseIndex int) error {
-	patient := getPatient(ctx, patientNationalID)
+// ChangeAsset marks the patient as having the disease and records why in the audit trail
+func (s *SmartContract) ChangeAsset(ctx contractapi.TransactionContextInterface, patientNationalID int, diseaseIndex int, reason string) (err error) {
+	defer catalogError(&err)
+	err = core.ValidateDiseaseIndex(diseaseIndex)
+	if err != nil {
+		return err
+	}
+	patient, err := readLivePatient(ctx, strconv.Itoa(patientNationalID))
+	if err != nil {
+		return err
+	}
 
-	publicKey, _, _ := Pailler.GenerateKeyPair(patient.PatientFamilyID)
-	N, g := publicKey.ToString()
-	publicKey2, _ := Pailler.NewPublicKey(N, g)
+	privateKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return err
+	}
 
-	patient.PatientDiseaseTable[diseaseIndex], _ = publicKey2.Encrypt(1, int64(patientNationalID))
+	patient.PatientDiseaseTable[diseaseIndex], err = encrypt(ctx, privateKey.Pk, 1, core.DiseaseSlot(strconv.Itoa(patientNationalID), diseaseIndex))
+	if err != nil {
+		return err
+	}
+	patient.KeyFingerprint = privateKey.Pk.Fingerprint()
 
-	fmt.Println("Patient's Disease Value Is Changed")
-	fmt.Println(patient)
+	err = putPatient(ctx, patient)
+	if err != nil {
+		return err
+	}
+	err = auditService(ctx).Record(strconv.Itoa(patientNationalID), core.AuditDiseaseChange, diseaseIndex, reason)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	event := events.New(events.DiseaseFlagChanged).WithDiseaseIndex(diseaseIndex)
+	event.PatientNationalIDs = []string{strconv.Itoa(patientNationalID)}
+	event.PatientFamilyID = strconv.Itoa(patient.PatientFamilyID)
+	event.KeyFingerprint = patient.KeyFingerprint
+	event.AddCiphertext(strconv.Itoa(patientNationalID), diseaseIndex, patient.PatientDiseaseTable[diseaseIndex])
+	return core.EmitEvent(newTransaction(ctx), event)
 }
 
 // GetAllAssets returns all assets found in world state
-func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface) error {
-	// range query with empty string for startKey and endKey does an
-	// open-ended query of all assets in the chaincode namespace.
-	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface) (_ []*PatientView, err error) {
+	defer catalogError(&err)
+	// partial key query with no attributes returns every patient
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(core.PatientNamespace, []string{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resultsIterator.Close()
 
+	assets := []*PatientView{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if isErased(queryResponse.Value) {
+			continue
 		}
 
-		var asset Patient
-		err = json.Unmarshal(queryResponse.Value, &asset)
+		asset, err := unmarshalPatientView(queryResponse.Value)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		fmt.Println(asset)
+		assets = append(assets, asset)
 	}
 
-	return nil
+	return assets, nil
 }
 
 // CreateAsset issues a new asset to the world state with given details.
-func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, patientName string, patientNationalID string, patientFamilyID string, firstDisease int, secondDisease int, thirdDisease int) error {
-	exists, err := s.AssetExists(ctx, patientNationalID)
+func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, patientName string, patientNationalID string, patientFamilyID string, firstDisease int, secondDisease int, thirdDisease int) (err error) {
+	defer catalogError(&err)
+	err = core.ValidateName(patientName)
+	if err != nil {
+		return err
+	}
+	patientnationalidInt, err := parseID("patientNationalID", patientNationalID)
+	if err != nil {
+		return err
+	}
+	patientfamilyidInt, err := parseID("patientFamilyID", patientFamilyID)
+	if err != nil {
+		return err
+	}
+	err = core.ValidateDiseaseValues(firstDisease, secondDisease, thirdDisease)
+	if err != nil {
+		return err
+	}
+	err = patientService(ctx).ValidateNew(strconv.Itoa(patientnationalidInt))
 	if err != nil {
 		return err
 	}
-	if exists {
-		return fmt.Errorf("the asset %s already exists", patientNationalID)
+	err = erasureService(ctx).ValidateFamily(strconv.Itoa(patientfamilyidInt), false)
+	if err != nil {
+		return err
 	}
 
-	patientfamilyidInt, err := strconv.Atoi(patientFamilyID)
-	patientnationalidInt, err := strconv.Atoi(patientNationalID)
+	privateKey, familyEpoch, err := familyKeyService(ctx).KeyOrNew(strconv.Itoa(patientfamilyidInt))
+	if err != nil {
+		return err
+	}
+	publicKey2 := privateKey.Pk
+	keyScope := ""
 
-	publicKey, _, _ := Pailler.GenerateKeyPair(patientfamilyidInt)
-	N, g := publicKey.ToString()
-	publicKey2, _ := Pailler.NewPublicKey(N, g)
+	patientKey, err := keyService(ctx).NewPatientKey(strconv.Itoa(patientnationalidInt), strconv.Itoa(patientfamilyidInt), privateKey, familyEpoch)
+	if err != nil {
+		return err
+	}
+	if patientKey != nil {
+		publicKey2 = patientKey
+		keyScope = keyScopePatient
+	}
 
-	firstDiseaseEncrypted, err := publicKey2.Encrypt(int64(firstDisease), int64(patientnationalidInt)) // First Disease Value Encryption
+	firstDiseaseEncrypted, err := encrypt(ctx, publicKey2, int64(firstDisease), core.DiseaseSlot(strconv.Itoa(patientnationalidInt), 0)) // First Disease Value Encryption
+	if err != nil {
+		return err
+	}
+	secondDiseaseEncrypted, err := encrypt(ctx, publicKey2, int64(secondDisease), core.DiseaseSlot(strconv.Itoa(patientnationalidInt), 1)) // Second Disease Value Encryption
 	if err != nil {
-		return fmt.Errorf("encryption error")
+		return err
+	}
+	thirdDiseaseEncrypted, err := encrypt(ctx, publicKey2, int64(thirdDisease), core.DiseaseSlot(strconv.Itoa(patientnationalidInt), 2)) // Third Disease Value Encryption
+	if err != nil {
+		return err
 	}
-	secondDiseaseEncrypted, err := publicKey2.Encrypt(int64(secondDisease), int64(patientnationalidInt)) // Second Disease Value Encryption
-	thirdDiseaseEncrypted, err := publicKey2.Encrypt(int64(thirdDisease), int64(patientnationalidInt))   // Third Disease Value Encryption
 
 	patient :=
 		Patient{
@@ -184,137 +265,126 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 			PatientNationalID:   patientnationalidInt,
 			PatientFamilyID:     patientfamilyidInt,
 			PatientDiseaseTable: [3]*big.Int{firstDiseaseEncrypted, secondDiseaseEncrypted, thirdDiseaseEncrypted},
+			KeyScope:            keyScope,
+			KeyFingerprint:      publicKey2.Fingerprint(),
 		}
 
-	patientJSON, err := json.Marshal(patient) // Patient Information Encoded as JSON
+	err = putPatient(ctx, &patient) // Patinet Information Saved To The Ledger
 	if err != nil {
-		return fmt.Errorf("asset cannot encoded right now")
+		return core.InternalError("failed in put state: %v", err)
 	}
-	
-	err = ctx.GetStub().PutState(patientNationalID, patientJSON) // Patinet Information Saved To The Ledger
+
+	err = auditService(ctx).Record(strconv.Itoa(patientnationalidInt), core.AuditCreate, core.NoDiseaseSlot, "")
 	if err != nil {
-		return fmt.Errorf("failed in put state")
+		return err
 	}
 
-	return nil
+	event := events.New(events.PatientCreated)
+	event.PatientFamilyID = patientFamilyID
+	event.KeyFingerprint = patient.KeyFingerprint
+	core.AddPatientCiphertexts(event, patient.toCore())
+	return core.EmitEvent(newTransaction(ctx), event)
 }
 
-func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, patientNationalID string) error {
-	// Delete the key from the state in ledger
-	err := ctx.GetStub().DelState(patientNationalID)
+func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, patientNationalID string) (err error) {
+	defer catalogError(&err)
+	patientJSON, err := getPatientState(ctx, patientNationalID)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// TransferAsset updates the owner field of asset with given id in world state.
-func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndex int) error {
-
-	ancestorIds := []int{115, 116, 119, 120}
-
-	level := 0
-
-	patient := new(Patient)
-	disease := new(Diseases)
-
-	patientAsset, err := ctx.GetStub().GetState(patientNationalID)
-	if err != nil {
-		return fmt.Errorf("patient doesn't exist")
+	if patientJSON == nil {
+		return core.NotFound("the asset %s does not exist", patientNationalID)
 	}
-	err = json.Unmarshal(patientAsset, patient)
+	if isErased(patientJSON) {
+		return core.InvalidArgument("patientNationalID", "the asset %s has been erased", patientNationalID)
+	}
+	var patient Patient
+	err = json.Unmarshal(patientJSON, &patient)
 	if err != nil {
-		return fmt.Errorf("patient can't be fetched")
+		return err
 	}
-
-	publicKey, privateKey, _ := Pailler.GenerateKeyPair(patient.PatientFamilyID)
-	N, g := publicKey.ToString()
-	publicKey2, _ := Pailler.NewPublicKey(N, g)
-
-	diseaseAsset, err := ctx.GetStub().GetState("DiseaseTable")
+	// Delete the patient and their family membership from the state in ledger
+	err = deletePatientState(ctx, patient.PatientNationalID, patient.PatientFamilyID)
 	if err != nil {
-		return fmt.Errorf("pailler Props can't be fetched")
+		return err
 	}
-	err = json.Unmarshal(diseaseAsset, disease)
+	err = auditService(ctx).Record(strconv.Itoa(patient.PatientNationalID), core.AuditDelete, core.NoDiseaseSlot, "")
 	if err != nil {
-		return fmt.Errorf("PaillerProps can't be fetched")
+		return err
 	}
 
-	diseaseProbability := disease.Achondroplasia
-	result, _ := publicKey2.Encrypt(0, int64(patient.PatientNationalID))
-
-	fmt.Println("******************************")
-	fmt.Println(patient.PatientName)
-	fmt.Println(patient.PatientNationalID)
-	fmt.Println("******************************")
-
-	for i := 0; i < 4; i += 2 {
-
-		level++
-
-		fatherId := ancestorIds[i]
-		fatherPatient := getPatient(ctx, fatherId)
-		probability, _ := fatherPatient.calculate(publicKey2, diseaseProbability, level, diseaseIndex)
-		fmt.Println("Father's Calculation Done " + fatherPatient.PatientName)
-		fmt.Print("Probability : ")
-		fmt.Println(probability)
-		dResult, _ := privateKey.Decrypt(probability)
-		dResultString := strconv.Itoa(int(dResult))
-		fmt.Println(" Total Result Decrypted Probability is  : " + dResultString + "%")
-		result, _ = publicKey2.Add(result, probability)
+	event := events.New(events.PatientDeleted)
+	event.PatientNationalIDs = []string{patientNationalID}
+	event.PatientFamilyID = strconv.Itoa(patient.PatientFamilyID)
+	return core.EmitEvent(newTransaction(ctx), event)
+}
 
-		fmt.Print("Result : ")
-		fmt.Println(result)
-		fmt.Print("Decrypt Result : ")
-		fmt.Println(privateKey.Decrypt(result))
+// TransferAsset computes the patient's risk of the disease over their recorded ancestors
+// and returns it both encrypted under the patient's key and decrypted.
+// Relatives that have not consented to risk computation by the requesting organization
+// are left out and listed in the result. The risk is also combined with the disease's
+// population prevalence into posterior log-odds, which are left encrypted.
+func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, patientNationalID string, diseaseIndex int) (_ *RiskResult, err error) {
+	defer catalogError(&err)
+	patient, err := readLivePatient(ctx, patientNationalID)
+	if err != nil {
+		return nil, err
+	}
 
-		motherId := ancestorIds[i+1]
-		motherPatient := getPatient(ctx, motherId)
-		probability, err = motherPatient.calculate(publicKey2, diseaseProbability, level, diseaseIndex)
-		fmt.Println("Mother's Calculation Done " + motherPatient.PatientName)
-		fmt.Print("Probability : ")
-		fmt.Println(probability)
-		dResult, _ = privateKey.Decrypt(probability)
-		dResultString = strconv.Itoa(int(dResult))
-		fmt.Println(" Total Result Decrypted Probability is  : " + dResultString + "%")
-		result, _ = publicKey2.Add(result, probability)
+	privateKey, err := getPatientKey(ctx, patient)
+	if err != nil {
+		return nil, err
+	}
+	publicKey2 := privateKey.Pk
 
-		fmt.Print("Final Result : ")
-		fmt.Println(result)
-		fmt.Print("Decrypted Final Result : ")
-		fmt.Println(privateKey.Decrypt(result))
+	disease, err := diseaseService(ctx).Get(diseaseIndex)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Println("******************************")
-	fmt.Println("Encrypted Result : " + result.String())
-	fmt.Println("******************************")
-	dResult, err := privateKey.Decrypt(result)
-	dResultString := strconv.Itoa(int(dResult))
+	generations, err := ancestorGenerations(ctx, patient.PatientNationalID)
 	if err != nil {
-		return fmt.Errorf("PaillerProps can't be fetched")
+		return nil, err
 	}
-	fmt.Println("The Probability of Patient's Having Achondroplasia Disease Is : " + dResultString + "%")
-	fmt.Println("******************************")
-	return nil
 
-}
+	consent, err := consentService(ctx).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return nil, err
+	}
+	result, explanation, err := riskService(ctx).Explain(publicKey2, patientNationalID, generations, disease.Weight, diseaseIndex, consent)
+	if err != nil {
+		return nil, err
+	}
+	excludedIDs := explanation.Excluded()
+	excluded := make([]int, len(excludedIDs))
+	for index, nationalID := range excludedIDs {
+		excluded[index], _ = strconv.Atoi(nationalID)
+	}
 
-func getPatient(ctx contractapi.TransactionContextInterface, nationalID int) *Patient {
-	patient := new(Patient)
-	nationalIDString := strconv.Itoa(nationalID)
-	patientAsset, err := ctx.GetStub().GetState(nationalIDString)
+	risk, err := core.DecryptEvidence(privateKey, result, core.MaxEvidence(generations, disease.Weight))
 	if err != nil {
-		fmt.Println("GetState Error")
+		return nil, core.InvalidCiphertext("risk cannot be decrypted: %v", err)
 	}
-	err = json.Unmarshal(patientAsset, patient)
+	posterior, err := core.ComputePosterior(privateKey.Pk, explanation.Evidence, generations, disease.Weight, disease.Prevalence)
 	if err != nil {
-		fmt.Println("Unmarshal Error")
+		return nil, err
+	}
+	err = core.EmitRiskComputed(newTransaction(ctx), strconv.Itoa(patient.PatientNationalID), diseaseIndex, publicKey2.Fingerprint(), result)
+	if err != nil {
+		return nil, err
 	}
-	return patient
-}
 
-func (t *Patient) calculate(patientProps *Pailler.PublicKey, diseaseProbability int, level int, diseaseIndex int) (*big.Int, error) {
-	diseaseValue := t.PatientDiseaseTable[diseaseIndex]
-	diseaseContribution := diseaseProbability / level
-	return patientProps.MultPlaintext(diseaseValue, int64(diseaseContribution))
+	return &RiskResult{
+		PatientNationalID: patient.PatientNationalID,
+		DiseaseIndex:      diseaseIndex,
+		Disease:           disease.Name,
+		EncryptedRisk:     result.Text(16),
+		KeyFingerprint:    publicKey2.Fingerprint(),
+		Risk:              risk,
+		ExcludedRelatives: excluded,
+		Prevalence:        disease.Prevalence,
+		EncryptedLogOdds:  posterior.Ciphertext.Text(16),
+		PriorLogOdds:      posterior.Prior,
+		MaxEvidence:       posterior.MaxEvidence,
+	}, nil
 }
@@ -0,0 +1,522 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+const (
+	testFamilyKeySeed = "genchain test family key seed, 32 bytes or more"
+	testWrapSecret    = "genchain test key wrap secret, 32 bytes or more"
+)
+
+// testDiseaseWeights are the weights InitLedger registers, by disease index
+var testDiseaseWeights = []int{100, 70, 50}
+
+// testbed is a simulated channel with the contract installed, submitting as a registry
+// admin of Org1MSP
+type testbed struct {
+	t         *testing.T
+	ledger    *ledgersim.Ledger
+	chaincode shim.Chaincode
+	admin     *ledgersim.Identity
+}
+
+// newChannel returns a testbed whose ledger is empty
+func newChannel(t *testing.T) *testbed {
+	t.Helper()
+	t.Setenv(core.KeyWrapSecretEnv, testWrapSecret)
+	chaincode, err := contractapi.NewChaincode(&SmartContract{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger := ledgersim.New()
+	ledger.Clock = ledgersim.FixedClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	return &testbed{
+		t:         t,
+		ledger:    ledger,
+		chaincode: chaincode,
+		admin:     ledgersim.NewIdentity("Org1MSP", "admin", map[string]string{core.RoleAttribute: core.RoleRegistryAdmin, core.FamiliesAttribute: "*"}),
+	}
+}
+
+// newTestbed returns a testbed on which InitLedger has run
+func newTestbed(t *testing.T) *testbed {
+	t.Helper()
+	tb := newChannel(t)
+	tb.mustInvoke(map[string][]byte{core.FamilyKeySeedField: []byte(testFamilyKeySeed)}, "InitLedger")
+	return tb
+}
+
+// invoke submits the transaction as the registry admin
+func (tb *testbed) invoke(transient map[string][]byte, function string, args ...string) ledgersim.Result {
+	return tb.invokeAs(tb.admin, transient, function, args...)
+}
+
+func (tb *testbed) invokeAs(identity *ledgersim.Identity, transient map[string][]byte, function string, args ...string) ledgersim.Result {
+	return tb.ledger.Invoke(tb.chaincode, ledgersim.Transaction{Function: function, Args: args, Identity: identity, Transient: transient})
+}
+
+// mustInvoke submits the transaction and returns its payload, failing the test when it
+// is not committed
+func (tb *testbed) mustInvoke(transient map[string][]byte, function string, args ...string) []byte {
+	tb.t.Helper()
+	result := tb.invoke(transient, function, args...)
+	if !result.Committed {
+		tb.t.Fatalf("%s %v: status %d: %s", function, args, result.Response.Status, result.Response.Message)
+	}
+	return result.Response.Payload
+}
+
+// mustDecode submits the transaction and decodes its JSON payload into v
+func (tb *testbed) mustDecode(v interface{}, function string, args ...string) {
+	tb.t.Helper()
+	err := json.Unmarshal(tb.mustInvoke(nil, function, args...), v)
+	if err != nil {
+		tb.t.Fatalf("%s %v: %v", function, args, err)
+	}
+}
+
+// checkResult fails the test when the transaction did not end as wanted: committed for
+// an empty want, otherwise failed with want in the error message
+func checkResult(t *testing.T, result ledgersim.Result, want string) {
+	t.Helper()
+	switch {
+	case want == "" && !result.Committed:
+		t.Errorf("status %d: %s", result.Response.Status, result.Response.Message)
+	case want != "" && result.Committed:
+		t.Errorf("committed, want an error with %q", want)
+	case want != "" && !strings.Contains(result.Response.Message, want):
+		t.Errorf("error %q, want %q in it", result.Response.Message, want)
+	}
+}
+
+// familyKey derives the key InitLedger generated for the family
+func familyKey(t *testing.T, familyID string) *Pailler.PrivateKey {
+	t.Helper()
+	_, privateKey, err := core.DeriveFamilyKey(familyID, []byte(testFamilyKeySeed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey
+}
+
+// decryptHex decrypts a ciphertext returned as a hex string
+func decryptHex(t *testing.T, key *Pailler.PrivateKey, ciphertext []byte) int64 {
+	t.Helper()
+	value, ok := new(big.Int).SetString(string(ciphertext), 16)
+	if !ok {
+		t.Fatalf("%q is not a hex ciphertext", ciphertext)
+	}
+	plaintext, err := key.Decrypt(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plaintext
+}
+
+// diseaseValues decrypts the patient's disease table with the key
+func (tb *testbed) diseaseValues(key *Pailler.PrivateKey, nationalID string) []int64 {
+	tb.t.Helper()
+	var patient PatientView
+	tb.mustDecode(&patient, "ReadAsset", nationalID)
+	values := make([]int64, len(patient.PatientDiseaseTable))
+	for index, ciphertext := range patient.PatientDiseaseTable {
+		values[index] = decryptHex(tb.t, key, []byte(ciphertext))
+	}
+	return values
+}
+
+// The contract API builds the metadata of every transaction: each parameter and return
+// type has to be one it can describe
+func TestNewChaincode(t *testing.T) {
+	_, err := contractapi.NewChaincode(&SmartContract{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInitLedger(t *testing.T) {
+	tests := []struct {
+		name string
+		seed string
+		want string
+	}{
+		{name: "seeded", seed: testFamilyKeySeed},
+		{name: "no seed", want: core.FamilyKeySeedField},
+		{name: "short seed", seed: "short", want: core.FamilyKeySeedField},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := newChannel(t)
+			var transient map[string][]byte
+			if test.seed != "" {
+				transient = map[string][]byte{core.FamilyKeySeedField: []byte(test.seed)}
+			}
+			checkResult(t, tb.invoke(transient, "InitLedger"), test.want)
+		})
+	}
+
+	// Every family gets its own key and grandparents 119 and 120 have type 2 diabetes
+	tb := newTestbed(t)
+	families := map[string][]string{"20": {"111", "112", "113"}, "21": {"114"}, "22": {"115", "116", "117", "118", "119", "120", "121"}}
+	for familyID, members := range families {
+		key := familyKey(t, familyID)
+		for _, nationalID := range members {
+			want := []int64{0, 0, 0}
+			if nationalID == "119" || nationalID == "120" {
+				want = []int64{0, 1, 0}
+			}
+			if got := tb.diseaseValues(key, nationalID); !reflect.DeepEqual(got, want) {
+				t.Errorf("patient %s has diseases %v, want %v", nationalID, got, want)
+			}
+		}
+	}
+}
+
+func TestCreateAsset(t *testing.T) {
+	tb := newTestbed(t)
+	seed := map[string][]byte{core.FamilyKeySeedField: []byte(testFamilyKeySeed)}
+	tests := []struct {
+		name      string
+		args      []string
+		transient map[string][]byte
+		want      string
+	}{
+		{name: "new patient", args: []string{"Zeynep Kaya", "130", "22", "1", "0", "1"}},
+		{name: "new family", args: []string{"Emre", "131", "23", "0", "0", "0"}, transient: seed},
+		{name: "new family without a seed", args: []string{"Emre", "135", "24", "0", "0", "0"}, want: core.FamilyKeySeedField},
+		{name: "existing patient", args: []string{"Zeynep Kaya", "130", "22", "0", "0", "0"}, want: "ALREADY_EXISTS"},
+		{name: "invalid family", args: []string{"Emre", "132", "x1", "0", "0", "0"}, want: "patientFamilyID"},
+		{name: "invalid disease value", args: []string{"Emre", "133", "22", "0", "7", "0"}, want: "disease[1]"},
+		{name: "empty name", args: []string{"", "134", "22", "0", "0", "0"}, want: "INVALID_ARGUMENT"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkResult(t, tb.invoke(test.transient, "CreateAsset", test.args...), test.want)
+		})
+	}
+
+	if got := tb.diseaseValues(familyKey(t, "22"), "130"); !reflect.DeepEqual(got, []int64{1, 0, 1}) {
+		t.Errorf("patient 130 has diseases %v, want [1 0 1]", got)
+	}
+	// A new family's key is derived from the seed passed with its first patient
+	if got := tb.diseaseValues(familyKey(t, "23"), "131"); !reflect.DeepEqual(got, []int64{0, 0, 0}) {
+		t.Errorf("patient 131 has diseases %v, want [0 0 0]", got)
+	}
+}
+
+func TestReadAsset(t *testing.T) {
+	tb := newTestbed(t)
+	tests := []struct {
+		nationalID string
+		name       string
+		want       string
+	}{
+		{nationalID: "115", name: "Nusret"},
+		{nationalID: "999", want: "NOT_FOUND"},
+	}
+	for _, test := range tests {
+		t.Run(test.nationalID, func(t *testing.T) {
+			result := tb.invoke(nil, "ReadAsset", test.nationalID)
+			checkResult(t, result, test.want)
+			exists := strings.TrimSpace(string(tb.mustInvoke(nil, "AssetExists", test.nationalID)))
+			if exists != strconv.FormatBool(test.want == "") {
+				t.Errorf("AssetExists = %s", exists)
+			}
+			if test.want != "" {
+				return
+			}
+			var patient PatientView
+			err := json.Unmarshal(result.Response.Payload, &patient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if patient.PatientName != test.name || strconv.Itoa(patient.PatientNationalID) != test.nationalID {
+				t.Errorf("ReadAsset = %+v", patient)
+			}
+		})
+	}
+}
+
+func TestChangeAsset(t *testing.T) {
+	tb := newTestbed(t)
+	lab := ledgersim.NewIdentity("Org1MSP", "lab", map[string]string{core.RoleAttribute: core.RoleLab, core.FamiliesAttribute: "22"})
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "patient of the lab's family", args: []string{"115", "0", "lab result"}},
+		{name: "patient of another family", args: []string{"112", "0", "lab result"}, want: "ACCESS_DENIED"},
+		{name: "unknown disease", args: []string{"115", "3", "lab result"}, want: "diseaseIndex"},
+		{name: "unknown patient", args: []string{"999", "0", "lab result"}, want: "NOT_FOUND"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkResult(t, tb.invokeAs(lab, nil, "ChangeAsset", test.args...), test.want)
+		})
+	}
+
+	if got := tb.diseaseValues(familyKey(t, "22"), "115"); !reflect.DeepEqual(got, []int64{1, 0, 0}) {
+		t.Errorf("patient 115 has diseases %v, want [1 0 0]", got)
+	}
+	if got := tb.diseaseValues(familyKey(t, "20"), "112"); !reflect.DeepEqual(got, []int64{0, 0, 0}) {
+		t.Errorf("patient 112 has diseases %v, want [0 0 0]", got)
+	}
+}
+
+func TestDeleteAsset(t *testing.T) {
+	tb := newTestbed(t)
+	tb.mustInvoke(nil, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	tests := []struct {
+		nationalID string
+		want       string
+	}{
+		{nationalID: "130"},
+		{nationalID: "130", want: "NOT_FOUND"},
+		{nationalID: "999", want: "NOT_FOUND"},
+	}
+	for _, test := range tests {
+		checkResult(t, tb.invoke(nil, "DeleteAsset", test.nationalID), test.want)
+	}
+	checkResult(t, tb.invoke(nil, "ReadAsset", "130"), "NOT_FOUND")
+
+	// The family membership goes with the patient
+	var page PatientPage
+	tb.mustDecode(&page, "GetAssetsPage", "20", "", "22")
+	for _, patient := range page.Records {
+		if patient.PatientNationalID == 130 {
+			t.Error("the deleted patient is still listed in their family")
+		}
+	}
+}
+
+// riskCase sets up patient 130 of family 22 with the affected relatives and the
+// relatives consenting to risk computation by Org1MSP. Parents, when given, are recorded
+// as the father and mother of 130 and then of each father in turn; without them the risk
+// is computed over the fixed ancestorIds pedigree.
+type riskCase struct {
+	name         string
+	diseaseIndex int
+	affected     []string
+	consenting   []string
+	parents      [][]string
+}
+
+var riskCases = []riskCase{
+	{name: "no consent", diseaseIndex: 0, affected: []string{"115", "119"}},
+	{name: "affected parent and grandparent", diseaseIndex: 0, affected: []string{"115", "119"}, consenting: []string{"115", "116", "119", "120"}},
+	{name: "one grandparent withholds consent", diseaseIndex: 0, affected: []string{"115", "119"}, consenting: []string{"115", "116", "120"}},
+	{name: "affected grandparents from InitLedger", diseaseIndex: 1, consenting: []string{"115", "116", "119", "120"}},
+	{name: "every relative affected", diseaseIndex: 2, affected: []string{"115", "116", "119", "120"}, consenting: []string{"115", "116", "119", "120"}},
+}
+
+// setUp builds the case on a fresh ledger
+func (c riskCase) setUp(t *testing.T) *testbed {
+	tb := newTestbed(t)
+	tb.mustInvoke(nil, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	child := "130"
+	for _, parents := range c.parents {
+		tb.mustInvoke(nil, "AddParent", child, parents[0], "father")
+		tb.mustInvoke(nil, "AddParent", child, parents[1], "mother")
+		child = parents[0]
+	}
+	for _, nationalID := range c.affected {
+		tb.mustInvoke(nil, "ChangeAsset", nationalID, strconv.Itoa(c.diseaseIndex), "lab result")
+	}
+	for _, nationalID := range c.consenting {
+		tb.mustInvoke(nil, "GrantConsent", nationalID, core.PurposeRiskComputation, "Org1MSP", "")
+	}
+	return tb
+}
+
+// reference computes the risk in plaintext: each consenting, affected ancestor adds the
+// disease weight over their generation. Grandparents 119 and 120 have type 2 diabetes
+// from InitLedger.
+func (c riskCase) reference() (int64, []int) {
+	affected := map[string]bool{}
+	for _, nationalID := range c.affected {
+		affected[nationalID] = true
+	}
+	if c.diseaseIndex == 1 {
+		affected["119"], affected["120"] = true, true
+	}
+	consenting := map[string]bool{}
+	for _, nationalID := range c.consenting {
+		consenting[nationalID] = true
+	}
+
+	var risk int64
+	excluded := []int{}
+	generations := core.FixedGenerations(ancestorIds)
+	if c.parents != nil {
+		generations = c.parents
+	}
+	for index, generation := range generations {
+		for _, nationalID := range generation {
+			if !consenting[nationalID] {
+				id, _ := strconv.Atoi(nationalID)
+				excluded = append(excluded, id)
+				continue
+			}
+			if affected[nationalID] {
+				risk += int64(testDiseaseWeights[c.diseaseIndex] / (index + 1))
+			}
+		}
+	}
+	return risk, excluded
+}
+
+// Every transaction computing a risk agrees with the plaintext reference once its
+// result is decrypted
+func TestRiskMatchesReference(t *testing.T) {
+	for _, c := range riskCases {
+		t.Run(c.name, func(t *testing.T) {
+			tb := c.setUp(t)
+			key := familyKey(t, "22")
+			diseaseIndex := strconv.Itoa(c.diseaseIndex)
+			want, excluded := c.reference()
+
+			var result RiskResult
+			tb.mustDecode(&result, "TransferAsset", "130", diseaseIndex)
+			if result.Risk != want || decryptHex(t, key, []byte(result.EncryptedRisk)) != want {
+				t.Errorf("TransferAsset risk = %d, want %d", result.Risk, want)
+			}
+			if !reflect.DeepEqual(result.ExcludedRelatives, excluded) {
+				t.Errorf("TransferAsset excluded %v, want %v", result.ExcludedRelatives, excluded)
+			}
+			prior, err := core.PriorLogOdds(result.Prevalence)
+			if err != nil {
+				t.Fatal(err)
+			}
+			posterior, ok := new(big.Int).SetString(result.EncryptedLogOdds, 16)
+			if !ok {
+				t.Fatalf("%q is not a hex ciphertext", result.EncryptedLogOdds)
+			}
+			logOdds, err := core.DecryptPosterior(key, posterior, result.PriorLogOdds, result.MaxEvidence)
+			if err != nil || result.PriorLogOdds != prior || logOdds != prior+want {
+				t.Errorf("TransferAsset posterior = %d (%v) from prior %d, want %d from %d", logOdds, err, result.PriorLogOdds, prior+want, prior)
+			}
+
+			var explanation RiskExplanation
+			tb.mustDecode(&explanation, "ExplainRisk", "130", diseaseIndex)
+			sum := decryptHex(t, key, []byte(explanation.InitialCiphertext))
+			for _, term := range explanation.Terms {
+				if term.Contribution != "" {
+					sum += decryptHex(t, key, []byte(term.Contribution))
+				}
+			}
+			if risk := decryptHex(t, key, []byte(explanation.EncryptedRisk)); risk != want || sum != want {
+				t.Errorf("ExplainRisk risk = %d with terms adding up to %d, want %d", risk, sum, want)
+			}
+
+			var profile RiskProfile
+			tb.mustDecode(&profile, "ComputeRiskProfile", "130", "[]")
+			if risk := decryptHex(t, key, []byte(profile.EncryptedRisks[diseaseIndex])); risk != want {
+				t.Errorf("ComputeRiskProfile risk = %d, want %d", risk, want)
+			}
+
+			// Patient 130 has no key of their own, so the family key reads their risks
+			crossFamily := tb.mustInvoke(nil, "CalculateCrossFamilyRisk", "130", diseaseIndex)
+			if risk := decryptHex(t, key, crossFamily); risk != want {
+				t.Errorf("CalculateCrossFamilyRisk risk = %d, want %d", risk, want)
+			}
+			_, computationKey, err := core.DerivePatientKey("computation", []byte(testFamilyKeySeed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			forKey := tb.mustInvoke(nil, "ComputeRiskForKey", "130", diseaseIndex, computationKey.Pk.N.Text(16), computationKey.Pk.G.Text(16))
+			if risk := decryptHex(t, computationKey, forKey); risk != want {
+				t.Errorf("ComputeRiskForKey risk = %d, want %d", risk, want)
+			}
+		})
+	}
+}
+
+// Recorded parents replace the fixed pedigree, whose relatives then no longer count
+var recordedPedigreeCases = []riskCase{
+	{name: "recorded parents", diseaseIndex: 0, parents: [][]string{{"117", "118"}},
+		affected: []string{"117", "119"}, consenting: []string{"115", "116", "117", "118", "119", "120"}},
+	{name: "recorded parents and grandparents", diseaseIndex: 1, parents: [][]string{{"117", "118"}, {"119", "120"}},
+		consenting: []string{"117", "119", "120"},
+	},
+}
+
+func TestRiskFollowsRecordedParents(t *testing.T) {
+	for _, c := range recordedPedigreeCases {
+		t.Run(c.name, func(t *testing.T) {
+			tb := c.setUp(t)
+			key := familyKey(t, "22")
+			diseaseIndex := strconv.Itoa(c.diseaseIndex)
+			want, excluded := c.reference()
+
+			var result RiskResult
+			tb.mustDecode(&result, "TransferAsset", "130", diseaseIndex)
+			if result.Risk != want || decryptHex(t, key, []byte(result.EncryptedRisk)) != want {
+				t.Errorf("TransferAsset risk = %d, want %d", result.Risk, want)
+			}
+			if !reflect.DeepEqual(result.ExcludedRelatives, excluded) {
+				t.Errorf("TransferAsset excluded %v, want %v", result.ExcludedRelatives, excluded)
+			}
+
+			var explanation RiskExplanation
+			tb.mustDecode(&explanation, "ExplainRisk", "130", diseaseIndex)
+			if risk := decryptHex(t, key, []byte(explanation.EncryptedRisk)); risk != want {
+				t.Errorf("ExplainRisk risk = %d, want %d", risk, want)
+			}
+			if len(explanation.Terms) != len(c.parents)*2 {
+				t.Errorf("ExplainRisk has %d terms, want one per recorded parent", len(explanation.Terms))
+			}
+
+			var profile RiskProfile
+			tb.mustDecode(&profile, "ComputeRiskProfile", "130", "[]")
+			if risk := decryptHex(t, key, []byte(profile.EncryptedRisks[diseaseIndex])); risk != want {
+				t.Errorf("ComputeRiskProfile risk = %d, want %d", risk, want)
+			}
+		})
+	}
+}
+
+func TestRiskOfUnknownPatientOrDisease(t *testing.T) {
+	tb := newTestbed(t)
+	tests := []struct {
+		function string
+		args     []string
+		want     string
+	}{
+		{function: "TransferAsset", args: []string{"999", "0"}, want: "NOT_FOUND"},
+		{function: "TransferAsset", args: []string{"115", "3"}, want: "diseaseIndex"},
+		{function: "ExplainRisk", args: []string{"999", "0"}, want: "NOT_FOUND"},
+		{function: "ComputeRiskProfile", args: []string{"115", "[0,0]"}, want: "listed twice"},
+		{function: "CalculateCrossFamilyRisk", args: []string{"115", "3"}, want: "diseaseIndex"},
+	}
+	for _, test := range tests {
+		t.Run(test.function+" "+strings.Join(test.args, " "), func(t *testing.T) {
+			checkResult(t, tb.invoke(nil, test.function, test.args...), test.want)
+		})
+	}
+}
+
+// A profile on a channel without registered diseases has no diseases and no risks
+func TestRiskProfileWithoutDiseases(t *testing.T) {
+	tb := newChannel(t)
+	tb.mustInvoke(map[string][]byte{core.FamilyKeySeedField: []byte(testFamilyKeySeed)}, "CreateAsset", "Zeynep Kaya", "130", "22", "0", "0", "0")
+
+	var profile RiskProfile
+	tb.mustDecode(&profile, "ComputeRiskProfile", "130", "[]")
+	if len(profile.Diseases) != 0 || len(profile.EncryptedRisks) != 0 {
+		t.Errorf("got diseases %v and risks %v, want none", profile.Diseases, profile.EncryptedRisks)
+	}
+}
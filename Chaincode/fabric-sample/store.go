@@ -0,0 +1,466 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// stubStore is the core.Store over the transaction's world state, or over a private
+// data collection when collection is set
+type stubStore struct {
+	stub       shim.ChaincodeStubInterface
+	collection string
+}
+
+func newStore(ctx contractapi.TransactionContextInterface) *stubStore {
+	return &stubStore{stub: ctx.GetStub()}
+}
+
+func newPrivateStore(ctx contractapi.TransactionContextInterface, collection string) *stubStore {
+	return &stubStore{stub: ctx.GetStub(), collection: collection}
+}
+
+func (s *stubStore) Get(key core.Key) ([]byte, error) {
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	if s.collection == "" {
+		value, err = s.stub.GetState(id)
+	} else {
+		value, err = s.stub.GetPrivateData(s.collection, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", key.Namespace, err)
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (s *stubStore) Put(key core.Key, value []byte) error {
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return err
+	}
+	if s.collection == "" {
+		err = s.stub.PutState(id, value)
+	} else {
+		err = s.stub.PutPrivateData(s.collection, id, value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put %s. %v", key.Namespace, err)
+	}
+	return nil
+}
+
+func (s *stubStore) Delete(key core.Key) error {
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return err
+	}
+	if s.collection == "" {
+		return s.stub.DelState(id)
+	}
+	return s.stub.DelPrivateData(s.collection, id)
+}
+
+// Purge is only supported for private data, since the world state keeps its history in
+// the blocks
+func (s *stubStore) Purge(key core.Key) error {
+	if s.collection == "" {
+		return fmt.Errorf("the world state cannot purge %s", key.Namespace)
+	}
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return err
+	}
+	err = s.stub.PurgePrivateData(s.collection, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge %s. %v", key.Namespace, err)
+	}
+	return nil
+}
+
+func (s *stubStore) Range(prefix core.Key, visit func(key core.Key, value []byte) error) error {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	if s.collection == "" {
+		resultsIterator, err = s.stub.GetStateByPartialCompositeKey(prefix.Namespace, prefix.Attributes)
+	} else {
+		resultsIterator, err = s.stub.GetPrivateDataByPartialCompositeKey(s.collection, prefix.Namespace, prefix.Attributes)
+	}
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		namespace, attributes, err := s.stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return err
+		}
+		err = visit(core.NewKey(namespace, attributes...), queryResponse.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RangePage is only supported for the world state
+func (s *stubStore) RangePage(prefix core.Key, pageSize int32, bookmark string, visit func(key core.Key, value []byte) error) (int32, string, error) {
+	if s.collection != "" {
+		return 0, "", fmt.Errorf("private data collection %s cannot be paged", s.collection)
+	}
+	resultsIterator, metadata, err := s.stub.GetStateByPartialCompositeKeyWithPagination(prefix.Namespace, prefix.Attributes, pageSize, bookmark)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+		namespace, attributes, err := s.stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, "", err
+		}
+		err = visit(core.NewKey(namespace, attributes...), queryResponse.Value)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	return metadata.FetchedRecordsCount, metadata.Bookmark, nil
+}
+
+// History is only kept for the world state
+func (s *stubStore) History(key core.Key) ([]core.Version, error) {
+	if s.collection != "" {
+		return nil, fmt.Errorf("private data collection %s keeps no history", s.collection)
+	}
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := s.stub.GetHistoryForKey(id)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var versions []core.Version
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		version := core.Version{TxID: modification.TxId, Value: modification.Value, IsDelete: modification.IsDelete}
+		if modification.Timestamp != nil {
+			version.Timestamp = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC().Format(time.RFC3339)
+		}
+		versions = append(versions, version)
+	}
+
+	// The ledger returns the history newest first
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions, nil
+}
+
+// stubTransaction is the core.Transaction the contract runs in
+type stubTransaction struct {
+	ctx contractapi.TransactionContextInterface
+}
+
+func newTransaction(ctx contractapi.TransactionContextInterface) *stubTransaction {
+	return &stubTransaction{ctx: ctx}
+}
+
+func (t *stubTransaction) TxID() string {
+	return t.ctx.GetStub().GetTxID()
+}
+
+func (t *stubTransaction) Time() (time.Time, error) {
+	timestamp, err := t.ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
+
+func (t *stubTransaction) Client() (core.Identity, error) {
+	return t.ctx.GetClientIdentity(), nil
+}
+
+func (t *stubTransaction) Transient() (map[string][]byte, error) {
+	transient, err := t.ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	return transient, nil
+}
+
+func (t *stubTransaction) SetEvent(name string, payload []byte) error {
+	return t.ctx.GetStub().SetEvent(name, payload)
+}
+
+// patientCodec stores core patients in this contract's Patient form
+type patientCodec struct{}
+
+func (patientCodec) Decode(value []byte) (*core.Patient, error) {
+	var patient Patient
+	err := json.Unmarshal(value, &patient)
+	if err != nil {
+		return nil, err
+	}
+	return patient.toCore(), nil
+}
+
+func (patientCodec) Encode(record *core.Patient) ([]byte, error) {
+	patient, err := patientFromCore(record)
+	if err != nil {
+		return nil, err
+	}
+	patient.DocType = core.PatientDocType
+	return json.Marshal(patient)
+}
+
+// recordCodec stores core records in this contract's forms, which hold IDs as numbers
+type recordCodec struct{}
+
+func (recordCodec) Encode(record interface{}) ([]byte, error) {
+	var stored interface{}
+	var err error
+	switch record := record.(type) {
+	case *core.AuditRecord:
+		stored, err = auditRecordFromCore(record)
+	case *core.ConsentRecord:
+		stored, err = consentRecordFromCore(record)
+	case *core.FamilyKeyRecord:
+		stored, err = familyKeyRecordFromCore(record)
+	case *core.PatientKeyRecord:
+		stored, err = patientKeyRecordFromCore(record)
+	case *core.Tombstone:
+		stored, err = tombstoneFromCore(record)
+	case *core.ErasureReceipt:
+		stored, err = erasureReceiptFromCore(record)
+	case *core.FamilyKey:
+		// Family keys are stored without their family ID, which their key holds
+		stored = record.Key
+	default:
+		stored = record
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(stored)
+}
+
+func (recordCodec) Decode(value []byte, record interface{}) error {
+	switch record := record.(type) {
+	case *core.AuditRecord:
+		var stored AuditRecord
+		err := json.Unmarshal(value, &stored)
+		*record = *stored.toCore()
+		return err
+	case *core.ConsentRecord:
+		var stored ConsentRecord
+		err := json.Unmarshal(value, &stored)
+		*record = *stored.toCore()
+		return err
+	case *core.FamilyKeyRecord:
+		var stored FamilyKeyRecord
+		err := json.Unmarshal(value, &stored)
+		*record = *stored.toCore()
+		return err
+	case *core.PatientKeyRecord:
+		var stored PatientKeyRecord
+		err := json.Unmarshal(value, &stored)
+		*record = *stored.toCore()
+		return err
+	case *core.Tombstone:
+		var stored Tombstone
+		err := json.Unmarshal(value, &stored)
+		*record = *stored.toCore()
+		return err
+	case *core.ErasureReceipt:
+		var stored ErasureReceipt
+		err := json.Unmarshal(value, &stored)
+		*record = *stored.toCore()
+		return err
+	case *core.FamilyKey:
+		record.Key = new(Pailler.PrivateKey)
+		return json.Unmarshal(value, record.Key)
+	}
+	return json.Unmarshal(value, record)
+}
+
+func (patient *Patient) toCore() *core.Patient {
+	return &core.Patient{
+		Name:           patient.PatientName,
+		NationalID:     strconv.Itoa(patient.PatientNationalID),
+		FamilyID:       strconv.Itoa(patient.PatientFamilyID),
+		DiseaseTable:   patient.PatientDiseaseTable[:],
+		Genotypes:      patient.PatientGenotypes[:],
+		BirthYear:      patient.PatientBirthYear,
+		KeyScope:       patient.KeyScope,
+		KeyFingerprint: patient.KeyFingerprint,
+		CreatedAt:      patient.CreatedAt,
+		UpdatedAt:      patient.UpdatedAt,
+	}
+}
+
+func patientFromCore(record *core.Patient) (*Patient, error) {
+	nationalID, err := strconv.Atoi(record.NationalID)
+	if err != nil {
+		return nil, err
+	}
+	familyID, err := strconv.Atoi(record.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	patient := &Patient{
+		PatientName:       record.Name,
+		PatientNationalID: nationalID,
+		PatientFamilyID:   familyID,
+		PatientBirthYear:  record.BirthYear,
+		KeyScope:          record.KeyScope,
+		KeyFingerprint:    record.KeyFingerprint,
+		DocType:           core.PatientDocType,
+		CreatedAt:         record.CreatedAt,
+		UpdatedAt:         record.UpdatedAt,
+	}
+	copy(patient.PatientDiseaseTable[:], record.DiseaseTable)
+	copy(patient.PatientGenotypes[:], record.Genotypes)
+	return patient, nil
+}
+
+// getPatientState returns the stored patient or tombstone, or nil when there is none
+func getPatientState(ctx contractapi.TransactionContextInterface, patientNationalID string) ([]byte, error) {
+	return newStore(ctx).Get(core.NewKey(core.PatientNamespace, patientNationalID))
+}
+
+// putPatient stamps and stores the patient and adds them to their family's membership index
+func putPatient(ctx contractapi.TransactionContextInterface, patient *Patient) error {
+	timestamp, err := core.Timestamp(newTransaction(ctx))
+	if err != nil {
+		return err
+	}
+	record := patient.toCore()
+	err = patientService(ctx).Put(record, timestamp)
+	if err != nil {
+		return err
+	}
+	patient.DocType = core.PatientDocType
+	patient.CreatedAt = record.CreatedAt
+	patient.UpdatedAt = record.UpdatedAt
+	return nil
+}
+
+// deletePatientState removes the patient and their family membership
+func deletePatientState(ctx contractapi.TransactionContextInterface, patientNationalID int, patientFamilyID int) error {
+	return patientService(ctx).Delete(strconv.Itoa(patientNationalID), strconv.Itoa(patientFamilyID))
+}
+
+// familyMembers returns every patient of the family that has not been erased
+func familyMembers(ctx contractapi.TransactionContextInterface, familyID int) ([]*Patient, error) {
+	records, err := patientService(ctx).FamilyMembers(strconv.Itoa(familyID))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*Patient, len(records))
+	for index, record := range records {
+		members[index], err = patientFromCore(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return members, nil
+}
+
+func removeFamilyMember(ctx contractapi.TransactionContextInterface, patientNationalID int, patientFamilyID int) error {
+	return patientService(ctx).RemoveFamilyMember(strconv.Itoa(patientNationalID), strconv.Itoa(patientFamilyID))
+}
+
+func patientService(ctx contractapi.TransactionContextInterface) *core.PatientService {
+	return &core.PatientService{State: newStore(ctx), Codec: patientCodec{}}
+}
+
+func diseaseService(ctx contractapi.TransactionContextInterface) *core.DiseaseService {
+	return &core.DiseaseService{State: newStore(ctx)}
+}
+
+func auditService(ctx contractapi.TransactionContextInterface) *core.AuditService {
+	return &core.AuditService{State: newStore(ctx), Codec: recordCodec{}, Patients: patientCodec{}, Tx: newTransaction(ctx)}
+}
+
+func consentService(ctx contractapi.TransactionContextInterface) *core.ConsentService {
+	return &core.ConsentService{State: newStore(ctx), Codec: recordCodec{}, Patients: patientService(ctx), Audit: auditService(ctx), Tx: newTransaction(ctx)}
+}
+
+func keyService(ctx contractapi.TransactionContextInterface) *core.KeyService {
+	return &core.KeyService{
+		State:      newStore(ctx),
+		Private:    newPrivateStore(ctx, keyCollection),
+		Codec:      recordCodec{},
+		FamilyKey:  familyKeyService(ctx).Key,
+		WrapSecret: core.KeyWrapSecret(),
+		Tx:         newTransaction(ctx),
+	}
+}
+
+func familyKeyService(ctx contractapi.TransactionContextInterface) *core.FamilyKeyService {
+	return &core.FamilyKeyService{State: newStore(ctx), Private: newPrivateStore(ctx, keyCollection), Codec: recordCodec{}, Tx: newTransaction(ctx)}
+}
+
+func erasureService(ctx contractapi.TransactionContextInterface) *core.ErasureService {
+	return &core.ErasureService{
+		State:      newStore(ctx),
+		Codec:      recordCodec{},
+		Patients:   patientService(ctx),
+		FamilyKeys: familyKeyService(ctx),
+		Keys:       keyService(ctx),
+		Audit:      auditService(ctx),
+		Randomness: randomness(ctx),
+		Tx:         newTransaction(ctx),
+	}
+}
+
+func riskService(ctx contractapi.TransactionContextInterface) *core.RiskService {
+	return &core.RiskService{Patients: patientService(ctx), Keys: keyService(ctx), Penetrance: diseaseService(ctx).Penetrance(newTransaction(ctx)), Randomness: randomness(ctx)}
+}
+
+// randomness is the randomness of the ciphertexts the transaction computes, the same on
+// every endorsing peer
+func randomness(ctx contractapi.TransactionContextInterface) *core.Randomness {
+	return core.TransactionRandomness(ctx.GetStub().GetTxID())
+}
+
+// encrypt encrypts a value of a patient's record in its slot
+func encrypt(ctx contractapi.TransactionContextInterface, key *Pailler.PublicKey, msg int64, slot string) (*big.Int, error) {
+	ciphertext, err := randomness(ctx).Encrypt(key, msg, slot)
+	var coreError *core.Error
+	if err != nil && !errors.As(err, &coreError) {
+		return nil, core.InternalError("encryption error: %v", err)
+	}
+	return ciphertext, err
+}
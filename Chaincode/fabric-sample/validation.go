@@ -0,0 +1,20 @@
+package chaincode
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// parseID checks an ID argument with core.ValidateID and returns its value
+func parseID(field string, id string) (int, error) {
+	err := core.ValidateID(field, id)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, core.InvalidArgument(field, "%q is out of range", id)
+	}
+	return value, nil
+}
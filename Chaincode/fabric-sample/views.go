@@ -0,0 +1,132 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"math/big"
+)
+
+// PatientView is the patient record as returned to clients. Ciphertexts are hex strings
+// because they do not fit in a JSON number.
+type PatientView struct {
+	PatientName         string   `json:"patientName"`
+	PatientNationalID   int      `json:"patientNationalID"`
+	PatientFamilyID     int      `json:"patientFamilyID"`
+	PatientDiseaseTable []string `json:"patientDiseaseTable"`
+	PatientGenotypes    []string `json:"patientGenotypes"`
+	BirthYear           int      `json:"birthYear,omitempty" metadata:",optional"`
+	KeyScope            string   `json:"keyScope,omitempty" metadata:",optional"`
+	KeyFingerprint      string   `json:"keyFingerprint,omitempty" metadata:",optional"`
+	CreatedAt           string   `json:"createdAt,omitempty" metadata:",optional"`
+	UpdatedAt           string   `json:"updatedAt,omitempty" metadata:",optional"`
+}
+
+// RiskResult is the outcome of a risk calculation. Risk is the weighted sum of affected
+// ancestors and is never negative. EncryptedLogOdds adds the family evidence, the same
+// sum less what unaffected ancestors take away in proportion to the penetrance at their
+// age, to PriorLogOdds, the log-odds of the disease's prevalence in hundredths of a nat,
+// under the patient's key. It decrypts to within MaxEvidence of the prior, and
+// core.Probability turns it into the posterior probability.
+type RiskResult struct {
+	PatientNationalID int    `json:"patientNationalID"`
+	DiseaseIndex      int    `json:"diseaseIndex"`
+	Disease           string `json:"disease"`
+	EncryptedRisk     string `json:"encryptedRisk"`
+	KeyFingerprint    string `json:"keyFingerprint"`
+	Risk              int64  `json:"risk"`
+	ExcludedRelatives []int  `json:"excludedRelatives"`
+	Prevalence        int    `json:"prevalence"`
+	EncryptedLogOdds  string `json:"encryptedLogOdds"`
+	PriorLogOdds      int64  `json:"priorLogOdds"`
+	MaxEvidence       int64  `json:"maxEvidence"`
+}
+
+// RecessiveRiskResult holds, for each of the patient's parents in turn, the encrypted
+// chance in percent that they pass the allele of a recessive disease on, under the
+// patient's key. The patient is affected with the product of the chances over 100,
+// which core.RecessiveRiskPercent computes for the holder of the key.
+type RecessiveRiskResult struct {
+	PatientNationalID      int      `json:"patientNationalID"`
+	DiseaseIndex           int      `json:"diseaseIndex"`
+	Disease                string   `json:"disease"`
+	Parents                []int    `json:"parents"`
+	EncryptedTransmissions []string `json:"encryptedTransmissions"`
+	KeyFingerprint         string   `json:"keyFingerprint"`
+}
+
+// RiskProfile is the outcome of a risk calculation for several diseases. The risks are
+// hex ciphertexts under the patient's key, keyed like the disease names by disease index.
+type RiskProfile struct {
+	PatientNationalID int               `json:"patientNationalID"`
+	KeyFingerprint    string            `json:"keyFingerprint"`
+	Diseases          map[string]string `json:"diseases,omitempty" metadata:",optional"`
+	EncryptedRisks    map[string]string `json:"encryptedRisks"`
+	ExcludedRelatives []int             `json:"excludedRelatives"`
+}
+
+// RiskExplanation is the breakdown of a risk calculation. The ciphertexts are under the
+// patient's key, so only its holder can read the contributions; they add up, with the
+// initial encrypted zero, to the encrypted risk.
+type RiskExplanation struct {
+	PatientNationalID int        `json:"patientNationalID"`
+	DiseaseIndex      int        `json:"diseaseIndex"`
+	Disease           string     `json:"disease"`
+	Weight            int        `json:"weight"`
+	KeyFingerprint    string     `json:"keyFingerprint"`
+	EncryptedRisk     string     `json:"encryptedRisk"`
+	InitialCiphertext string     `json:"initialCiphertext"`
+	Terms             []RiskTerm `json:"terms"`
+}
+
+// RiskTerm is one relative's part in a risk calculation. Contribution is empty for
+// relatives left out for lack of consent. Penetrance is the percent of the weight the
+// relative takes away from the family evidence if unaffected, given their age.
+type RiskTerm struct {
+	PatientNationalID int    `json:"patientNationalID"`
+	Relationship      string `json:"relationship"`
+	Level             int    `json:"level"`
+	Weight            int    `json:"weight"`
+	Penetrance        int    `json:"penetrance,omitempty" metadata:",optional"`
+	Status            string `json:"status"`
+	Contribution      string `json:"contribution,omitempty" metadata:",optional"`
+}
+
+// newPatientView converts a stored patient for a client
+func newPatientView(patient *Patient) *PatientView {
+	view := &PatientView{
+		PatientName:         patient.PatientName,
+		PatientNationalID:   patient.PatientNationalID,
+		PatientFamilyID:     patient.PatientFamilyID,
+		PatientDiseaseTable: make([]string, len(patient.PatientDiseaseTable)),
+		PatientGenotypes:    make([]string, len(patient.PatientGenotypes)),
+		BirthYear:           patient.PatientBirthYear,
+		KeyScope:            patient.KeyScope,
+		KeyFingerprint:      patient.KeyFingerprint,
+		CreatedAt:           patient.CreatedAt,
+		UpdatedAt:           patient.UpdatedAt,
+	}
+	for index, value := range patient.PatientDiseaseTable {
+		view.PatientDiseaseTable[index] = ciphertextString(value)
+	}
+	for index, value := range patient.PatientGenotypes {
+		view.PatientGenotypes[index] = ciphertextString(value)
+	}
+	return view
+}
+
+// unmarshalPatientView decodes a stored patient straight into its client form
+func unmarshalPatientView(patientJSON []byte) (*PatientView, error) {
+	var patient Patient
+	err := json.Unmarshal(patientJSON, &patient)
+	if err != nil {
+		return nil, err
+	}
+	return newPatientView(&patient), nil
+}
+
+// ciphertextString encodes a ciphertext as hex, or as an empty string when it is missing
+func ciphertextString(value *big.Int) string {
+	if value == nil {
+		return ""
+	}
+	return value.Text(16)
+}
@@ -0,0 +1,93 @@
+package simple
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// accessRules are the chaincode's functions as the access check sees them. The default
+// policy is used until a registry admin stores a policy on the ledger.
+var accessRules = &core.AccessRules{
+	Transactions: []string{
+		"changeDisease", "readAllPatients", "readPatientsPage", "queryPatients", "readAllPailler",
+		"addPatient", "deletePatient", "queryPatient", "calculateDiseaseProbabilityWithoutTree", "erasePatient",
+		"eraseFamily", "computeRiskForKey", "addParent", "calculateCrossFamilyRisk", "migrateLedger",
+		"getPatientHistory", "setAccessPolicy", "getAccessPolicy", "grantConsent", "revokeConsent", "getConsents",
+		"explainRisk", "computeRiskProfile", "setGenotype", "computeRecessiveRisk", "setBirthYear",
+	},
+	Scopes: map[string][]core.ScopeArgument{
+		"queryPatient":                           {{Index: 0}},
+		"changeDisease":                          {{Index: 0}},
+		"addPatient":                             {{Index: 2, Family: true}},
+		"deletePatient":                          {{Index: 0}},
+		"calculateDiseaseProbabilityWithoutTree": {{Index: 0}},
+		"explainRisk":                            {{Index: 0}},
+		"computeRiskProfile":                     {{Index: 0}},
+		"setGenotype":                            {{Index: 0}},
+		"setBirthYear":                           {{Index: 0}},
+		"computeRecessiveRisk":                   {{Index: 0}},
+		"erasePatient":                           {{Index: 0}},
+		"eraseFamily":                            {{Index: 0, Family: true}},
+		"computeRiskForKey":                      {{Index: 0}},
+		"addParent":                              {{Index: 0}, {Index: 1}},
+		"calculateCrossFamilyRisk":               {{Index: 0}},
+		"readPatientsPage":                       {{Index: 2, Family: true}},
+		"getPatientHistory":                      {{Index: 0}},
+		"grantConsent":                           {{Index: 0}},
+		"revokeConsent":                          {{Index: 0}},
+		"getConsents":                            {{Index: 0}},
+	},
+	SetPolicy: "setAccessPolicy",
+	Default: core.AccessPolicy{Roles: map[string][]string{
+		core.RoleRegistryAdmin: {"*"},
+		core.RoleClinician: {"queryPatient", "addPatient", "changeDisease", "setGenotype", "setBirthYear", "calculateDiseaseProbabilityWithoutTree", "explainRisk",
+			"computeRiskProfile", "computeRecessiveRisk", "addParent", "calculateCrossFamilyRisk", "getPatientHistory", "readPatientsPage", "getConsents"},
+		core.RoleLab:        {"queryPatient", "changeDisease", "setGenotype"},
+		core.RoleGeneticist: {"queryPatient", "calculateDiseaseProbabilityWithoutTree", "explainRisk", "computeRiskProfile", "computeRecessiveRisk", "computeRiskForKey", "calculateCrossFamilyRisk", "addParent", "readPatientsPage"},
+		core.RolePatient: {"queryPatient", "getPatientHistory", "erasePatient", "grantConsent",
+			"revokeConsent", "getConsents"},
+	}},
+}
+
+func accessService(stub shim.ChaincodeStubInterface) *core.AccessService {
+	return &core.AccessService{State: newStore(stub), Rules: accessRules}
+}
+
+// Replace the access policy stored on the ledger
+func (t *Patient) setAccessPolicy(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errorResponse(core.WrongArgumentCount("1"))
+	}
+	err := accessService(stub).SetPolicy(args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Return the access policy in force
+func (t *Patient) getAccessPolicy(stub shim.ChaincodeStubInterface) pb.Response {
+	policy, err := accessService(stub).Policy()
+	if err != nil {
+		return errorResponse(err)
+	}
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(policyJSON)
+}
+
+// authorize runs the access check on the function's arguments
+func authorize(stub shim.ChaincodeStubInterface, transaction string, args []string) error {
+	identity, err := cid.New(stub)
+	if err != nil {
+		return fmt.Errorf("cannot read the client identity: %v", err)
+	}
+	return accessService(stub).Authorize(identity, transaction, args)
+}
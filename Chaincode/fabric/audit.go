@@ -0,0 +1,46 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// PatientVersion is one version of a patient record from the ledger history
+type PatientVersion struct {
+	TxID      string            `json:"txID"`
+	Timestamp string            `json:"timestamp"`
+	IsDelete  bool              `json:"isDelete"`
+	Erased    bool              `json:"erased"`
+	Record    *PatientView      `json:"record,omitempty" metadata:",optional"`
+	Audit     *core.AuditRecord `json:"audit,omitempty" metadata:",optional"`
+}
+
+// Return every version of a patient record, oldest first, with the audit record of the
+// transaction that wrote it. The history of an erased patient keeps their audit trail
+// but not the records written before the erasure.
+func (t *Patient) getPatientHistory(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errorResponse(core.WrongArgumentCount("1"))
+	}
+	history, err := auditService(stub).History(args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	versions := make([]*PatientVersion, len(history))
+	for index, version := range history {
+		versions[index] = &PatientVersion{TxID: version.TxID, Timestamp: version.Timestamp, IsDelete: version.IsDelete, Erased: version.Erased, Audit: version.Audit}
+		if version.Record != nil {
+			versions[index].Record = newPatientView(patientFromCore(version.Record))
+		}
+	}
+
+	resultJSON, err := json.Marshal(versions)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(resultJSON)
+}
@@ -0,0 +1,40 @@
+package simple
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Record the year the patient was born. Risk calculations read the age of an unaffected
+// relative from it to tell how much their being unaffected says about a late-onset
+// disease; relatives with no birth year recorded are not down-weighted.
+func (t *Patient) setBirthYear(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(core.WrongArgumentCount("2"))
+	}
+	patientNationalID := args[0]
+	currentYear, err := core.Year(newTransaction(stub))
+	if err != nil {
+		return errorResponse(err)
+	}
+	birthYear, err := parseBirthYear(args[1], currentYear)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	patient, err := readLivePatient(stub, patientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	patient.PatientBirthYear = birthYear
+	err = putPatient(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = auditService(stub).Record(patientNationalID, core.AuditBirthYearChange, core.NoDiseaseSlot, args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
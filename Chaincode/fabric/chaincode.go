@@ -9,11 +9,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"os"
-	"strconv"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
 	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
 )
 
@@ -22,8 +22,18 @@ type Patient struct {
 	PatientNationalID   string      `json:"patientNationalID"`
 	PatientFamilyID     string      `json:"patientFamilyID"`
 	PatientDiseaseTable [3]*big.Int `json:"patientDiseaseTable"`
+	PatientGenotypes    [3]*big.Int `json:"patientGenotypes"`
+	PatientBirthYear    int         `json:"patientBirthYear,omitempty"`
+	KeyScope            string      `json:"keyScope,omitempty"`
+	KeyFingerprint      string      `json:"keyFingerprint,omitempty"`
+	DocType             string      `json:"docType"`
+	CreatedAt           string      `json:"createdAt,omitempty"`
+	UpdatedAt           string      `json:"updatedAt,omitempty"`
 }
 
+// ancestorIds lists the father and mother of each generation, nearest first
+var ancestorIds = []string{"115", "116", "119", "120"}
+
 type PaillerKey struct {
 	PatientFamilyID string              `json:"patientFamilyID"`
 	Key             *Pailler.PrivateKey `json:"key"`
@@ -37,12 +47,12 @@ type Diseases struct {
 
 type PatientResult struct {
 	Key    string `json:"Key"`
-	Record *Patient
+	Record *PatientView
 }
 
 type PaillerResult struct {
 	Key    string `json:"Key"`
-	Record *PaillerKey
+	Record *FamilyKeyView
 }
 
 func (t *Patient) Init(stub shim.ChaincodeStubInterface) pb.Response {
@@ -63,141 +73,184 @@ func (t *Patient) Init(stub shim.ChaincodeStubInterface) pb.Response {
 		{PatientName: "Hamza", PatientNationalID: "121", PatientFamilyID: "22", PatientDiseaseTable: [3]*big.Int{zero, zero, zero}},
 	}
 
-	previousFamilyID := patients[0].PatientFamilyID
-	var paillerAssets []PaillerKey
-
-	publicKey, privateKey, _ := Pailler.GenerateKeyPair(1024)
-	N, g := publicKey.ToString()
-	publicKey2, _ := Pailler.NewPublicKey(N, g)
-
-	fmt.Println("Keys Generated...")
+	seed, err := core.TransientSeed(newTransaction(stub), core.FamilyKeySeedField)
+	if err != nil {
+		return errorResponse(err)
+	}
+	// A transaction does not read its own writes, so the new family keys are kept here
+	familyKeys := map[string]*Pailler.PrivateKey{}
 
+	created := events.New(events.PatientCreated)
 	for _, patient := range patients {
-		if previousFamilyID != patient.PatientFamilyID {
-			previousFamilyID = patient.PatientFamilyID
-			publicKey, privateKey, _ = Pailler.GenerateKeyPair(1024)
-			N, g = publicKey.ToString()
-			publicKey2, _ = Pailler.NewPublicKey(N, g)
-			pailler := PaillerKey{PatientFamilyID: patient.PatientFamilyID, Key: privateKey}
-			paillerAssets = append(paillerAssets, pailler)
-			fmt.Println("Keys Generated For FamilyID" + patient.PatientFamilyID)
+		familyKey, ok := familyKeys[patient.PatientFamilyID]
+		if !ok {
+			familyKey, err = familyKeyService(stub).New(patient.PatientFamilyID, seed)
+			if err != nil {
+				return errorResponse(err)
+			}
+			familyKeys[patient.PatientFamilyID] = familyKey
 		}
-		for index, _ := range patient.PatientDiseaseTable {
-			patient.PatientDiseaseTable[index], _ = publicKey2.Encrypt(0)
+		publicKey := familyKey.Pk
+		for index := range patient.PatientDiseaseTable {
+			value, err := encrypt(stub, publicKey, 0, core.DiseaseSlot(patient.PatientNationalID, index))
+			if err != nil {
+				return errorResponse(err)
+			}
+			patient.PatientDiseaseTable[index] = value
 		}
+		patient.KeyFingerprint = publicKey.Fingerprint()
 
-		patientJSON, err := json.Marshal(patient)
+		err := putPatient(stub, &patient)
 		if err != nil {
-			return shim.Error("Json Mars")
+			return errorResponse(err)
 		}
-		err = stub.PutState(patient.PatientNationalID, patientJSON)
+		err = auditService(stub).Record(patient.PatientNationalID, core.AuditCreate, core.NoDiseaseSlot, "")
 		if err != nil {
-			return shim.Error("Cannot put Patient to the ledger")
+			return errorResponse(err)
 		}
-
-		fmt.Println("Encryption Done for Patient : " + patient.PatientName)
+		core.AddPatientCiphertexts(created, patient.toCore())
 	}
 
 	disease := Diseases{SickleCellDisease: 100, Type2Diabetes: 70, Achondroplasia: 50}
-	diseaseJSON, err := json.Marshal(disease)
-	if err != nil {
-		return shim.Error("Json Mars")
-	}
-	err = stub.PutState("DiseaseTable", diseaseJSON)
+	err = diseaseService(stub).PutTable(disease.SickleCellDisease, disease.Type2Diabetes, disease.Achondroplasia)
 	if err != nil {
-		return shim.Error("Cannot put Patient to the ledger")
+		return errorResponse(err)
 	}
 
-	for _, paillerAsset := range paillerAssets {
-		paillerJSON, err := json.Marshal(paillerAsset)
-		if err != nil {
-			return shim.Error("Json Mars")
-		}
-		err = stub.PutState(paillerAsset.PatientFamilyID, paillerJSON)
-		if err != nil {
-			return shim.Error("Cannot put PaillerProps to the ledger")
-		}
+	err = core.EmitEvent(newTransaction(stub), created)
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	fmt.Println("Init returning with success")
 	return shim.Success(nil)
 }
 
 func (t *Patient) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
-	fmt.Println("ex02 Invoke")
-	if os.Getenv("DEVMODE_ENABLED") != "" {
-		fmt.Println("invoking in devmode")
-	}
 	function, args := stub.GetFunctionAndParameters()
+	err := authorize(stub, function, args)
+	if err != nil {
+		return errorResponse(err)
+	}
 	switch function {
 	case "changeDisease":
 		return t.changeDisease(stub, args)
 	case "readAllPatients":
 		return t.readAllPatients(stub)
+	case "readPatientsPage":
+		return t.readPatientsPage(stub, args)
+	case "queryPatients":
+		return t.queryPatients(stub, args)
 	case "readAllPailler":
 		return t.readAllPailler(stub)
 	case "addPatient":
 		return t.addPatient(stub, args)
 	case "deletePatient":
 		return t.deletePatient(stub, args)
+	case "setGenotype":
+		return t.setGenotype(stub, args)
+	case "setBirthYear":
+		return t.setBirthYear(stub, args)
 	case "queryPatient":
 		return t.queryPatient(stub, args)
 	case "calculateDiseaseProbabilityWithoutTree":
 		return t.calculateDiseaseProbabilityWithoutTree(stub, args)
+	case "explainRisk":
+		return t.explainRisk(stub, args)
+	case "computeRiskProfile":
+		return t.computeRiskProfile(stub, args)
+	case "computeRecessiveRisk":
+		return t.computeRecessiveRisk(stub, args)
+	case "erasePatient":
+		return t.erasePatient(stub, args)
+	case "eraseFamily":
+		return t.eraseFamily(stub, args)
+	case "computeRiskForKey":
+		return t.computeRiskForKey(stub, args)
+	case "addParent":
+		return t.addParent(stub, args)
+	case "calculateCrossFamilyRisk":
+		return t.calculateCrossFamilyRisk(stub, args)
+	case "migrateLedger":
+		return t.migrateLedger(stub)
+	case "getPatientHistory":
+		return t.getPatientHistory(stub, args)
+	case "setAccessPolicy":
+		return t.setAccessPolicy(stub, args)
+	case "getAccessPolicy":
+		return t.getAccessPolicy(stub)
+	case "grantConsent":
+		return t.grantConsent(stub, args)
+	case "revokeConsent":
+		return t.revokeConsent(stub, args)
+	case "getConsents":
+		return t.getConsents(stub, args)
 	default:
-		return shim.Error(`Invalid invoke function name. Expecting "invoke", "delete", "query", "respond", "mspid", or "event"`)
+		return errorResponse(core.NewError(core.CodeUnknownTransaction, map[string]string{"function": function}, "unknown function %q", function))
 	}
 }
 
 // Add a patient to the state
 func (t *Patient) addPatient(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 6 {
-		return shim.Error("Incorrect number of arguments. Expecting 6")
+		return errorResponse(core.WrongArgumentCount("6"))
 	}
 
-	var paillerAsset PaillerKey
 	patientNationalID := args[1]
 	patientFamilyID := args[2]
 
-	val, err := stub.GetState(patientNationalID)
+	err := core.ValidateName(args[0])
 	if err != nil {
-		return shim.Error("Patient Already Exist : " + string(val))
+		return errorResponse(err)
 	}
-
-	asset, err := stub.GetState(patientFamilyID)
+	err = core.ValidateID("patientNationalID", patientNationalID)
 	if err != nil {
-		return shim.Error("Error From Pailler Ledger")
+		return errorResponse(err)
 	}
-
-	if len(asset) == 0 {
-		fmt.Println("Family Tree Doesn't Exist")
-		_, privateKey, _ := Pailler.GenerateKeyPair(1024)
-		fmt.Println("Keys Generated...")
-		paillerAsset = PaillerKey{
-			PatientFamilyID: patientFamilyID,
-			Key:             privateKey,
-		}
-		fmt.Println("Pailler Props Generated...")
-	} else {
-		err = json.Unmarshal(asset, &paillerAsset)
+	err = core.ValidateID("patientFamilyID", patientFamilyID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	diseaseValues, err := parseDiseaseValues(args[3:6])
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = patientService(stub).ValidateNew(patientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = erasureService(stub).ValidateFamily(patientFamilyID, false)
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	fmt.Println(paillerAsset.PatientFamilyID)
-	fmt.Println("Fetched Pailler Asset")
+	familyKey, familyEpoch, err := familyKeyService(stub).KeyOrNew(patientFamilyID)
+	if err != nil {
+		return errorResponse(err)
+	}
 
-	firstDisease, err := strconv.Atoi(args[3])
+	publicKey := familyKey.Pk
+	keyScope := ""
+	patientKey, err := keyService(stub).NewPatientKey(patientNationalID, patientFamilyID, familyKey, familyEpoch)
 	if err != nil {
-		return shim.Error("Atoi error")
+		return errorResponse(err)
+	}
+	if patientKey != nil {
+		publicKey = patientKey
+		keyScope = keyScopePatient
 	}
-	secondDisease, err := strconv.Atoi(args[4])
-	thirdDisease, err := strconv.Atoi(args[5])
 
-	firstDiseaseEncrypted, err := paillerAsset.Key.Pk.Encrypt(int64(firstDisease))
+	firstDiseaseEncrypted, err := encrypt(stub, publicKey, int64(diseaseValues[0]), core.DiseaseSlot(patientNationalID, 0))
+	if err != nil {
+		return errorResponse(err)
+	}
+	secondDiseaseEncrypted, err := encrypt(stub, publicKey, int64(diseaseValues[1]), core.DiseaseSlot(patientNationalID, 1))
+	if err != nil {
+		return errorResponse(err)
+	}
+	thirdDiseaseEncrypted, err := encrypt(stub, publicKey, int64(diseaseValues[2]), core.DiseaseSlot(patientNationalID, 2))
 	if err != nil {
-		return shim.Error("Encryption error")
+		return errorResponse(err)
 	}
-	secondDiseaseEncrypted, err := paillerAsset.Key.Pk.Encrypt(int64(secondDisease))
-	thirdDiseaseEncrypted, err := paillerAsset.Key.Pk.Encrypt(int64(thirdDisease))
 
 	fmt.Println("Encryption Done...")
 
@@ -207,30 +260,28 @@ func (t *Patient) addPatient(stub shim.ChaincodeStubInterface, args []string) pb
 			PatientNationalID:   patientNationalID,
 			PatientFamilyID:     patientFamilyID,
 			PatientDiseaseTable: [3]*big.Int{firstDiseaseEncrypted, secondDiseaseEncrypted, thirdDiseaseEncrypted},
+			KeyScope:            keyScope,
+			KeyFingerprint:      publicKey.Fingerprint(),
 		}
 
-	patientJSON, err := json.Marshal(patient)
-	if err != nil {
-		return shim.Error("Asset cannot encoded right now...")
-	}
-
 	fmt.Println(patient)
 
-	err = stub.PutState(patient.PatientNationalID, patientJSON)
+	err = putPatient(stub, &patient)
 	if err != nil {
-		return shim.Error("Failed in put state...")
+		return errorResponse(err)
 	}
-
-	paillerJSON, err := json.Marshal(paillerAsset)
+	err = auditService(stub).Record(patientNationalID, core.AuditCreate, core.NoDiseaseSlot, "")
 	if err != nil {
-		return shim.Error("Asset cannot encoded right now...")
+		return errorResponse(err)
 	}
 
-	fmt.Println(paillerAsset)
-
-	err = stub.PutState(paillerAsset.PatientFamilyID, paillerJSON)
+	event := events.New(events.PatientCreated)
+	event.PatientFamilyID = patientFamilyID
+	event.KeyFingerprint = patient.KeyFingerprint
+	core.AddPatientCiphertexts(event, patient.toCore())
+	err = core.EmitEvent(newTransaction(stub), event)
 	if err != nil {
-		return shim.Error("Failed in put state...")
+		return errorResponse(err)
 	}
 
 	fmt.Println("Patient Successfully Saved...")
@@ -241,15 +292,43 @@ func (t *Patient) addPatient(stub shim.ChaincodeStubInterface, args []string) pb
 // Delete a patient from state
 func (t *Patient) deletePatient(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errorResponse(core.WrongArgumentCount("1"))
 	}
 
 	nationalID := args[0]
 
-	// Delete the key from the state in ledger
-	err := stub.DelState(nationalID)
+	patientAsset, err := getPatientState(stub, nationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if len(patientAsset) == 0 {
+		return errorResponse(core.NotFound("patient %s doesn't exist", nationalID))
+	}
+	if isErased(patientAsset) {
+		return errorResponse(core.Erased("patient %s has been erased", nationalID))
+	}
+	patient := new(Patient)
+	err = json.Unmarshal(patientAsset, patient)
+	if err != nil {
+		return errorResponse(core.InternalError("patient can't be fetched: %v", err))
+	}
+
+	// Delete the patient and their family membership from the state in ledger
+	err = deletePatientState(stub, nationalID, patient.PatientFamilyID)
+	if err != nil {
+		return errorResponse(core.InternalError("failed to delete state: %v", err))
+	}
+	err = auditService(stub).Record(nationalID, core.AuditDelete, core.NoDiseaseSlot, "")
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	event := events.New(events.PatientDeleted)
+	event.PatientNationalIDs = []string{nationalID}
+	event.PatientFamilyID = patient.PatientFamilyID
+	err = core.EmitEvent(newTransaction(stub), event)
 	if err != nil {
-		return shim.Error("Failed to delete state")
+		return errorResponse(err)
 	}
 
 	return shim.Success(nil)
@@ -258,12 +337,9 @@ func (t *Patient) deletePatient(stub shim.ChaincodeStubInterface, args []string)
 // Read all patients that in the state
 func (t *Patient) readAllPatients(stub shim.ChaincodeStubInterface) pb.Response {
 
-	startKey := ""
-	endKey := ""
-
-	resultsIterator, err := stub.GetStateByRange(startKey, endKey)
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(core.PatientNamespace, []string{})
 	if err != nil {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errorResponse(err)
 	}
 
 	defer func(resultsIterator shim.StateQueryIteratorInterface) {
@@ -273,39 +349,41 @@ func (t *Patient) readAllPatients(stub shim.ChaincodeStubInterface) pb.Response
 		}
 	}(resultsIterator)
 
+	queryResults := []PatientResult{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 
 		if err != nil {
-			return shim.Error("Incorrect number of arguments. Expecting 1")
+			return errorResponse(err)
+		}
+
+		if isErased(queryResponse.Value) {
+			continue
 		}
 
 		patient := new(Patient)
-		_ = json.Unmarshal(queryResponse.Value, patient)
-
-		queryResult := PatientResult{Key: queryResponse.Key, Record: patient}
-		fmt.Println("*************************************************")
-		fmt.Println("Key : " + string(queryResult.Key) + " |  Name : " + (queryResult.Record.PatientName))
-		fmt.Print("[ ")
-		for _, value := range queryResult.Record.PatientDiseaseTable {
-			fmt.Println(value)
+		err = json.Unmarshal(queryResponse.Value, patient)
+		if err != nil {
+			return errorResponse(core.InternalError("patient can't be fetched: %v", err))
 		}
-		fmt.Print("]")
-		fmt.Println("*************************************************")
+
+		queryResults = append(queryResults, PatientResult{Key: patient.PatientNationalID, Record: newPatientView(patient)})
 	}
-	return shim.Success(nil)
+
+	resultsJSON, err := json.Marshal(queryResults)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(resultsJSON)
 }
 
-// Read all pailler props that in the state
+// Read the public half of every family key in the state
 func (t *Patient) readAllPailler(stub shim.ChaincodeStubInterface) pb.Response {
 
-	startKey := ""
-	endKey := ""
-
-	resultsIterator, err := stub.GetStateByRange(startKey, endKey)
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(core.FamilyKeyNamespace, []string{})
 
 	if err != nil {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errorResponse(err)
 	}
 	defer func(resultsIterator shim.StateQueryIteratorInterface) {
 		err := resultsIterator.Close()
@@ -314,218 +392,209 @@ func (t *Patient) readAllPailler(stub shim.ChaincodeStubInterface) pb.Response {
 		}
 	}(resultsIterator)
 
+	queryResults := []PaillerResult{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 
 		if err != nil {
-			return shim.Error("Incorrect number of arguments. Expecting 1")
+			return errorResponse(err)
 		}
 
-		pailler := new(PaillerKey)
-		_ = json.Unmarshal(queryResponse.Value, pailler)
+		record := new(core.FamilyKeyRecord)
+		err = json.Unmarshal(queryResponse.Value, record)
+		if err != nil {
+			return errorResponse(core.InternalError("family key can't be fetched: %v", err))
+		}
+		view, err := newFamilyKeyView(record)
+		if err != nil {
+			return errorResponse(err)
+		}
 
-		queryResult := PaillerResult{Key: queryResponse.Key, Record: pailler}
-		fmt.Println("*************************************************")
-		fmt.Println(queryResult.Key)
-		fmt.Println(queryResult.Record)
-		fmt.Println("*************************************************")
+		queryResults = append(queryResults, PaillerResult{Key: record.PatientFamilyID, Record: view})
 	}
-	return shim.Success(nil)
+
+	resultsJSON, err := json.Marshal(queryResults)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(resultsJSON)
 }
 
 // Query callback representing the query of a chaincode
 func (t *Patient) queryPatient(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
 	if len(args) != 1 {
-		return shim.Error("Incorrect number of arguments. Expecting 1")
+		return errorResponse(core.WrongArgumentCount("1"))
 	}
 
-	patient := new(Patient)
-	paillerKey := new(PaillerKey)
 	patientNationalID := args[0]
 
-	patient = getPatient(stub, patientNationalID)
-	paillerKey = getPaillerKey(stub, patient.PatientFamilyID)
-	fmt.Println("Patient's Name : " + patient.PatientName)
-	fmt.Println("Patient's FamilyID : " + paillerKey.PatientFamilyID)
-	fmt.Println("Patient's Diseses Values")
+	patient, err := readLivePatient(stub, patientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
 
+	queryResult := PatientQueryResult{Record: newPatientView(patient), DiseaseValues: []int64{}}
 	for _, encryptedValue := range patient.PatientDiseaseTable {
-		fmt.Println(encryptedValue)
-		decryptedValue, err := paillerKey.Key.Decrypt(encryptedValue)
+		decryptedValue, err := patientKey.Decrypt(encryptedValue)
 		if err != nil {
-			return shim.Error("Patient Disease Value cannot decrypted")
+			return errorResponse(core.InvalidCiphertext("disease value of patient %s cannot be decrypted: %v", patientNationalID, err))
 		}
-		fmt.Println(decryptedValue)
+		queryResult.DiseaseValues = append(queryResult.DiseaseValues, decryptedValue)
 	}
 
-	return shim.Success(nil)
+	resultJSON, err := json.Marshal(queryResult)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(resultJSON)
 }
 
-// Change the value of the disease for patient in the state
+// Change the value of the disease for patient in the state. An optional third argument
+// is recorded in the audit trail as the reason for the change.
 func (t *Patient) changeDisease(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
-	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2")
+	if len(args) != 2 && len(args) != 3 {
+		return errorResponse(core.WrongArgumentCount("2 or 3"))
 	}
 
-	patient := new(Patient)
-	paillerKey := new(PaillerKey)
 	patientNationalID := args[0]
 
-	diseaseIndex, err := strconv.Atoi(args[1])
+	diseaseIndex, err := parseDiseaseIndex(args[1])
 	if err != nil {
-		return shim.Error("Second argument is not an integer")
+		return errorResponse(err)
+	}
+	reason := ""
+	if len(args) == 3 {
+		reason = args[2]
 	}
 
-	patient = getPatient(stub, patientNationalID)
-	paillerKey = getPaillerKey(stub, patient.PatientFamilyID)
-
-	patient.PatientDiseaseTable[diseaseIndex], err = paillerKey.Key.Pk.Encrypt(1)
+	patient, err := readLivePatient(stub, patientNationalID)
 	if err != nil {
-		return shim.Error("Patient's disease value cannot assigned to encrypted 1")
+		return errorResponse(err)
+	}
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
 	}
 
-	err = stub.DelState(patientNationalID)
+	patient.PatientDiseaseTable[diseaseIndex], err = encrypt(stub, patientKey.Pk, 1, core.DiseaseSlot(patientNationalID, diseaseIndex))
 	if err != nil {
-		return shim.Error("Delete State Error")
+		return errorResponse(err)
 	}
+	patient.KeyFingerprint = patientKey.Pk.Fingerprint()
 
-	patientJSON, err := json.Marshal(patient)
+	err = putPatient(stub, patient)
 	if err != nil {
-		return shim.Error("Json Mars")
+		return errorResponse(err)
 	}
-	err = stub.PutState(patient.PatientNationalID, patientJSON)
+	err = auditService(stub).Record(patientNationalID, core.AuditDiseaseChange, diseaseIndex, reason)
 	if err != nil {
-		return shim.Error("Cannot put Patient to the ledger")
+		return errorResponse(err)
+	}
+
+	event := events.New(events.DiseaseFlagChanged).WithDiseaseIndex(diseaseIndex)
+	event.PatientNationalIDs = []string{patientNationalID}
+	event.PatientFamilyID = patient.PatientFamilyID
+	event.KeyFingerprint = patient.KeyFingerprint
+	event.AddCiphertext(patientNationalID, diseaseIndex, patient.PatientDiseaseTable[diseaseIndex])
+	err = core.EmitEvent(newTransaction(stub), event)
+	if err != nil {
+		return errorResponse(err)
 	}
 
 	return shim.Success(nil)
 }
 
+// Compute the patient's risk of the disease over their recorded ancestors. Relatives
+// that have not consented to risk computation by the requesting organization are left
+// out and listed in the result. The risk is also combined with the disease's population
+// prevalence into posterior log-odds, which are left encrypted.
 func (t *Patient) calculateDiseaseProbabilityWithoutTree(stub shim.ChaincodeStubInterface, args []string) pb.Response {
 
-	ancestorIds := []string{"115", "116", "119", "120"}
-
 	if len(args) != 2 {
-		return shim.Error("Incorrect number of arguments. Expecting 2")
+		return errorResponse(core.WrongArgumentCount("2"))
 	}
 
-	level := 0
-
-	patient := new(Patient)
-	patientProps := new(PaillerKey)
-	disease := new(Diseases)
 	patientNationalID := args[0]
 
-	diseaseIndex, err := strconv.Atoi(args[1])
+	diseaseIndex, err := parseDiseaseIndex(args[1])
 	if err != nil {
-		return shim.Error("Second argument is not an integer")
+		return errorResponse(err)
 	}
 
-	patientAsset, err := stub.GetState(patientNationalID)
+	patient, err := readLivePatient(stub, patientNationalID)
 	if err != nil {
-		return shim.Error("Patient doesn't exist")
+		return errorResponse(err)
 	}
-	err = json.Unmarshal(patientAsset, patient)
+
+	patientKey, err := getPatientKey(stub, patient)
 	if err != nil {
-		return shim.Error("Patient can't be fetched")
+		return errorResponse(err)
 	}
 
-	paillerAsset, err := stub.GetState(patient.PatientFamilyID)
+	disease, err := diseaseService(stub).Get(diseaseIndex)
 	if err != nil {
-		return shim.Error("Pailler Props can't be fetched")
+		return errorResponse(err)
 	}
-	err = json.Unmarshal(paillerAsset, patientProps)
+
+	generations, err := ancestorGenerations(stub, patient.PatientNationalID)
 	if err != nil {
-		return shim.Error("PaillerProps can't be fetched")
+		return errorResponse(err)
 	}
 
-	diseaseAsset, err := stub.GetState(patient.PatientFamilyID)
+	consent, err := consentService(stub).ClientConsent(core.PurposeRiskComputation)
 	if err != nil {
-		return shim.Error("Pailler Props can't be fetched")
+		return errorResponse(err)
 	}
-	err = json.Unmarshal(diseaseAsset, disease)
+	result, explanation, err := riskService(stub).Explain(patientKey.Pk, patient.PatientNationalID, generations, disease.Weight, diseaseIndex, consent)
 	if err != nil {
-		return shim.Error("PaillerProps can't be fetched")
+		return errorResponse(err)
 	}
 
-	diseaseProbability := disease.Achondroplasia
-	result, _ := patientProps.Key.Pk.Encrypt(0)
-
-	fmt.Println(patient.PatientName)
-	fmt.Println(patient.PatientNationalID)
-	fmt.Println(patientProps.PatientFamilyID)
-
-	for i := 0; i < 4; i += 2 {
-
-		level++
-
-		fatherId := ancestorIds[i]
-		fatherPatient := getPatient(stub, fatherId)
-		fmt.Println("Father Name Is : " + fatherPatient.PatientName)
-		probability, _ := fatherPatient.calcualte(patientProps, diseaseProbability, level, diseaseIndex)
-		fmt.Println("Father Calculation Done " + fatherPatient.PatientName)
-		fmt.Println(probability)
-		result, err = patientProps.Key.Pk.Add(result, probability)
-		if err != nil {
-			fmt.Println(err)
-			return shim.Error("Father Calculation Error")
-		}
-		fmt.Println(result)
-
-		motherId := ancestorIds[i+1]
-		motherPatient := getPatient(stub, motherId)
-		fmt.Println("Mother Name Is : " + motherPatient.PatientName)
-		probability, err = motherPatient.calcualte(patientProps, diseaseProbability, level, diseaseIndex)
-		fmt.Println("Mother Calculation Done" + motherPatient.PatientName)
-		if err != nil {
-			return shim.Error("Mother Calculation Error")
-		}
-		result, err = patientProps.Key.Pk.Add(result, probability)
-		fmt.Println(result)
-
+	risk, err := core.DecryptEvidence(patientKey, result, core.MaxEvidence(generations, disease.Weight))
+	if err != nil {
+		return errorResponse(core.InvalidCiphertext("risk cannot be decrypted: %v", err))
 	}
-
-	fmt.Println("******************************")
-	fmt.Println(result)
-	fmt.Println("******************************")
-	fmt.Println(patientProps.Key.Decrypt(result))
-	fmt.Println("******************************")
-
-	return shim.Success(nil)
-}
-
-func getPatient(stub shim.ChaincodeStubInterface, nationalID string) *Patient {
-
-	patient := new(Patient)
-	patientAsset, err := stub.GetState(nationalID)
+	posterior, err := core.ComputePosterior(patientKey.Pk, explanation.Evidence, generations, disease.Weight, disease.Prevalence)
 	if err != nil {
-		fmt.Println("GetState Error")
+		return errorResponse(err)
 	}
-	err = json.Unmarshal(patientAsset, patient)
+	err = core.EmitRiskComputed(newTransaction(stub), patient.PatientNationalID, diseaseIndex, patientKey.Pk.Fingerprint(), result)
 	if err != nil {
-		fmt.Println("Unmarshal Error")
+		return errorResponse(err)
+	}
+
+	riskResult := RiskResult{
+		PatientNationalID: patient.PatientNationalID,
+		DiseaseIndex:      diseaseIndex,
+		EncryptedRisk:     result.Text(16),
+		KeyFingerprint:    patientKey.Pk.Fingerprint(),
+		Risk:              risk,
+		ExcludedRelatives: explanation.Excluded(),
+		Prevalence:        disease.Prevalence,
+		EncryptedLogOdds:  posterior.Ciphertext.Text(16),
+		PriorLogOdds:      posterior.Prior,
+		MaxEvidence:       posterior.MaxEvidence,
+	}
+	riskJSON, err := json.Marshal(riskResult)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
 	}
-	return patient
+	return shim.Success(riskJSON)
 }
 
 func getPaillerKey(stub shim.ChaincodeStubInterface, familyID string) *PaillerKey {
 
-	paillerKey := new(PaillerKey)
-	paillerAsset, err := stub.GetState(familyID)
+	familyKey, _, err := familyKeyService(stub).Key(familyID)
 	if err != nil {
 		fmt.Println("GetState Error")
+		return new(PaillerKey)
 	}
-	err = json.Unmarshal(paillerAsset, paillerKey)
-	if err != nil {
-		fmt.Println("Unmarshal Error")
-	}
-	return paillerKey
-}
-
-func (t *Patient) calcualte(patientProps *PaillerKey, diseaseProbability int, level int, diseaseIndex int) (*big.Int, error) {
-	diseaseValue := t.PatientDiseaseTable[diseaseIndex]
-	diseaseContribution := diseaseProbability / level
-	return patientProps.Key.Pk.MultPlaintext(diseaseValue, int64(diseaseContribution))
+	return &PaillerKey{PatientFamilyID: familyID, Key: familyKey}
 }
@@ -0,0 +1,14 @@
+// Command genchain-sim runs a transaction script against the patient chaincode on an
+// in-memory ledger. See ledgersim.Main for the script format.
+package main
+
+import (
+	"os"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+	"github.com/hyperledger/fabric/integration/chaincode/simple"
+)
+
+func main() {
+	os.Exit(ledgersim.Main(new(simple.Patient), os.Args[1:]))
+}
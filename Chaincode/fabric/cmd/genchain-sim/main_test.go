@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+	"github.com/hyperledger/fabric/integration/chaincode/simple"
+)
+
+// Every scenario runs to the end with its expectations met
+func TestScenarios(t *testing.T) {
+	scenarios, err := filepath.Glob("scenarios/*.txt")
+	if err != nil || len(scenarios) == 0 {
+		t.Fatalf("no scenarios found (%v)", err)
+	}
+	for _, scenario := range scenarios {
+		t.Run(filepath.Base(scenario), func(t *testing.T) {
+			file, err := os.Open(scenario)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			var report strings.Builder
+			failed, err := ledgersim.NewRunner(ledgersim.New(), new(simple.Patient), &report).Run(file)
+			if err != nil || failed > 0 {
+				t.Errorf("%d failures (%v):\n%s", failed, err, report.String())
+			}
+		})
+	}
+}
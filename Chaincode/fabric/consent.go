@@ -0,0 +1,56 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Let the organization with the given MSP ID use the patient's record for the purpose.
+// Arguments are the national ID, purpose, organization and an optional RFC3339 expiry.
+// A guardian grants consent with a patient identity issued for their ward.
+func (t *Patient) grantConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 && len(args) != 4 {
+		return errorResponse(core.WrongArgumentCount("3 or 4"))
+	}
+	expiresAt := ""
+	if len(args) == 4 {
+		expiresAt = args[3]
+	}
+	err := consentService(stub).Grant(args[0], args[1], args[2], expiresAt)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Withdraw a consent given with grantConsent. Arguments are the national ID, purpose
+// and organization.
+func (t *Patient) revokeConsent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		return errorResponse(core.WrongArgumentCount("3"))
+	}
+	err := consentService(stub).Revoke(args[0], args[1], args[2])
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Return every consent the patient has granted, including revoked and expired ones
+func (t *Patient) getConsents(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errorResponse(core.WrongArgumentCount("1"))
+	}
+	consents, err := consentService(stub).List(args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+	consentsJSON, err := json.Marshal(consents)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(consentsJSON)
+}
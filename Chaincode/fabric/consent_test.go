@@ -0,0 +1,49 @@
+package simple
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Revoking a relative's consent takes them out of every risk computation, including the
+// ones under a patient key or a caller's computation key
+func TestRevokedConsentExcludesRelative(t *testing.T) {
+	tb := newTestbed(t)
+	patientSeed := []byte("genchain test patient key seed, 32 bytes or more")
+	tb.mustInvoke(map[string][]byte{core.KeySeedField: patientSeed}, "addPatient", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	tb.mustInvoke(nil, "changeDisease", "115", "0")
+
+	_, patientKey, err := core.DerivePatientKey("130", patientSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, computationKey, err := core.DerivePatientKey("computation", patientSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	risks := func() map[string]int64 {
+		t.Helper()
+		crossFamily := tb.mustInvoke(nil, "calculateCrossFamilyRisk", "130", "0")
+		forKey := tb.mustInvoke(nil, "computeRiskForKey", "130", "0", computationKey.Pk.N.Text(16), computationKey.Pk.G.Text(16))
+		return map[string]int64{
+			"calculateCrossFamilyRisk": decryptHex(t, patientKey, crossFamily),
+			"computeRiskForKey":        decryptHex(t, computationKey, forKey),
+		}
+	}
+
+	// Parent 115 is affected and weighs 100 once they consent
+	tb.mustInvoke(nil, "grantConsent", "115", core.PurposeRiskComputation, "Org1MSP")
+	for function, risk := range risks() {
+		if risk != 100 {
+			t.Errorf("%s with consent = %d, want 100", function, risk)
+		}
+	}
+
+	tb.mustInvoke(nil, "revokeConsent", "115", core.PurposeRiskComputation, "Org1MSP")
+	for function, risk := range risks() {
+		if risk != 0 {
+			t.Errorf("%s after the revocation = %d, want 0", function, risk)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package simple
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/ledgersim"
+)
+
+// endorseTwice endorses the proposal twice under the same transaction ID, as two peers
+// would, and fails the test unless the two endorsements are identical
+func (tb *testbed) endorseTwice(transient map[string][]byte, function string, args ...string) {
+	tb.t.Helper()
+	tx := ledgersim.Transaction{
+		Function:  function,
+		Args:      args,
+		Identity:  tb.admin,
+		Transient: transient,
+		TxID:      "endorsed-" + function,
+		Timestamp: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	first := tb.ledger.Endorse(new(Patient), tx)
+	if first.Response.Status != shim.OK {
+		tb.t.Fatalf("%s %v: status %d: %s", function, args, first.Response.Status, first.Response.Message)
+	}
+	second := tb.ledger.Endorse(new(Patient), tx)
+	if !reflect.DeepEqual(first.Response, second.Response) {
+		tb.t.Errorf("%s %v: the two endorsements returned different responses", function, args)
+	}
+	if !reflect.DeepEqual(first.Writes, second.Writes) || !reflect.DeepEqual(first.PrivateWrites, second.PrivateWrites) {
+		tb.t.Errorf("%s %v: the two endorsements have different write sets", function, args)
+	}
+	if !reflect.DeepEqual(first.Event, second.Event) {
+		tb.t.Errorf("%s %v: the two endorsements set different events", function, args)
+	}
+}
+
+// Every transaction that writes or returns ciphertexts is endorsed identically by every
+// peer holding the same key wrap secret
+func TestEndorsementsAgree(t *testing.T) {
+	tb := newTestbed(t)
+	patientSeed := map[string][]byte{core.KeySeedField: []byte("genchain test patient key seed, 32 bytes or more")}
+	tb.mustInvoke(patientSeed, "addPatient", "Zeynep Kaya", "130", "22", "0", "0", "0")
+	for _, nationalID := range []string{"115", "116", "119", "120"} {
+		tb.mustInvoke(nil, "grantConsent", nationalID, core.PurposeRiskComputation, "Org1MSP")
+	}
+	tb.mustInvoke(nil, "changeDisease", "115", "0")
+	tb.mustInvoke(nil, "setGenotype", "115", "0", "1", "carrier screening")
+	tb.mustInvoke(nil, "setGenotype", "116", "0", "2", "carrier screening")
+
+	tests := []struct {
+		transient map[string][]byte
+		function  string
+		args      []string
+	}{
+		{function: "addPatient", args: []string{"Deniz Kaya", "131", "22", "1", "0", "1"}},
+		{transient: patientSeed, function: "addPatient", args: []string{"Ali Kaya", "132", "22", "0", "1", "0"}},
+		{function: "changeDisease", args: []string{"116", "1"}},
+		{function: "setGenotype", args: []string{"119", "0", "1", "carrier screening"}},
+		{function: "calculateDiseaseProbabilityWithoutTree", args: []string{"130", "0"}},
+		{function: "explainRisk", args: []string{"130", "0"}},
+		{function: "computeRiskProfile", args: []string{"130"}},
+		{function: "computeRecessiveRisk", args: []string{"130", "0"}},
+		{function: "calculateCrossFamilyRisk", args: []string{"130", "0"}},
+		{transient: patientSeed, function: "erasePatient", args: []string{"116", core.ReasonSubjectRequest}},
+	}
+	for _, test := range tests {
+		tb.endorseTwice(test.transient, test.function, test.args...)
+	}
+}
@@ -0,0 +1,53 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// keyCollection is the private data collection holding family private keys. Private
+// data never reaches the ordered blocks, so purging it leaves no copy on the ledger. The
+// keys are derived from seeds the client passes in the transient map, so whoever keeps
+// a seed can derive its key again: purging a key ends the ledger's copy, not the key.
+const keyCollection = "genchainKeys"
+
+// Tombstone a patient and purge the key their records were encrypted under. A patient
+// with their own key only loses that key. For a patient under the family key, the rest
+// of the family is re-keyed under a new family key generated from the "keySeed"
+// transient field.
+func (t *Patient) erasePatient(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(core.WrongArgumentCount("2"))
+	}
+	receipt, err := erasureService(stub).ErasePatient(args[0], args[1])
+	return receiptResponse(receipt, err)
+}
+
+// Tombstone every member of a family and purge the family key
+func (t *Patient) eraseFamily(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(core.WrongArgumentCount("2"))
+	}
+	receipt, err := erasureService(stub).EraseFamily(args[0], args[1])
+	return receiptResponse(receipt, err)
+}
+
+// receiptResponse returns the erasure receipt as the transaction payload
+func receiptResponse(receipt *core.ErasureReceipt, err error) pb.Response {
+	if err != nil {
+		return errorResponse(err)
+	}
+	receiptJSON, err := json.Marshal(receipt)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(receiptJSON)
+}
+
+// isErased reports whether a world state value is a tombstone
+func isErased(value []byte) bool {
+	return core.IsTombstone(value)
+}
@@ -0,0 +1,13 @@
+package simple
+
+import (
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// errorResponse is the response of a failed transaction: its core.ChaincodeError as
+// JSON in the message, with the code's status as the response status
+func errorResponse(err error) pb.Response {
+	chaincodeError := core.AsChaincodeError(err)
+	return pb.Response{Status: chaincodeError.Status, Message: chaincodeError.Error()}
+}
@@ -0,0 +1,74 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Compute the same risk as calculateDiseaseProbabilityWithoutTree and return it broken
+// down by relative: their relationship and generation, the weight applied, whether they
+// were left out and why, and their encrypted contribution. Nothing is decrypted, so the
+// breakdown can only be read by the holder of the patient's key.
+func (t *Patient) explainRisk(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(core.WrongArgumentCount("2"))
+	}
+	diseaseIndex, err := parseDiseaseIndex(args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patient, err := readLivePatient(stub, args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+	weight, err := diseaseWeight(stub, diseaseIndex)
+	if err != nil {
+		return errorResponse(err)
+	}
+	generations, err := ancestorGenerations(stub, patient.PatientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	consent, err := consentService(stub).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	result, explanation, err := riskService(stub).Explain(patientKey.Pk, patient.PatientNationalID, generations, weight, diseaseIndex, consent)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	view := RiskExplanation{
+		PatientNationalID: patient.PatientNationalID,
+		DiseaseIndex:      diseaseIndex,
+		Weight:            weight,
+		KeyFingerprint:    patientKey.Pk.Fingerprint(),
+		EncryptedRisk:     result.Text(16),
+		InitialCiphertext: explanation.Initial.Text(16),
+		Terms:             make([]RiskTerm, len(explanation.Terms)),
+	}
+	for index, term := range explanation.Terms {
+		view.Terms[index] = RiskTerm{
+			PatientNationalID: term.NationalID,
+			Relationship:      term.Relationship,
+			Level:             term.Level,
+			Weight:            term.Weight,
+			Penetrance:        term.Penetrance,
+			Status:            string(term.Status),
+			Contribution:      ciphertextString(term.Contribution),
+		}
+	}
+	viewJSON, err := json.Marshal(view)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(viewJSON)
+}
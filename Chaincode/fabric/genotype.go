@@ -0,0 +1,136 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/events"
+)
+
+// Record the patient's genotype for the disease, encrypted under their key: 0 for a
+// non-carrier, 1 for a carrier and 2 for an affected patient. The disease flag is kept
+// in step with it, set for an affected patient and cleared otherwise.
+func (t *Patient) setGenotype(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 && len(args) != 4 {
+		return errorResponse(core.WrongArgumentCount("3 or 4"))
+	}
+	patientNationalID := args[0]
+	diseaseIndex, err := parseDiseaseIndex(args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+	genotype, err := parseGenotype(args[2])
+	if err != nil {
+		return errorResponse(err)
+	}
+	reason := ""
+	if len(args) == 4 {
+		reason = args[3]
+	}
+
+	patient, err := readLivePatient(stub, patientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	patient.PatientGenotypes[diseaseIndex], err = encrypt(stub, patientKey.Pk, int64(genotype), core.GenotypeSlot(patientNationalID, diseaseIndex))
+	if err != nil {
+		return errorResponse(err)
+	}
+	affected := int64(0)
+	if genotype == core.GenotypeAffected {
+		affected = 1
+	}
+	patient.PatientDiseaseTable[diseaseIndex], err = encrypt(stub, patientKey.Pk, affected, core.DiseaseSlot(patientNationalID, diseaseIndex))
+	if err != nil {
+		return errorResponse(err)
+	}
+	patient.KeyFingerprint = patientKey.Pk.Fingerprint()
+
+	err = putPatient(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = auditService(stub).Record(patientNationalID, core.AuditGenotypeChange, diseaseIndex, reason)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	event := events.New(events.GenotypeChanged).WithDiseaseIndex(diseaseIndex)
+	event.PatientNationalIDs = []string{patientNationalID}
+	event.PatientFamilyID = patient.PatientFamilyID
+	event.KeyFingerprint = patient.KeyFingerprint
+	event.AddCiphertext(patientNationalID, diseaseIndex, patient.PatientGenotypes[diseaseIndex])
+	err = core.EmitEvent(newTransaction(stub), event)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// Compute, for each of the patient's parents, the encrypted chance in percent that they
+// pass the allele of a recessive disease on: 50% per copy they carry. Nothing is
+// decrypted; the patient is affected with the product of the chances over 100. The
+// parents are the recorded ones, or the first generation of the fixed pedigree. It fails
+// unless both parents have consented and have a recorded genotype.
+func (t *Patient) computeRecessiveRisk(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(core.WrongArgumentCount("2"))
+	}
+	diseaseIndex, err := parseDiseaseIndex(args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patient, err := readLivePatient(stub, args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+	generations, err := ancestorGenerations(stub, patient.PatientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	parents := []string{}
+	if len(generations) > 0 {
+		parents = generations[0]
+	}
+	consent, err := consentService(stub).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	transmissions, err := riskService(stub).RecessiveRisk(patientKey.Pk, patient.PatientNationalID, parents, diseaseIndex, consent)
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = core.EmitRiskComputed(newTransaction(stub), patient.PatientNationalID, diseaseIndex, patientKey.Pk.Fingerprint(), transmissions...)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	encrypted := make([]string, len(transmissions))
+	for index, transmission := range transmissions {
+		encrypted[index] = transmission.Text(16)
+	}
+	riskResult := RecessiveRiskResult{
+		PatientNationalID:      patient.PatientNationalID,
+		DiseaseIndex:           diseaseIndex,
+		Parents:                parents,
+		EncryptedTransmissions: encrypted,
+		KeyFingerprint:         patientKey.Pk.Fingerprint(),
+	}
+	riskJSON, err := json.Marshal(riskResult)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(riskJSON)
+}
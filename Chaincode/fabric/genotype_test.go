@@ -0,0 +1,87 @@
+package simple
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Each parent passes the allele on with a chance of 50% per copy, so two carrier
+// parents have an affected child one time in four. Without both parents' genotypes
+// there is no risk to compute.
+func TestComputeRecessiveRisk(t *testing.T) {
+	tests := []struct {
+		name       string
+		parents    []string
+		consenting []string
+		genotypes  map[string]int
+		risk       int64
+		want       string
+	}{
+		{name: "no genotypes", genotypes: map[string]int{}, want: "115, 116"},
+		{name: "one genotype", genotypes: map[string]int{"115": 1}, want: "parents 116 "},
+		{name: "parent without consent", consenting: []string{"115"}, genotypes: map[string]int{"115": 1, "116": 1}, want: "parents 116 "},
+		{name: "one recorded parent", parents: []string{"115"}, genotypes: map[string]int{"115": 1, "116": 1}, want: "1 known parents"},
+		{name: "two carriers", genotypes: map[string]int{"115": 1, "116": 1}, risk: 25},
+		{name: "carrier and affected", genotypes: map[string]int{"115": 1, "116": 2}, risk: 50},
+		{name: "both affected", genotypes: map[string]int{"115": 2, "116": 2}, risk: 100},
+		{name: "non-carrier and affected", genotypes: map[string]int{"115": 0, "116": 2}, risk: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tb := newTestbed(t)
+			tb.mustInvoke(nil, "addPatient", "Zeynep Kaya", "130", "22", "0", "0", "0")
+			for _, nationalID := range test.parents {
+				tb.mustInvoke(nil, "addParent", "130", nationalID, "father")
+			}
+			consenting := test.consenting
+			if consenting == nil {
+				consenting = []string{"115", "116"}
+			}
+			for _, nationalID := range consenting {
+				tb.mustInvoke(nil, "grantConsent", nationalID, core.PurposeRiskComputation, "Org1MSP")
+			}
+			for nationalID, genotype := range test.genotypes {
+				tb.mustInvoke(nil, "setGenotype", nationalID, "0", strconv.Itoa(genotype), "carrier screening")
+			}
+
+			result := tb.invoke(nil, "computeRecessiveRisk", "130", "0")
+			checkResult(t, result, test.want)
+			if test.want != "" {
+				return
+			}
+			var risk RecessiveRiskResult
+			err := json.Unmarshal(result.Response.Payload, &risk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			transmissions := make([]*big.Int, len(risk.EncryptedTransmissions))
+			for index, transmission := range risk.EncryptedTransmissions {
+				transmissions[index], _ = new(big.Int).SetString(transmission, 16)
+			}
+			percent, err := core.RecessiveRiskPercent(familyKey(t, "22"), transmissions)
+			if err != nil || len(transmissions) != 2 || percent != test.risk {
+				t.Errorf("risk = %d (%v) from %d chances, want %d from 2", percent, err, len(transmissions), test.risk)
+			}
+		})
+	}
+}
+
+func TestSetGenotype(t *testing.T) {
+	tb := newTestbed(t)
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"115", "0", "1", "carrier screening"}},
+		{args: []string{"115", "0", "3", "carrier screening"}, want: "genotype"},
+		{args: []string{"115", "3", "1", "carrier screening"}, want: "diseaseIndex"},
+		{args: []string{"999", "0", "1", "carrier screening"}, want: "NOT_FOUND"},
+	}
+	for _, test := range tests {
+		checkResult(t, tb.invoke(nil, "setGenotype", test.args...), test.want)
+	}
+}
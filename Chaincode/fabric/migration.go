@@ -0,0 +1,111 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// MigrationReport lists what migrateLedger moved into the namespaced keys
+type MigrationReport struct {
+	Patients   []string `json:"patients"`
+	FamilyKeys []string `json:"familyKeys"`
+	Diseases   bool     `json:"diseases"`
+	Skipped    []string `json:"skipped"`
+}
+
+// Move records written under flat keys by earlier versions of the chaincode into their
+// namespaces: patients and tombstones under "patient" with a "family" membership entry,
+// family keys into the key collection under "familykey", and the disease table under
+// "disease". Unrecognised keys are left in place and reported. Running it again is a no-op.
+func (t *Patient) migrateLedger(stub shim.ChaincodeStubInterface) pb.Response {
+	// Range queries only return simple keys, which are exactly the legacy records
+	resultsIterator, err := stub.GetStateByRange("", "")
+	if err != nil {
+		return errorResponse(err)
+	}
+	defer resultsIterator.Close()
+
+	report := MigrationReport{Patients: []string{}, FamilyKeys: []string{}, Skipped: []string{}}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return errorResponse(err)
+		}
+
+		migrated, err := migrateRecord(stub, queryResponse.Key, queryResponse.Value, &report)
+		if err != nil {
+			return errorResponse(err)
+		}
+		if !migrated {
+			report.Skipped = append(report.Skipped, queryResponse.Key)
+			continue
+		}
+		err = stub.DelState(queryResponse.Key)
+		if err != nil {
+			return errorResponse(core.InternalError("failed to delete state: %v", err))
+		}
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(reportJSON)
+}
+
+// migrateRecord stores one legacy record under its namespace and reports whether it was recognised
+func migrateRecord(stub shim.ChaincodeStubInterface, key string, value []byte, report *MigrationReport) (bool, error) {
+	if key == "DiseaseTable" {
+		var table Diseases
+		err := json.Unmarshal(value, &table)
+		if err != nil {
+			return false, err
+		}
+		report.Diseases = true
+		return true, diseaseService(stub).PutTable(table.SickleCellDisease, table.Type2Diabetes, table.Achondroplasia)
+	}
+
+	var paillerKey PaillerKey
+	if json.Unmarshal(value, &paillerKey) == nil && paillerKey.Key != nil && paillerKey.Key.Pk != nil {
+		familyKeys := familyKeyService(stub)
+		record, err := familyKeys.Record(paillerKey.PatientFamilyID)
+		if err != nil {
+			return false, err
+		}
+		// A family re-keyed since the legacy copy was written keeps its current key
+		if record == nil {
+			err = familyKeys.PutLegacy(paillerKey.PatientFamilyID, paillerKey.Key)
+			if err != nil {
+				return false, err
+			}
+		}
+		report.FamilyKeys = append(report.FamilyKeys, paillerKey.PatientFamilyID)
+		return true, nil
+	}
+
+	var patient Patient
+	if json.Unmarshal(value, &patient) != nil || patient.PatientNationalID == "" {
+		return false, nil
+	}
+	report.Patients = append(report.Patients, patient.PatientNationalID)
+	if !isErased(value) {
+		err := putPatient(stub, &patient)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		// Tombstones are copied as they are and are not added to the family index
+		patientID, err := stub.CreateCompositeKey(core.PatientNamespace, []string{patient.PatientNationalID})
+		if err != nil {
+			return false, err
+		}
+		err = stub.PutState(patientID, value)
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, auditService(stub).Record(patient.PatientNationalID, core.AuditMigrate, core.NoDiseaseSlot, "")
+}
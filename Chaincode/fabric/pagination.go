@@ -0,0 +1,50 @@
+package simple
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// PatientPage is one page of a patient listing. Pass Bookmark to the next call to
+// continue; an empty bookmark means the listing is complete.
+type PatientPage struct {
+	Records             []PatientResult `json:"records"`
+	FetchedRecordsCount int32           `json:"fetchedRecordsCount"`
+	Bookmark            string          `json:"bookmark"`
+}
+
+// Read a page of patients. Arguments are the page size, the bookmark returned by the
+// previous page (empty for the first) and an optional family ID to list only that
+// family. Erased patients are counted as fetched but left out of the records.
+func (t *Patient) readPatientsPage(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 2 || len(args) > 3 {
+		return errorResponse(core.WrongArgumentCount("2 or 3"))
+	}
+	pageSize, err := strconv.ParseInt(args[0], 10, 32)
+	if err != nil {
+		return errorResponse(core.InvalidArgument("pageSize", "must be between 1 and %d", core.MaxPageSize))
+	}
+	familyID := ""
+	if len(args) == 3 {
+		familyID = args[2]
+	}
+
+	result, err := patientService(stub).Page(familyID, int32(pageSize), args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+	page := PatientPage{Records: []PatientResult{}, FetchedRecordsCount: result.FetchedRecordsCount, Bookmark: result.Bookmark}
+	for _, record := range result.Patients {
+		page.Records = append(page.Records, PatientResult{Key: record.NationalID, Record: newPatientView(patientFromCore(record))})
+	}
+
+	pageJSON, err := json.Marshal(page)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(pageJSON)
+}
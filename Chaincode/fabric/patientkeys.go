@@ -0,0 +1,76 @@
+package simple
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// keyScopePatient marks a patient whose disease table is encrypted under their own key.
+// Records without a key scope are encrypted under the family key.
+const keyScopePatient = core.KeyScopePatient
+
+// Compute the patient's encrypted risk under a one-time computation key supplied by the
+// caller as hex N and g. The endorsing peer re-encrypts each relative's contribution from
+// the relative's own key under the computation key, so the caller never needs the family
+// key; the peer itself sees the contributions in the clear. Relatives that have not
+// consented to risk computation by the requesting organization are left out.
+func (t *Patient) computeRiskForKey(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 4 {
+		return errorResponse(core.WrongArgumentCount("4"))
+	}
+
+	patientNationalID := args[0]
+	diseaseIndex, err := parseDiseaseIndex(args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+	target, err := Pailler.NewPublicKey(args[2], args[3])
+	if err != nil {
+		return errorResponse(core.InvalidArgument("computationKey", "invalid computation key: %v", err))
+	}
+
+	patient, err := readLivePatient(stub, patientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	diseaseProbability, err := diseaseWeight(stub, diseaseIndex)
+	if err != nil {
+		return errorResponse(err)
+	}
+	generations, err := ancestorGenerations(stub, patient.PatientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	consent, err := consentService(stub).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return errorResponse(err)
+	}
+	result, _, err := riskService(stub).Compute(target, patient.PatientNationalID, generations, diseaseProbability, diseaseIndex, consent)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	err = core.EmitRiskComputed(newTransaction(stub), patient.PatientNationalID, diseaseIndex, target.Fingerprint(), result)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success([]byte(result.Text(16)))
+}
+
+// getPatientKey returns the key the patient's disease table is encrypted under
+func getPatientKey(stub shim.ChaincodeStubInterface, patient *Patient) (*Pailler.PrivateKey, error) {
+	return keyService(stub).PatientKey(patient.toCore())
+}
+
+// diseaseWeight returns the weight applied to relatives affected by the disease
+func diseaseWeight(stub shim.ChaincodeStubInterface, diseaseIndex int) (int, error) {
+	disease, err := diseaseService(stub).Get(diseaseIndex)
+	if err != nil {
+		return 0, err
+	}
+	return disease.Weight, nil
+}
@@ -0,0 +1,122 @@
+package simple
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// maxGenerations is how far up the pedigree risk computations walk
+const maxGenerations = 2
+
+// Edge links a child to one of their parents. The two may belong to different
+// families and therefore have their records encrypted under different keys.
+type Edge struct {
+	ChildNationalID  string `json:"childNationalID"`
+	ParentNationalID string `json:"parentNationalID"`
+	Relation         string `json:"relation"`
+}
+
+// Record that the parent is the child's father or mother
+func (t *Patient) addParent(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 3 {
+		return errorResponse(core.WrongArgumentCount("3"))
+	}
+	childNationalID := args[0]
+	parentNationalID := args[1]
+	relation := args[2]
+
+	if relation != "father" && relation != "mother" {
+		return errorResponse(core.InvalidArgument("relation", "must be father or mother"))
+	}
+	if childNationalID == parentNationalID {
+		return errorResponse(core.InvalidArgument("parentNationalID", "a patient cannot be their own parent"))
+	}
+	for _, nationalID := range []string{childNationalID, parentNationalID} {
+		if _, err := readLivePatient(stub, nationalID); err != nil {
+			return errorResponse(err)
+		}
+	}
+
+	edge := Edge{ChildNationalID: childNationalID, ParentNationalID: parentNationalID, Relation: relation}
+	edgeJSON, err := json.Marshal(edge)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	edgeID, err := stub.CreateCompositeKey(core.EdgeNamespace, []string{childNationalID, parentNationalID})
+	if err != nil {
+		return errorResponse(err)
+	}
+	err = stub.PutState(edgeID, edgeJSON)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot put edge to the ledger: %v", err))
+	}
+	return shim.Success(nil)
+}
+
+// Compute the patient's encrypted risk over their recorded ancestors, whichever families
+// they belong to, and return it as hex under the patient's own key. Contributions from
+// ancestors under another key are re-encrypted under the patient's key by the endorsing
+// peer, which sees them in the clear, before they are added. Relatives that have not
+// consented to risk computation by the requesting organization are left out.
+func (t *Patient) calculateCrossFamilyRisk(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 2 {
+		return errorResponse(core.WrongArgumentCount("2"))
+	}
+	diseaseIndex, err := parseDiseaseIndex(args[1])
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	patient, err := readLivePatient(stub, args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+	target := patientKey.Pk
+
+	diseaseProbability, err := diseaseWeight(stub, diseaseIndex)
+	if err != nil {
+		return errorResponse(err)
+	}
+	generations, err := ancestorGenerations(stub, patient.PatientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	consent, err := consentService(stub).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return errorResponse(err)
+	}
+	result, _, err := riskService(stub).Compute(target, patient.PatientNationalID, generations, diseaseProbability, diseaseIndex, consent)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	err = core.EmitRiskComputed(newTransaction(stub), patient.PatientNationalID, diseaseIndex, target.Fingerprint(), result)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return shim.Success([]byte(result.Text(16)))
+}
+
+// ancestorGenerations walks the parent edges up to maxGenerations and returns the
+// ancestors of each generation, parents first. Patients without recorded parents fall
+// back to the fixed ancestorIds pedigree.
+func ancestorGenerations(stub shim.ChaincodeStubInterface, nationalID string) ([][]string, error) {
+	return patientService(stub).Generations(nationalID, maxGenerations, core.FixedGenerations(ancestorIds))
+}
+
+// readLivePatient returns the patient stored under the ID, failing for missing or erased records
+func readLivePatient(stub shim.ChaincodeStubInterface, nationalID string) (*Patient, error) {
+	record, err := patientService(stub).Get(nationalID)
+	if err != nil {
+		return nil, err
+	}
+	return patientFromCore(record), nil
+}
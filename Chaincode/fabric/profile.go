@@ -0,0 +1,94 @@
+package simple
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Compute the patient's encrypted risk of several diseases in one traversal of their
+// relatives, each risk being the one calculateDiseaseProbabilityWithoutTree would
+// compute. The arguments after the patient's national ID are disease indexes; without
+// any, every registered disease is computed. Nothing is decrypted: the risks are
+// returned under the patient's key, keyed by disease index.
+func (t *Patient) computeRiskProfile(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) < 1 {
+		return errorResponse(core.WrongArgumentCount("at least 1"))
+	}
+	diseases, err := profileDiseases(stub, args[1:])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patient, err := readLivePatient(stub, args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+	patientKey, err := getPatientKey(stub, patient)
+	if err != nil {
+		return errorResponse(err)
+	}
+	generations, err := ancestorGenerations(stub, patient.PatientNationalID)
+	if err != nil {
+		return errorResponse(err)
+	}
+	consent, err := consentService(stub).ClientConsent(core.PurposeRiskComputation)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	weights := make([]core.DiseaseWeight, len(diseases))
+	for index, disease := range diseases {
+		weights[index] = core.DiseaseWeight{Index: disease.Index, Weight: disease.Weight}
+	}
+	risks, excluded, err := riskService(stub).ComputeProfile(patientKey.Pk, patient.PatientNationalID, generations, weights, consent)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	profile := RiskProfile{
+		PatientNationalID: patient.PatientNationalID,
+		KeyFingerprint:    patientKey.Pk.Fingerprint(),
+		Diseases:          make(map[string]string, len(diseases)),
+		EncryptedRisks:    make(map[string]string, len(diseases)),
+		ExcludedRelatives: excluded,
+	}
+	for _, disease := range diseases {
+		diseaseIndex := strconv.Itoa(disease.Index)
+		profile.Diseases[diseaseIndex] = disease.Name
+		profile.EncryptedRisks[diseaseIndex] = risks[disease.Index].Text(16)
+	}
+	profileJSON, err := json.Marshal(profile)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(profileJSON)
+}
+
+// profileDiseases returns the diseases of the given indexes, or every registered disease
+// when there are none
+func profileDiseases(stub shim.ChaincodeStubInterface, args []string) ([]*core.Disease, error) {
+	if len(args) == 0 {
+		return diseaseService(stub).List()
+	}
+	diseases := make([]*core.Disease, len(args))
+	seen := map[int]bool{}
+	for index, arg := range args {
+		diseaseIndex, err := parseDiseaseIndex(arg)
+		if err != nil {
+			return nil, err
+		}
+		if seen[diseaseIndex] {
+			return nil, core.InvalidArgument("diseaseIndex", "disease %d is listed twice", diseaseIndex)
+		}
+		seen[diseaseIndex] = true
+		disease, err := diseaseService(stub).Get(diseaseIndex)
+		if err != nil {
+			return nil, err
+		}
+		diseases[index] = disease
+	}
+	return diseases, nil
+}
@@ -0,0 +1,195 @@
+package simple
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// PatientFilter is the restricted selector accepted by queryPatients. Empty fields do
+// not filter. HasConsent selects the patients with, or without, a valid research
+// aggregation consent for the requesting organization.
+type PatientFilter struct {
+	PatientFamilyID string `json:"patientFamilyID,omitempty"`
+	CreatedAfter    string `json:"createdAfter,omitempty"`
+	HasConsent      *bool  `json:"hasConsent,omitempty"`
+}
+
+// Query the live patients matching the filter given as JSON, e.g.
+// {"patientFamilyID":"22","createdAfter":"2024-01-01T00:00:00Z","hasConsent":true}.
+// On a peer whose core.StateDatabaseEnv is CouchDB the filter is turned into a selector
+// served by the indexes in META-INF/statedb/couchdb/indexes; on LevelDB the same filter
+// is applied to a range scan.
+// Consent lives in the consent registry rather than on the patient record, so the
+// hasConsent filter is applied to the results.
+func (t *Patient) queryPatients(stub shim.ChaincodeStubInterface, args []string) pb.Response {
+	if len(args) != 1 {
+		return errorResponse(core.WrongArgumentCount("1"))
+	}
+	filter, err := parsePatientFilter(args[0])
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	var patients []*Patient
+	if !core.RichQueries() {
+		patients, err = queryPatientsByRange(stub, filter)
+		if err != nil {
+			return errorResponse(err)
+		}
+	} else {
+		selector, err := filter.selector()
+		if err != nil {
+			return errorResponse(err)
+		}
+		resultsIterator, err := stub.GetQueryResult(selector)
+		if err != nil {
+			return errorResponse(err)
+		}
+		defer resultsIterator.Close()
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				return errorResponse(err)
+			}
+			patient := new(Patient)
+			err = json.Unmarshal(queryResponse.Value, patient)
+			if err != nil {
+				return errorResponse(core.InternalError("patient can't be fetched: %v", err))
+			}
+			patients = append(patients, patient)
+		}
+	}
+	patients, err = filter.consented(stub, patients)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	queryResults := []PatientResult{}
+	for _, patient := range patients {
+		queryResults = append(queryResults, PatientResult{Key: patient.PatientNationalID, Record: newPatientView(patient)})
+	}
+	resultsJSON, err := json.Marshal(queryResults)
+	if err != nil {
+		return errorResponse(core.InternalError("cannot marshal the response"))
+	}
+	return shim.Success(resultsJSON)
+}
+
+func parsePatientFilter(filterJSON string) (*PatientFilter, error) {
+	filter := new(PatientFilter)
+	if filterJSON == "" {
+		return filter, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(filterJSON))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(filter)
+	if err != nil {
+		return nil, core.InvalidArgument("filter", "invalid patient filter: %v", err)
+	}
+	if filter.CreatedAfter != "" {
+		createdAfter, err := time.Parse(time.RFC3339, filter.CreatedAfter)
+		if err != nil {
+			return nil, core.InvalidArgument("createdAfter", "must be an RFC3339 time")
+		}
+		// Stored times are UTC without fractions, which compare correctly as strings
+		filter.CreatedAfter = createdAfter.UTC().Format(time.RFC3339)
+	}
+	return filter, nil
+}
+
+// selector builds the CouchDB query for the filter. Only the fields of the filter can
+// reach the selector, so clients cannot run arbitrary queries.
+func (filter *PatientFilter) selector() (string, error) {
+	selector := map[string]interface{}{"docType": core.PatientDocType}
+	if filter.PatientFamilyID != "" {
+		selector["patientFamilyID"] = filter.PatientFamilyID
+	}
+	if filter.CreatedAfter != "" {
+		selector["createdAt"] = map[string]string{"$gt": filter.CreatedAfter}
+	}
+	query, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return "", err
+	}
+	return string(query), nil
+}
+
+// matches applies the filter to a patient read by a range scan
+func (filter *PatientFilter) matches(patient *Patient) bool {
+	if filter.PatientFamilyID != "" && patient.PatientFamilyID != filter.PatientFamilyID {
+		return false
+	}
+	if filter.CreatedAfter != "" && patient.CreatedAt <= filter.CreatedAfter {
+		return false
+	}
+	return true
+}
+
+// consented applies the hasConsent filter to patients read by a query or a range scan
+func (filter *PatientFilter) consented(stub shim.ChaincodeStubInterface, patients []*Patient) ([]*Patient, error) {
+	if filter.HasConsent == nil {
+		return patients, nil
+	}
+	hasConsent, err := consentService(stub).ClientConsent(core.PurposeResearchAggregation)
+	if err != nil {
+		return nil, err
+	}
+	var consented []*Patient
+	for _, patient := range patients {
+		valid, err := hasConsent(patient.PatientNationalID)
+		if err != nil {
+			return nil, err
+		}
+		if valid == *filter.HasConsent {
+			consented = append(consented, patient)
+		}
+	}
+	return consented, nil
+}
+
+// queryPatientsByRange serves queryPatients on LevelDB. A family filter scans only the
+// family's membership index.
+func queryPatientsByRange(stub shim.ChaincodeStubInterface, filter *PatientFilter) ([]*Patient, error) {
+	var patients []*Patient
+	if filter.PatientFamilyID != "" {
+		members, err := familyMembers(stub, filter.PatientFamilyID)
+		if err != nil {
+			return nil, err
+		}
+		patients = members
+	} else {
+		resultsIterator, err := stub.GetStateByPartialCompositeKey(core.PatientNamespace, []string{})
+		if err != nil {
+			return nil, err
+		}
+		defer resultsIterator.Close()
+		for resultsIterator.HasNext() {
+			queryResponse, err := resultsIterator.Next()
+			if err != nil {
+				return nil, err
+			}
+			if isErased(queryResponse.Value) {
+				continue
+			}
+			patient := new(Patient)
+			err = json.Unmarshal(queryResponse.Value, patient)
+			if err != nil {
+				return nil, err
+			}
+			patients = append(patients, patient)
+		}
+	}
+
+	var matching []*Patient
+	for _, patient := range patients {
+		if filter.matches(patient) {
+			matching = append(matching, patient)
+		}
+	}
+	return matching, nil
+}
@@ -0,0 +1,68 @@
+package simple
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// Each field of the filter reaches the selector, except hasConsent, which is applied to
+// the results
+func TestPatientFilterSelector(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{filter: ``, want: `{"selector":{"docType":"patient"}}`},
+		{filter: `{"patientFamilyID":"22"}`, want: `{"selector":{"docType":"patient","patientFamilyID":"22"}}`},
+		{filter: `{"createdAfter":"2026-01-01T03:00:00+03:00"}`, want: `{"selector":{"createdAt":{"$gt":"2026-01-01T00:00:00Z"},"docType":"patient"}}`},
+		{filter: `{"hasConsent":true}`, want: `{"selector":{"docType":"patient"}}`},
+	}
+	for _, test := range tests {
+		filter, err := parsePatientFilter(test.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", test.filter, err)
+		}
+		selector, err := filter.selector()
+		if err != nil || selector != test.want {
+			t.Errorf("selector of %s = %s (%v), want %s", test.filter, selector, err, test.want)
+		}
+	}
+}
+
+// queryPatients finds the same patients with a CouchDB selector and with the range scan
+// it falls back to on LevelDB
+func TestQueryPatients(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: `{"patientFamilyID":"20"}`, want: []string{"111", "112", "113"}},
+		{filter: `{"createdAfter":"2026-01-01T00:00:00Z"}`, want: []string{"130"}},
+		{filter: `{"patientFamilyID":"22","hasConsent":true}`, want: []string{"119"}},
+	}
+	for _, database := range []string{"goleveldb", "CouchDB"} {
+		t.Run(database, func(t *testing.T) {
+			tb := newTestbed(t)
+			t.Setenv(core.StateDatabaseEnv, database)
+			tb.ledger.CouchDB = database == "CouchDB"
+			tb.mustInvoke(nil, "addPatient", "Zeynep Kaya", "130", "22", "0", "0", "0")
+			tb.mustInvoke(nil, "grantConsent", "119", core.PurposeResearchAggregation, "Org1MSP")
+
+			for _, test := range tests {
+				var results []PatientResult
+				tb.mustDecode(&results, "queryPatients", test.filter)
+				got := []string{}
+				for _, result := range results {
+					got = append(got, result.Key)
+				}
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, test.want) {
+					t.Errorf("queryPatients(%s) = %v, want %v", test.filter, got, test.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package simple
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+	Pailler "github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/paillerCrypto"
+)
+
+// stubStore is the core.Store over the transaction's world state, or over a private
+// data collection when collection is set
+type stubStore struct {
+	stub       shim.ChaincodeStubInterface
+	collection string
+}
+
+func newStore(stub shim.ChaincodeStubInterface) *stubStore {
+	return &stubStore{stub: stub}
+}
+
+func newPrivateStore(stub shim.ChaincodeStubInterface, collection string) *stubStore {
+	return &stubStore{stub: stub, collection: collection}
+}
+
+func (s *stubStore) Get(key core.Key) ([]byte, error) {
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	var value []byte
+	if s.collection == "" {
+		value, err = s.stub.GetState(id)
+	} else {
+		value, err = s.stub.GetPrivateData(s.collection, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", key.Namespace, err)
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (s *stubStore) Put(key core.Key, value []byte) error {
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return err
+	}
+	if s.collection == "" {
+		err = s.stub.PutState(id, value)
+	} else {
+		err = s.stub.PutPrivateData(s.collection, id, value)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put %s. %v", key.Namespace, err)
+	}
+	return nil
+}
+
+func (s *stubStore) Delete(key core.Key) error {
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return err
+	}
+	if s.collection == "" {
+		return s.stub.DelState(id)
+	}
+	return s.stub.DelPrivateData(s.collection, id)
+}
+
+// Purge is only supported for private data, since the world state keeps its history in
+// the blocks
+func (s *stubStore) Purge(key core.Key) error {
+	if s.collection == "" {
+		return fmt.Errorf("the world state cannot purge %s", key.Namespace)
+	}
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return err
+	}
+	err = s.stub.PurgePrivateData(s.collection, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge %s. %v", key.Namespace, err)
+	}
+	return nil
+}
+
+func (s *stubStore) Range(prefix core.Key, visit func(key core.Key, value []byte) error) error {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var err error
+	if s.collection == "" {
+		resultsIterator, err = s.stub.GetStateByPartialCompositeKey(prefix.Namespace, prefix.Attributes)
+	} else {
+		resultsIterator, err = s.stub.GetPrivateDataByPartialCompositeKey(s.collection, prefix.Namespace, prefix.Attributes)
+	}
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		namespace, attributes, err := s.stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return err
+		}
+		err = visit(core.NewKey(namespace, attributes...), queryResponse.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RangePage is only supported for the world state
+func (s *stubStore) RangePage(prefix core.Key, pageSize int32, bookmark string, visit func(key core.Key, value []byte) error) (int32, string, error) {
+	if s.collection != "" {
+		return 0, "", fmt.Errorf("private data collection %s cannot be paged", s.collection)
+	}
+	resultsIterator, metadata, err := s.stub.GetStateByPartialCompositeKeyWithPagination(prefix.Namespace, prefix.Attributes, pageSize, bookmark)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return 0, "", err
+		}
+		namespace, attributes, err := s.stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, "", err
+		}
+		err = visit(core.NewKey(namespace, attributes...), queryResponse.Value)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	return metadata.FetchedRecordsCount, metadata.Bookmark, nil
+}
+
+// History is only kept for the world state
+func (s *stubStore) History(key core.Key) ([]core.Version, error) {
+	if s.collection != "" {
+		return nil, fmt.Errorf("private data collection %s keeps no history", s.collection)
+	}
+	id, err := s.stub.CreateCompositeKey(key.Namespace, key.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := s.stub.GetHistoryForKey(id)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var versions []core.Version
+	for resultsIterator.HasNext() {
+		modification, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		version := core.Version{TxID: modification.TxId, Value: modification.Value, IsDelete: modification.IsDelete}
+		if modification.Timestamp != nil {
+			version.Timestamp = time.Unix(modification.Timestamp.Seconds, int64(modification.Timestamp.Nanos)).UTC().Format(time.RFC3339)
+		}
+		versions = append(versions, version)
+	}
+
+	// The ledger returns the history newest first
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+	return versions, nil
+}
+
+// stubTransaction is the core.Transaction the chaincode runs in
+type stubTransaction struct {
+	stub shim.ChaincodeStubInterface
+}
+
+func newTransaction(stub shim.ChaincodeStubInterface) *stubTransaction {
+	return &stubTransaction{stub: stub}
+}
+
+func (t *stubTransaction) TxID() string {
+	return t.stub.GetTxID()
+}
+
+func (t *stubTransaction) Time() (time.Time, error) {
+	timestamp, err := t.stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read transaction timestamp: %v", err)
+	}
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
+
+func (t *stubTransaction) Client() (core.Identity, error) {
+	return cid.New(t.stub)
+}
+
+func (t *stubTransaction) Transient() (map[string][]byte, error) {
+	transient, err := t.stub.GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	return transient, nil
+}
+
+func (t *stubTransaction) SetEvent(name string, payload []byte) error {
+	return t.stub.SetEvent(name, payload)
+}
+
+// patientCodec stores core patients in this chaincode's Patient form
+type patientCodec struct{}
+
+func (patientCodec) Decode(value []byte) (*core.Patient, error) {
+	var patient Patient
+	err := json.Unmarshal(value, &patient)
+	if err != nil {
+		return nil, err
+	}
+	return patient.toCore(), nil
+}
+
+func (patientCodec) Encode(record *core.Patient) ([]byte, error) {
+	return json.Marshal(patientFromCore(record))
+}
+
+func (t *Patient) toCore() *core.Patient {
+	return &core.Patient{
+		Name:           t.PatientName,
+		NationalID:     t.PatientNationalID,
+		FamilyID:       t.PatientFamilyID,
+		DiseaseTable:   t.PatientDiseaseTable[:],
+		Genotypes:      t.PatientGenotypes[:],
+		BirthYear:      t.PatientBirthYear,
+		KeyScope:       t.KeyScope,
+		KeyFingerprint: t.KeyFingerprint,
+		CreatedAt:      t.CreatedAt,
+		UpdatedAt:      t.UpdatedAt,
+	}
+}
+
+func patientFromCore(record *core.Patient) *Patient {
+	patient := &Patient{
+		PatientName:       record.Name,
+		PatientNationalID: record.NationalID,
+		PatientFamilyID:   record.FamilyID,
+		PatientBirthYear:  record.BirthYear,
+		KeyScope:          record.KeyScope,
+		KeyFingerprint:    record.KeyFingerprint,
+		DocType:           core.PatientDocType,
+		CreatedAt:         record.CreatedAt,
+		UpdatedAt:         record.UpdatedAt,
+	}
+	copy(patient.PatientDiseaseTable[:], record.DiseaseTable)
+	copy(patient.PatientGenotypes[:], record.Genotypes)
+	return patient
+}
+
+// getPatientState returns the stored patient or tombstone, or nil when there is none
+func getPatientState(stub shim.ChaincodeStubInterface, patientNationalID string) ([]byte, error) {
+	return newStore(stub).Get(core.NewKey(core.PatientNamespace, patientNationalID))
+}
+
+// putPatient stamps and stores the patient and adds them to their family's membership index
+func putPatient(stub shim.ChaincodeStubInterface, patient *Patient) error {
+	timestamp, err := core.Timestamp(newTransaction(stub))
+	if err != nil {
+		return err
+	}
+	record := patient.toCore()
+	err = patientService(stub).Put(record, timestamp)
+	if err != nil {
+		return err
+	}
+	patient.DocType = core.PatientDocType
+	patient.CreatedAt = record.CreatedAt
+	patient.UpdatedAt = record.UpdatedAt
+	return nil
+}
+
+// deletePatientState removes the patient and their family membership
+func deletePatientState(stub shim.ChaincodeStubInterface, patientNationalID string, patientFamilyID string) error {
+	return patientService(stub).Delete(patientNationalID, patientFamilyID)
+}
+
+// familyMembers returns every patient of the family that has not been erased
+func familyMembers(stub shim.ChaincodeStubInterface, familyID string) ([]*Patient, error) {
+	records, err := patientService(stub).FamilyMembers(familyID)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*Patient, len(records))
+	for index, record := range records {
+		members[index] = patientFromCore(record)
+	}
+	return members, nil
+}
+
+func removeFamilyMember(stub shim.ChaincodeStubInterface, patientNationalID string, patientFamilyID string) error {
+	return patientService(stub).RemoveFamilyMember(patientNationalID, patientFamilyID)
+}
+
+func patientService(stub shim.ChaincodeStubInterface) *core.PatientService {
+	return &core.PatientService{State: newStore(stub), Codec: patientCodec{}}
+}
+
+func diseaseService(stub shim.ChaincodeStubInterface) *core.DiseaseService {
+	return &core.DiseaseService{State: newStore(stub)}
+}
+
+func auditService(stub shim.ChaincodeStubInterface) *core.AuditService {
+	return &core.AuditService{State: newStore(stub), Codec: core.JSONCodec{}, Patients: patientCodec{}, Tx: newTransaction(stub)}
+}
+
+func consentService(stub shim.ChaincodeStubInterface) *core.ConsentService {
+	return &core.ConsentService{State: newStore(stub), Codec: core.JSONCodec{}, Patients: patientService(stub), Audit: auditService(stub), Tx: newTransaction(stub)}
+}
+
+func keyService(stub shim.ChaincodeStubInterface) *core.KeyService {
+	return &core.KeyService{
+		State:      newStore(stub),
+		Private:    newPrivateStore(stub, keyCollection),
+		Codec:      core.JSONCodec{},
+		FamilyKey:  familyKeyService(stub).Key,
+		WrapSecret: core.KeyWrapSecret(),
+		Tx:         newTransaction(stub),
+	}
+}
+
+func familyKeyService(stub shim.ChaincodeStubInterface) *core.FamilyKeyService {
+	return &core.FamilyKeyService{State: newStore(stub), Private: newPrivateStore(stub, keyCollection), Codec: core.JSONCodec{}, Tx: newTransaction(stub)}
+}
+
+func erasureService(stub shim.ChaincodeStubInterface) *core.ErasureService {
+	return &core.ErasureService{
+		State:      newStore(stub),
+		Codec:      core.JSONCodec{},
+		Patients:   patientService(stub),
+		FamilyKeys: familyKeyService(stub),
+		Keys:       keyService(stub),
+		Audit:      auditService(stub),
+		Randomness: randomness(stub),
+		Tx:         newTransaction(stub),
+	}
+}
+
+func riskService(stub shim.ChaincodeStubInterface) *core.RiskService {
+	return &core.RiskService{Patients: patientService(stub), Keys: keyService(stub), Penetrance: diseaseService(stub).Penetrance(newTransaction(stub)), Randomness: randomness(stub)}
+}
+
+// randomness is the randomness of the ciphertexts the transaction computes, the same on
+// every endorsing peer
+func randomness(stub shim.ChaincodeStubInterface) *core.Randomness {
+	return core.TransactionRandomness(stub.GetTxID())
+}
+
+// encrypt encrypts a value of a patient's record in its slot
+func encrypt(stub shim.ChaincodeStubInterface, key *Pailler.PublicKey, msg int64, slot string) (*big.Int, error) {
+	ciphertext, err := randomness(stub).Encrypt(key, msg, slot)
+	var coreError *core.Error
+	if err != nil && !errors.As(err, &coreError) {
+		return nil, core.InternalError("encryption error: %v", err)
+	}
+	return ciphertext, err
+}
@@ -0,0 +1,49 @@
+package simple
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/core"
+)
+
+// parseDiseaseIndex parses a disease index argument and checks it names a disease slot
+func parseDiseaseIndex(arg string) (int, error) {
+	diseaseIndex, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, core.InvalidArgument("diseaseIndex", "%q is not an integer", arg)
+	}
+	return diseaseIndex, core.ValidateDiseaseIndex(diseaseIndex)
+}
+
+// parseGenotype parses a plaintext genotype and checks it is one of the core genotypes
+func parseGenotype(arg string) (int, error) {
+	genotype, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, core.InvalidArgument("genotype", "%q is not an integer", arg)
+	}
+	return genotype, core.ValidateGenotype(genotype)
+}
+
+// parseBirthYear parses a birth year and checks it lies between core.MinBirthYear and
+// the current year
+func parseBirthYear(arg string, currentYear int) (int, error) {
+	birthYear, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, core.InvalidArgument("birthYear", "%q is not an integer", arg)
+	}
+	return birthYear, core.ValidateBirthYear(birthYear, currentYear)
+}
+
+// parseDiseaseValues parses the plaintext disease flags of a new patient
+func parseDiseaseValues(args []string) ([]int, error) {
+	values := make([]int, len(args))
+	for index, arg := range args {
+		value, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, core.InvalidArgument(fmt.Sprintf("disease[%d]", index), "%q is not an integer", arg)
+		}
+		values[index] = value
+	}
+	return values, core.ValidateDiseaseValues(values...)
+}
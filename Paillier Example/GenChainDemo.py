@@ -1,5 +1,4 @@
 from random import randint
-import libnum
 import sys
 
 
@@ -32,78 +31,84 @@ def decrption(cipher,gLambda,n,gMu):
 	l = (pow(cipher, gLambda, n*n)-1) // n
 	return (l * gMu) % n
 
-def HomomorphicMultiplication(cipher1,message2):
-	return (pow(cipher1,message2)) % (n*n)
+def HomomorphicMultiplication(cipher1,message2,n):
+	return pow(cipher1, message2, n*n)
 
-def HomomorphicAddition(cipher1,cipher2):
+def HomomorphicAddition(cipher1,cipher2,n):
 	return (cipher1 * cipher2) % (n*n)
-	
 
-"""Pick p and q"""
+def pedigreeRisk(relatives,weight,g,n,r0):
+	"""Sum each relative's encrypted disease flag times weight // level, as the chaincode does.
+	relatives is a list of (level, affected, r) tuples."""
+	sumCipher = encryption(g,0,r0,n)
+	for level, affected, r in relatives:
+		cipher = encryption(g,affected,r,n)
+		sumCipher = HomomorphicAddition(HomomorphicMultiplication(cipher,weight//level,n),sumCipher,n)
+	return sumCipher
+	
 
-p=17
-q=19
+if __name__ == "__main__":
+	import libnum
 
+	"""Pick p and q"""
 
-fatherList = [0,1,0,1,0]
-motherList = [0,0,1,0,0]
-auntList = [0,0,0,0,0]
-grandfatherList = [0,0,1,0,0]
+	p=17
+	q=19
 
-father = Patient("father",fatherList, 1)
-mother = Patient("mother",motherList, 1)
-aunt = Patient("aunt",auntList, 2)
-grandFather = Patient("grandfather",grandfatherList, 2)
 
-familyTree = [father,mother,aunt,grandFather]
+	fatherList = [0,1,0,1,0]
+	motherList = [0,0,1,0,0]
+	auntList = [0,0,0,0,0]
+	grandfatherList = [0,0,1,0,0]
 
-sum = 0;
-diseaseIndex = 2;
-diseasePercentage = 25;
+	father = Patient("father",fatherList, 1)
+	mother = Patient("mother",motherList, 1)
+	aunt = Patient("aunt",auntList, 2)
+	grandFather = Patient("grandfather",grandfatherList, 2)
 
+	familyTree = [father,mother,aunt,grandFather]
 
-if (p==q):
-	print("P and Q cannot be the same")
-	sys.exit()
+	sum = 0;
+	diseaseIndex = 2;
+	diseasePercentage = 25;
 
-"""Calculation for Paillier Formulas"""
 
-n = p*q
-gLambda = lcm(p-1,q-1)
-g = randint(20,150)
-r = randint(20,150)
-l = (pow(g, gLambda, n*n)-1)//n
-gMu = libnum.invmod(l, n)
+	if (p==q):
+		print("P and Q cannot be the same")
+		sys.exit()
 
-if (gcd(g,n*n)!=1):
-	print("g is not relatively prime to n*n. Exit...")
-	sys.exit()
-	
+	"""Calculation for Paillier Formulas"""
 
-sumCipher = encryption(g,sum,r,n)
-
-relativeLevel = 1
+	n = p*q
+	gLambda = lcm(p-1,q-1)
+	g = randint(20,150)
+	r = randint(20,150)
+	l = (pow(g, gLambda, n*n)-1)//n
+	gMu = libnum.invmod(l, n)
 
+	if (gcd(g,n*n)!=1):
+		print("g is not relatively prime to n*n. Exit...")
+		sys.exit()
+	
 
-for patient in familyTree:
+	sumCipher = encryption(g,sum,r,n)
 
-	print(patient.name , " , Relative Level : " , patient.relative , " , Is it sick : " , patient.diseaseList[diseaseIndex])
 
-	if(patient.relative != relativeLevel):
-		relativeLevel+=1
-		diseasePercentage//=2
+	for patient in familyTree:
 
+		print(patient.name , " , Relative Level : " , patient.relative , " , Is it sick : " , patient.diseaseList[diseaseIndex])
 
-	message1 = diseasePercentage
-	message2 = patient.diseaseList[diseaseIndex]
+		"""The chaincode weighs a relative at level k by diseasePercentage // k"""
+		message1 = diseasePercentage // patient.relative
+		message2 = patient.diseaseList[diseaseIndex]
 
-	cipher2 = encryption(g,message2,r,n)
+		cipher2 = encryption(g,message2,r,n)
 
-	ciphertotal = HomomorphicMultiplication(cipher2,message1)
-	sumCipher = HomomorphicAddition(ciphertotal,sumCipher)
-	messTotal = decrption(sumCipher,gLambda,n,gMu)
+		ciphertotal = HomomorphicMultiplication(cipher2,message1,n)
+		sumCipher = HomomorphicAddition(ciphertotal,sumCipher,n)
+		messTotal = decrption(sumCipher,gLambda,n,gMu)
 
-	print("================")
-	print("Currently sick probability : %" , messTotal)
-	print("================")
+		print("================")
+		print("Currently sick probability : %" , messTotal)
+		print("================")
 
@@ -1,5 +1,4 @@
 from random import randint
-import libnum
 import sys
 
 def gcd(a,b):
@@ -25,70 +24,79 @@ def decrption(cipher,gLambda,n,gMu):
 	l = (pow(cipher, gLambda, n*n)-1) // n
 	return (l * gMu) % n
 
-def HomomorphicMultiplication(cipher1,message2):
-	return (pow(cipher1,message2)) % (n*n)
+def HomomorphicMultiplication(cipher1,message2,n):
+	return pow(cipher1, message2, n*n)
 
-def HomomorphicAddition(cipher1,cipher2):
+def HomomorphicAddition(cipher1,cipher2,n):
 	return (cipher1 * cipher2) % (n*n)
+
+def HomomorphicSubtraction(cipher1,cipher2,n):
+	return (cipher1 * pow(cipher2, -1, n*n)) % (n*n)
+
+def HomomorphicAddPlaintext(cipher1,message2,g,n):
+	return (cipher1 * pow(g, message2, n*n)) % (n*n)
 	
 
-"""Pick p and q"""
+if __name__ == "__main__":
+	import libnum
+
+	"""Pick p and q"""
 
-p=17
-q=19
+	p=17
+	q=19
 
 
-if (p==q):
-	print("P and Q cannot be the same")
-	sys.exit()
+	if (p==q):
+		print("P and Q cannot be the same")
+		sys.exit()
 
-"""Calculation for Paillier Formulas"""
+	"""Calculation for Paillier Formulas"""
 
-n = p*q
-gLambda = lcm(p-1,q-1)
-g = randint(20,150)
-r = randint(20,150)
-l = (pow(g, gLambda, n*n)-1)//n
-gMu = libnum.invmod(l, n)
+	n = p*q
+	gLambda = lcm(p-1,q-1)
+	g = randint(20,150)
+	r = randint(20,150)
+	l = (pow(g, gLambda, n*n)-1)//n
+	gMu = libnum.invmod(l, n)
 
-if (gcd(g,n*n)==1):
-	print("g is relatively prime to n*n")
-else:
-	print("g is not relatively prime to n*n. Exit...")
-	sys.exit()
+	if (gcd(g,n*n)==1):
+		print("g is relatively prime to n*n")
+	else:
+		print("g is not relatively prime to n*n. Exit...")
+		sys.exit()
 
 
-message1 = 10
-message2 = 2
+	message1 = 10
+	message2 = 2
 
 
 
-cipher1 = encryption(g,message1,r,n)
-mess = decrption(cipher1,gLambda,n,gMu)
+	cipher1 = encryption(g,message1,r,n)
+	mess = decrption(cipher1,gLambda,n,gMu)
 
-cipher2 = encryption(g,message2,r,n)
-mess2= decrption(cipher2,gLambda,n,gMu)
+	cipher2 = encryption(g,message2,r,n)
+	mess2= decrption(cipher2,gLambda,n,gMu)
 
-ciphertotal = HomomorphicMultiplication(cipher1,message2)
-messTotal = decrption(ciphertotal,gLambda,n,gMu)
+	ciphertotal = HomomorphicMultiplication(cipher1,message2,n)
+	messTotal = decrption(ciphertotal,gLambda,n,gMu)
 
-print("p=",p,"\tq=",q)
-print("g=",g,"\tr=",r)
-print("================")
-print("Mu:\t\t",gMu,"\tgLambda:\t",gLambda)
-print("================")
-print("Public key (n,g):\t\t",n,g)
-print("Private key (lambda,mu):\t",gLambda,gMu)
-print("================")
-print("Message 1 :\t",message1)
-print("Cipher:\t\t",cipher1)
-print("Decrypted:\t",mess)
-print("================")
-print("Message 2 :\t",message2)
-print("Cipher:\t\t",cipher2)
-print("Decrypted:\t",mess2)
-print("================")
-print("Sum Of Two Messages :\t\t",message1 * message2)
-print("Cipher:\t\t",ciphertotal)
-print("Decrypted:\t",messTotal)
-print("================")
\ No newline at end of file
+	print("p=",p,"\tq=",q)
+	print("g=",g,"\tr=",r)
+	print("================")
+	print("Mu:\t\t",gMu,"\tgLambda:\t",gLambda)
+	print("================")
+	print("Public key (n,g):\t\t",n,g)
+	print("Private key (lambda,mu):\t",gLambda,gMu)
+	print("================")
+	print("Message 1 :\t",message1)
+	print("Cipher:\t\t",cipher1)
+	print("Decrypted:\t",mess)
+	print("================")
+	print("Message 2 :\t",message2)
+	print("Cipher:\t\t",cipher2)
+	print("Decrypted:\t",mess2)
+	print("================")
+	print("Sum Of Two Messages :\t\t",message1 * message2)
+	print("Cipher:\t\t",ciphertotal)
+	print("Decrypted:\t",messTotal)
+	print("================")
\ No newline at end of file
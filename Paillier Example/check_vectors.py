@@ -0,0 +1,73 @@
+"""Check the Paillier implementation in Paillier.py and the family risk of GenChainDemo.py
+against the test vectors the Go chaincode library generates (testvectors.json).
+
+Run with: python3 check_vectors.py [testvectors.json]"""
+
+import json
+import os
+import sys
+
+from Paillier import encryption, decrption, HomomorphicAddition, HomomorphicMultiplication, HomomorphicSubtraction, HomomorphicAddPlaintext
+from GenChainDemo import pedigreeRisk
+
+
+def evaluate(vector,key):
+	n, g = key["n"], key["g"]
+	a = encryption(g,vector["a"],vector["rA"],n)
+	operation = vector["operation"]
+	if operation == "add":
+		return HomomorphicAddition(a,encryption(g,vector["b"],vector["rB"],n),n)
+	if operation == "sub":
+		return HomomorphicSubtraction(a,encryption(g,vector["b"],vector["rB"],n),n)
+	if operation == "multPlaintext":
+		return HomomorphicMultiplication(a,vector["b"],n)
+	if operation == "addPlaintext":
+		return HomomorphicAddPlaintext(a,vector["b"],g,n)
+	raise ValueError("unknown operation " + operation)
+
+
+def check(path):
+	with open(path) as f:
+		vectors = json.load(f)
+	keys = {key["name"]: key for key in vectors["keys"]}
+	failures = []
+
+	for key in keys.values():
+		if key["p"] * key["q"] != key["n"]:
+			failures.append("key %s: n is not p*q" % key["name"])
+
+	for i, vector in enumerate(vectors["encryptions"]):
+		key = keys[vector["key"]]
+		cipher = encryption(key["g"],vector["plaintext"],vector["r"],key["n"])
+		if cipher != vector["ciphertext"]:
+			failures.append("encryption %d: got %d, want %d" % (i, cipher, vector["ciphertext"]))
+		message = decrption(vector["ciphertext"],key["lambda"],key["n"],key["mu"])
+		if message != vector["plaintext"]:
+			failures.append("decryption %d: got %d, want %d" % (i, message, vector["plaintext"]))
+
+	for i, vector in enumerate(vectors["homomorphic"]):
+		key = keys[vector["key"]]
+		cipher = evaluate(vector,key)
+		message = decrption(cipher,key["lambda"],key["n"],key["mu"])
+		if cipher != vector["ciphertext"] or message != vector["plaintext"]:
+			failures.append("%s %d: got %d = %d, want %d = %d" % (vector["operation"], i, cipher, message, vector["ciphertext"], vector["plaintext"]))
+
+	for i, pedigree in enumerate(vectors["pedigrees"]):
+		key = keys[pedigree["key"]]
+		relatives = [(relative["level"], relative["affected"], relative["r"]) for relative in pedigree["relatives"]]
+		cipher = pedigreeRisk(relatives,pedigree["weight"],key["g"],key["n"],pedigree["r0"])
+		risk = decrption(cipher,key["lambda"],key["n"],key["mu"])
+		if cipher != pedigree["ciphertext"] or risk != pedigree["risk"]:
+			failures.append("pedigree %d: got %d = %d, want %d = %d" % (i, cipher, risk, pedigree["ciphertext"], pedigree["risk"]))
+
+	return failures
+
+
+if __name__ == "__main__":
+	path = sys.argv[1] if len(sys.argv) > 1 else os.path.join(os.path.dirname(os.path.abspath(__file__)), "testvectors.json")
+	failures = check(path)
+	for failure in failures:
+		print(failure)
+	if failures:
+		sys.exit(1)
+	print("all vectors match")